@@ -0,0 +1,79 @@
+package uno_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// closeHook 连接关闭时关闭 closed
+type closeHook struct {
+	uno.ConnEvent
+	closed chan struct{}
+}
+
+func (h *closeHook) OnClose(c uno.Conn) { close(h.closed) }
+
+// dialUnacked 以 ARQ 连接一个从不应答的对端，并发出足以填满发送窗口的消息
+func dialUnacked(t *testing.T, opts uno.ARQOptions) (uno.Conn, *closeHook) {
+	t.Helper()
+	sink, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sink.Close() })
+
+	h := &closeHook{closed: make(chan struct{})}
+	c, err := uno.Dial(context.Background(), h, sink.LocalAddr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithNetwork("udp"), uno.WithARQ(opts), uno.WithCloseTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 16; i++ {
+		c.Send([]byte("x"))
+	}
+	// 等待写协程阻塞在已满的发送窗口上
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if st, ok := uno.ReliableStats(c); ok && st.Queued >= opts.SendWindow {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("send queue did not fill up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return c, h
+}
+
+func TestARQDeadLinkClosesBlockedConn(t *testing.T) {
+	c, h := dialUnacked(t, uno.ARQOptions{SendWindow: 2, Interval: 5 * time.Millisecond, DeadLink: 3})
+
+	select {
+	case <-h.closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("conn with a dead link and a full send window did not close")
+	}
+	if err := context.Cause(c.Context()); !errors.Is(err, uno.ErrARQDeadLink) {
+		t.Fatalf("close cause = %v, want ErrARQDeadLink", err)
+	}
+}
+
+func TestARQCloseWithFullSendWindow(t *testing.T) {
+	c, h := dialUnacked(t, uno.ARQOptions{SendWindow: 2, Interval: 5 * time.Millisecond, DeadLink: 1000})
+
+	start := time.Now()
+	c.Close()
+	select {
+	case <-h.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not abort the write blocked on the send window")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Fatalf("Close took %v", d)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+// 空闲长连接开销：对比读协程模式与 epoll 事件循环模式（WithReactor）下每个空闲连接占用的协程与内存。
+//
+//	go run ./examples/idleconns -n 10000 -loops 2
+//
+// 连接数受限于进程的文件描述符上限（ulimit -n），每个连接在本进程内占用客户端与服务端两个描述符。
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+var (
+	n     = flag.Int("n", 5000, "连接数")
+	loops = flag.Int("loops", 2, "事件循环数量")
+)
+
+type quiet struct{}
+
+func (quiet) Debug(string, ...any) {}
+func (quiet) Info(string, ...any)  {}
+func (quiet) Warn(string, ...any)  {}
+func (quiet) Error(string, ...any) {}
+
+func main() {
+	flag.Parse()
+
+	fmt.Printf("conns=%d\n\n", *n)
+	fmt.Printf("%-10s %16s %12s\n", "mode", "goroutines/conn", "bytes/conn")
+	measure("goroutine", 0)
+	measure("reactor", *loops)
+}
+
+// measure 建立 n 个连接并各发送一条消息，待其空闲后统计协程数与堆、栈内存的增量
+func measure(mode string, loops int) {
+	echo := uno.WithHandlers(func(ctx uno.Context, next func()) {
+		ctx.Conn().Send(ctx.Payload())
+	})
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, "127.0.0.1:0",
+		uno.WithReactor(loops), uno.WithLogger(quiet{}), echo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Stop()
+
+	g0, m0 := snapshot()
+	conns := make([]net.Conn, 0, *n)
+	defer func() {
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+	buf := make([]byte, 16)
+	for i := 0; i < *n; i++ {
+		c, err := net.Dial("tcp", srv.Addr().String())
+		if err != nil {
+			log.Fatal(err)
+		}
+		conns = append(conns, c)
+		if _, err = c.Write([]byte("ping")); err != nil {
+			log.Fatal(err)
+		}
+		if _, err = c.Read(buf); err != nil {
+			log.Fatal(err)
+		}
+	}
+	time.Sleep(500 * time.Millisecond) // 等待写出与处理任务结束
+	g1, m1 := snapshot()
+
+	fmt.Printf("%-10s %16.2f %12.0f\n", mode, float64(g1-g0)/float64(*n), float64(m1-m0)/float64(*n))
+}
+
+func snapshot() (int, uint64) {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return runtime.NumGoroutine(), ms.HeapAlloc + ms.StackInuse
+}
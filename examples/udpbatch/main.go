@@ -0,0 +1,85 @@
+package main
+
+// UDP 批量收发演示：对比 UDP 回显服务端关闭与开启 WithUDPBatch 时的端到端吞吐。
+//
+//	go run ./examples/udpbatch -d 3s -batch 64 -size 64
+//
+// 套接字层的收发速率见 internal/mmsg 的基准测试（go test -bench . ./internal/mmsg）。
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+var (
+	duration = flag.Duration("d", 3*time.Second, "每项测量时长")
+	batch    = flag.Int("batch", 64, "批量大小")
+	size     = flag.Int("size", 64, "数据报大小（字节）")
+	peers    = flag.Int("peers", runtime.NumCPU(), "发送方数量")
+)
+
+type quiet struct{}
+
+func (quiet) Debug(string, ...any) {}
+func (quiet) Info(string, ...any)  {}
+func (quiet) Warn(string, ...any)  {}
+func (quiet) Error(string, ...any) {}
+
+func main() {
+	flag.Parse()
+
+	fmt.Printf("batch=%d size=%d peers=%d\n\n", *batch, *size, *peers)
+
+	fmt.Println("echo server, received by server (datagrams/s)")
+	fmt.Printf("  single  %12.0f\n", benchServer(0))
+	fmt.Printf("  batched %12.0f\n", benchServer(*batch))
+}
+
+// benchServer 多个发送方向 UDP 回显服务端发送，测量服务端收到的消息速率（含回显写出）
+func benchServer(batchSize int) float64 {
+	var recv atomic.Int64
+	echo := uno.WithHandlers(func(ctx uno.Context, next func()) {
+		recv.Add(1)
+		ctx.Conn().Send(ctx.Payload())
+	})
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, "127.0.0.1:0",
+		uno.WithNetwork("udp"), uno.WithUDPBatch(batchSize), uno.WithLogger(quiet{}), echo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+	start := time.Now()
+	blast(ctx, srv.Addr())
+	<-ctx.Done()
+	return float64(recv.Load()) / time.Since(start).Seconds()
+}
+
+// blast 启动 peers 个发送方，持续发送直到 ctx 结束
+func blast(ctx context.Context, to net.Addr) {
+	var ready sync.WaitGroup
+	for i := 0; i < *peers; i++ {
+		ready.Add(1)
+		go func() {
+			pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+			defer pc.Close()
+			buf := make([]byte, *size)
+			ready.Done()
+			for ctx.Err() == nil {
+				_, _ = pc.WriteTo(buf, to)
+			}
+		}()
+	}
+	ready.Wait()
+}
@@ -0,0 +1,193 @@
+//go:build unix
+
+package uno_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// 子进程角色由环境变量选择：重新执行测试二进制，仅运行 TestHandoffChild
+const (
+	envHandoffChild  = "UNO_TEST_HANDOFF_CHILD"
+	envHandoffSocket = "UNO_TEST_HANDOFF_SOCKET"
+)
+
+const handoffAddr = "127.0.0.1:0"
+
+// startHandoff 启动启用交接的服务端，回复 "<name>:<payload>"；payload 为 slow 时等待 release 关闭
+func startHandoff(t *testing.T, name, socket string, started chan<- struct{}, release <-chan struct{}) uno.Server {
+	t.Helper()
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, handoffAddr, uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)),
+		uno.WithHandoff(uno.HandoffOptions{Socket: socket, DrainTimeout: 10 * time.Second}),
+		uno.WithHandlers(func(ctx uno.Context, next func()) {
+			payload := ctx.Payload().(string)
+			if payload == "slow" {
+				started <- struct{}{}
+				<-release
+			}
+			ctx.Reply(name + ":" + payload)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// TestHandoffChild 新进程：接管监听套接字后输出地址，标准输入关闭时退出
+func TestHandoffChild(t *testing.T) {
+	socket := os.Getenv(envHandoffSocket)
+	if os.Getenv(envHandoffChild) == "" || socket == "" {
+		t.Skip("only runs as the child of TestHandoff")
+	}
+	srv := startHandoff(t, "new", socket, nil, nil)
+	defer srv.Stop()
+	fmt.Printf("ready %s\n", srv.Addr())
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+func call(t *testing.T, addr, msg string) string {
+	t.Helper()
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, addr, uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)))
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := c.(uno.RPCConn).Call(ctx, msg)
+	if err != nil {
+		t.Fatalf("call %s: %v", msg, err)
+	}
+	return reply.(string)
+}
+
+func TestHandoff(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "handoff.sock")
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	old := startHandoff(t, "old", socket, started, release)
+	defer old.Stop()
+	addr := old.Addr().String()
+
+	if got := call(t, addr, "a"); got != "old:a" {
+		t.Fatalf("reply before handoff = %q", got)
+	}
+
+	// 交接前发出、处理中的请求须在排空期间完成
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, addr, uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	slow := make(chan any, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		reply, err := c.(uno.RPCConn).Call(ctx, "slow")
+		if err != nil {
+			reply = err
+		}
+		slow <- reply
+	}()
+	<-started
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHandoffChild$")
+	cmd.Env = append(os.Environ(), envHandoffChild+"=1", envHandoffSocket+"="+socket)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	}()
+
+	ready := make(chan string, 1)
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		for sc.Scan() {
+			if line, ok := strings.CutPrefix(sc.Text(), "ready "); ok {
+				ready <- line
+			}
+		}
+		close(ready)
+	}()
+	select {
+	case got, ok := <-ready:
+		if !ok {
+			t.Fatal("child exited before taking over the listener")
+		}
+		if got != addr {
+			t.Fatalf("child listens on %s, want %s", got, addr)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("child did not take over the listener")
+	}
+
+	// 旧进程已关闭监听器：新连接全部由新进程处理
+	for i := 0; i < 5; i++ {
+		if got := call(t, addr, "b"); got != "new:b" {
+			t.Fatalf("reply after handoff = %q, want new:b", got)
+		}
+	}
+
+	// 旧进程仍在排空处理中的请求
+	waited := make(chan error, 1)
+	go func() { waited <- old.Wait() }()
+	select {
+	case err := <-waited:
+		t.Fatalf("old server stopped before draining: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if n := old.Conns().Count(); n != 1 {
+		t.Fatalf("old server has %d conns while draining, want 1", n)
+	}
+
+	close(release)
+	select {
+	case got := <-slow:
+		if got != "old:slow" {
+			t.Fatalf("in-flight reply = %v, want old:slow", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("in-flight request was not answered")
+	}
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Fatalf("old server exit error: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("old server did not stop after draining")
+	}
+	if n := old.Conns().Count(); n != 0 {
+		t.Fatalf("old server has %d conns after draining", n)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.IsActive() {
+		if time.Now().After(deadline) {
+			t.Fatal("drained conn was not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,581 @@
+package arq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Name 处理层名称
+const Name = "arq"
+
+// 报文头：[cmd 1][frg 2][wnd 2][ts 4][sn 4][una 4][len 2]
+const headerSize = 19
+
+const (
+	cmdPush byte = 1 // 数据段
+	cmdAck  byte = 2 // 选择确认
+)
+
+const maxRTO = 60 * time.Second
+
+// fastLimit 单个数据段快速重传的最大发送次数，超过后仅依赖超时重传
+const fastLimit = 5
+
+var (
+	ErrDeadLink  = errors.New("arq: dead link, too many retransmissions")
+	ErrTooLarge  = errors.New("arq: message exceeds receive window")
+	ErrClosed    = errors.New("arq: session closed")
+	ErrMalformed = errors.New("arq: malformed segment")
+	ErrMTUTooLow = errors.New("arq: mtu too small")
+)
+
+// Options 可靠传输参数，服务端与客户端应保持一致。
+type Options struct {
+	// Interval 内部刷新周期（重传检测、确认发送）。
+	// 如果为 0，默认 10ms。
+	Interval time.Duration
+
+	// SendWindow 发送窗口（数据段个数），同时限制等待进入窗口的数据段：
+	// 发送队列达到该值时 Write 阻塞，直到确认推进窗口或连接关闭，使连接的发送队列产生背压。
+	// 如果为 0，默认 128。
+	SendWindow int
+
+	// RecvWindow 接收窗口（数据段个数），同时限制单条消息的最大分段数。
+	// 如果为 0，默认 128。
+	RecvWindow int
+
+	// MinRTO 最小重传超时。
+	// 如果为 0，默认 30ms。
+	MinRTO time.Duration
+
+	// FastResend 快速重传阈值：被后续确认跳过的次数达到该值即重传。
+	// 如果为 0，默认 2；小于 0 表示关闭快速重传。
+	FastResend int
+
+	// DeadLink 单个数据段最大发送次数，超过后判定链路失效并关闭连接。
+	// 如果为 0，默认 20。
+	DeadLink int
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.Interval <= 0 {
+		o.Interval = 10 * time.Millisecond
+	}
+	if o.SendWindow <= 0 {
+		o.SendWindow = 128
+	}
+	if o.RecvWindow <= 0 {
+		o.RecvWindow = 128
+	}
+	if o.MinRTO <= 0 {
+		o.MinRTO = 30 * time.Millisecond
+	}
+	if o.FastResend == 0 {
+		o.FastResend = 2
+	}
+	if o.DeadLink <= 0 {
+		o.DeadLink = 20
+	}
+}
+
+// Stats 连接级可靠传输统计
+type Stats struct {
+	SRTT   time.Duration // 平滑 RTT
+	RTTVar time.Duration // RTT 偏差
+	RTO    time.Duration // 当前重传超时
+
+	Sent            uint64 // 发送的数据段（含重传）
+	Recv            uint64 // 接收的数据段（含重复）
+	Delivered       uint64 // 向上层投递的消息数
+	Retransmits     uint64 // 重传次数（超时 + 快速）
+	FastRetransmits uint64 // 快速重传次数
+	Timeouts        uint64 // 超时重传次数
+	Duplicates      uint64 // 收到的重复数据段
+
+	InFlight int // 已发送未确认的数据段
+	Queued   int // 等待进入发送窗口的数据段
+}
+
+// LossRate 估算丢包率（重传 / 发送）
+func (s Stats) LossRate() float64 {
+	if s.Sent == 0 {
+		return 0
+	}
+	return float64(s.Retransmits) / float64(s.Sent)
+}
+
+// StatsOf 获取连接的可靠传输统计，未启用时返回 false
+func StatsOf(c boot.Conn) (Stats, bool) {
+	lc, ok := c.(interface {
+		Layer(name string) (boot.Layer, bool)
+	})
+	if !ok {
+		return Stats{}, false
+	}
+	l, ok := lc.Layer(Name)
+	if !ok {
+		return Stats{}, false
+	}
+	return l.(*Session).Stats(), true
+}
+
+type segment struct {
+	sn       uint32
+	frg      uint16
+	ts       uint32
+	data     []byte
+	xmit     int
+	rto      time.Duration
+	resendAt time.Time
+	fastack  int
+}
+
+type ack struct {
+	sn uint32
+	ts uint32
+}
+
+// Session 单连接的可靠有序传输层（类 KCP 实现）。
+// 发送端按序号分段、超时与快速重传，接收端选择确认并按序重组投递。
+type Session struct {
+	opts Options
+	mss  int
+	mtu  int
+	io   boot.LayerIO
+
+	mu    sync.Mutex
+	cond  *sync.Cond // 发送队列腾出空间或会话关闭时广播
+	epoch time.Time
+
+	sndQueue []*segment
+	sndBuf   []*segment // 按 sn 升序
+	sndUna   uint32
+	sndNxt   uint32
+	rmtWnd   int
+
+	rcvNxt uint32
+	rcvBuf map[uint32]*segment
+	acks   []ack
+
+	srtt   time.Duration
+	rttvar time.Duration
+	rto    time.Duration
+
+	stats  Stats
+	closed bool
+	err    error // 链路失效或被中止后 Write 直接返回的错误
+
+	upcalls atomic.Int32 // 正在向上层投递的 Read 数，期间的 Write 不阻塞，以免读路径等待自身处理的确认
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New 创建可靠传输层，mtu 为下层单个数据报的最大字节数
+func New(opts Options, mtu int) *Session {
+	opts.WithDefault()
+	s := &Session{
+		opts:   opts,
+		mtu:    mtu,
+		mss:    mtu - headerSize,
+		rmtWnd: opts.RecvWindow,
+		rcvBuf: make(map[uint32]*segment),
+		rto:    200 * time.Millisecond,
+		epoch:  time.Now(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *Session) Name() string { return Name }
+
+func (s *Session) Open(c boot.Conn, io boot.LayerIO) {
+	s.io = io
+	go s.loop()
+}
+
+func (s *Session) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	close(s.stop)
+	<-s.done
+}
+
+// Abort 中止会话：阻塞中与此后的 Write 返回 ErrClosed，由连接在强制关闭时调用
+func (s *Session) Abort() {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = ErrClosed
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+// dead 判定链路失效：唤醒阻塞中的 Write 并关闭连接
+func (s *Session) dead(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	s.io.Fail(err)
+}
+
+// Write 将消息分段放入发送队列并尝试立即发送；发送队列已达 SendWindow 时阻塞等待窗口推进，
+// 链路失效或会话关闭、中止时返回错误。由上层在 Read 投递期间发起的写（如多路复用的控制帧）不阻塞
+func (s *Session) Write(buf []byte) error {
+	if s.mss <= 0 {
+		return ErrMTUTooLow
+	}
+
+	count := (len(buf) + s.mss - 1) / s.mss
+	if count == 0 {
+		count = 1
+	}
+	if count > s.opts.RecvWindow || count > 0xFFFF {
+		return ErrTooLarge
+	}
+
+	s.mu.Lock()
+	for !s.closed && s.err == nil && len(s.sndQueue) >= s.opts.SendWindow && s.upcalls.Load() == 0 {
+		s.cond.Wait()
+	}
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	if s.err != nil {
+		err := s.err
+		s.mu.Unlock()
+		return err
+	}
+	for i := 0; i < count; i++ {
+		end := min((i+1)*s.mss, len(buf))
+		data := append([]byte(nil), buf[i*s.mss:end]...)
+		s.sndQueue = append(s.sndQueue, &segment{frg: uint16(count - 1 - i), data: data})
+	}
+	out, err := s.flush(time.Now())
+	s.mu.Unlock()
+
+	return s.output(out, err)
+}
+
+// Read 解析下层数据报中的报文段，按序投递完整消息
+func (s *Session) Read(chunk []byte) error {
+	now := time.Now()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+
+	var err error
+	for len(chunk) > 0 {
+		if len(chunk) < headerSize {
+			err = ErrMalformed
+			break
+		}
+		cmd := chunk[0]
+		frg := binary.BigEndian.Uint16(chunk[1:])
+		wnd := binary.BigEndian.Uint16(chunk[3:])
+		ts := binary.BigEndian.Uint32(chunk[5:])
+		sn := binary.BigEndian.Uint32(chunk[9:])
+		una := binary.BigEndian.Uint32(chunk[13:])
+		size := int(binary.BigEndian.Uint16(chunk[17:]))
+		if len(chunk) < headerSize+size {
+			err = ErrMalformed
+			break
+		}
+		data := chunk[headerSize : headerSize+size]
+		chunk = chunk[headerSize+size:]
+
+		s.rmtWnd = int(wnd)
+		s.ackUna(una)
+
+		switch cmd {
+		case cmdAck:
+			s.ackSn(sn, ts, now)
+		case cmdPush:
+			s.stats.Recv++
+			if diff(sn, s.rcvNxt+uint32(s.opts.RecvWindow)) >= 0 {
+				continue // 超出接收窗口，丢弃且不确认
+			}
+			s.acks = append(s.acks, ack{sn: sn, ts: ts})
+			if diff(sn, s.rcvNxt) < 0 {
+				s.stats.Duplicates++
+				continue
+			}
+			if _, ok := s.rcvBuf[sn]; ok {
+				s.stats.Duplicates++
+				continue
+			}
+			s.rcvBuf[sn] = &segment{sn: sn, frg: frg, data: append([]byte(nil), data...)}
+		default:
+			err = fmt.Errorf("%w: unknown cmd %d", ErrMalformed, cmd)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	msgs := s.deliver()
+	out, ferr := s.flush(now)
+	s.mu.Unlock()
+
+	s.upcalls.Add(1)
+	for _, msg := range msgs {
+		s.io.Up(msg)
+	}
+	s.upcalls.Add(-1)
+	if oerr := s.output(out, ferr); oerr != nil && err == nil {
+		err = oerr
+	}
+	return err
+}
+
+// Stats 返回统计快照
+func (s *Session) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stats
+	st.SRTT = s.srtt
+	st.RTTVar = s.rttvar
+	st.RTO = s.rto
+	st.InFlight = len(s.sndBuf)
+	st.Queued = len(s.sndQueue)
+	return st
+}
+
+func (s *Session) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			out, err := s.flush(now)
+			s.mu.Unlock()
+			if errors.Is(err, ErrDeadLink) {
+				s.dead(err)
+				return
+			}
+			_ = s.output(out, nil)
+		}
+	}
+}
+
+// output 写出待发送数据报
+func (s *Session) output(out [][]byte, err error) error {
+	if errors.Is(err, ErrDeadLink) {
+		s.dead(err)
+		return err
+	}
+	for _, pkt := range out {
+		if werr := s.io.Down(pkt); werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// ackUna 移除所有 sn < una 的已确认数据段
+func (s *Session) ackUna(una uint32) {
+	n := 0
+	for n < len(s.sndBuf) && diff(s.sndBuf[n].sn, una) < 0 {
+		n++
+	}
+	if n > 0 {
+		s.sndBuf = s.sndBuf[n:]
+	}
+	s.updateUna()
+}
+
+// ackSn 处理单个选择确认：更新 RTT、移除数据段，
+// 并为在被确认段之前发出、却仍未确认的数据段累计跳过次数
+func (s *Session) ackSn(sn, ts uint32, now time.Time) {
+	if rtt := s.now(now) - ts; int32(rtt) >= 0 {
+		s.updateRTT(time.Duration(rtt) * time.Millisecond)
+	}
+
+	for i, seg := range s.sndBuf {
+		if seg.sn == sn {
+			s.sndBuf = append(s.sndBuf[:i], s.sndBuf[i+1:]...)
+			break
+		}
+		if diff(seg.sn, sn) < 0 && diff(seg.ts, ts) <= 0 {
+			seg.fastack++
+		}
+	}
+	s.updateUna()
+}
+
+func (s *Session) updateUna() {
+	if len(s.sndBuf) > 0 {
+		s.sndUna = s.sndBuf[0].sn
+	} else {
+		s.sndUna = s.sndNxt
+	}
+}
+
+// updateRTT 按 RFC 6298 估算 RTO
+func (s *Session) updateRTT(rtt time.Duration) {
+	if s.srtt == 0 {
+		s.srtt = rtt
+		s.rttvar = rtt / 2
+	} else {
+		delta := s.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		s.rttvar = (3*s.rttvar + delta) / 4
+		s.srtt = (7*s.srtt + rtt) / 8
+	}
+	rto := s.srtt + max(s.opts.Interval, 4*s.rttvar)
+	s.rto = min(max(rto, s.opts.MinRTO), maxRTO)
+}
+
+// deliver 从 rcvNxt 开始按序取出完整消息
+func (s *Session) deliver() [][]byte {
+	var msgs [][]byte
+	for {
+		var parts []*segment
+		sn := s.rcvNxt
+		complete := false
+		for {
+			seg, ok := s.rcvBuf[sn]
+			if !ok {
+				break
+			}
+			parts = append(parts, seg)
+			sn++
+			if seg.frg == 0 {
+				complete = true
+				break
+			}
+		}
+		if !complete {
+			return msgs
+		}
+
+		var msg []byte
+		for _, seg := range parts {
+			msg = append(msg, seg.data...)
+			delete(s.rcvBuf, seg.sn)
+		}
+		s.rcvNxt = sn
+		s.stats.Delivered++
+		msgs = append(msgs, msg)
+	}
+}
+
+// flush 组装确认与数据段，返回需要写出的数据报
+func (s *Session) flush(now time.Time) ([][]byte, error) {
+	var out [][]byte
+	pkt := make([]byte, 0, s.mtu)
+	emit := func(cmd byte, seg *segment, ts uint32) {
+		if len(pkt)+headerSize+len(seg.data) > s.mtu {
+			out = append(out, pkt)
+			pkt = make([]byte, 0, s.mtu)
+		}
+		wnd := max(s.opts.RecvWindow-len(s.rcvBuf), 0)
+		pkt = append(pkt, cmd)
+		pkt = binary.BigEndian.AppendUint16(pkt, seg.frg)
+		pkt = binary.BigEndian.AppendUint16(pkt, uint16(wnd))
+		pkt = binary.BigEndian.AppendUint32(pkt, ts)
+		pkt = binary.BigEndian.AppendUint32(pkt, seg.sn)
+		pkt = binary.BigEndian.AppendUint32(pkt, s.rcvNxt)
+		pkt = binary.BigEndian.AppendUint16(pkt, uint16(len(seg.data)))
+		pkt = append(pkt, seg.data...)
+	}
+
+	// 确认
+	for _, a := range s.acks {
+		emit(cmdAck, &segment{sn: a.sn}, a.ts)
+	}
+	s.acks = s.acks[:0]
+
+	// 发送队列进入窗口（对端窗口为 0 时仍允许 1 段用于探测）
+	cwnd := uint32(max(min(s.opts.SendWindow, s.rmtWnd), 1))
+	queued := len(s.sndQueue)
+	for len(s.sndQueue) > 0 && diff(s.sndNxt, s.sndUna+cwnd) < 0 {
+		seg := s.sndQueue[0]
+		s.sndQueue[0] = nil
+		s.sndQueue = s.sndQueue[1:]
+		seg.sn = s.sndNxt
+		s.sndNxt++
+		s.sndBuf = append(s.sndBuf, seg)
+	}
+	if len(s.sndQueue) < queued {
+		s.cond.Broadcast()
+	}
+
+	// 首发、超时重传、快速重传
+	var err error
+	for _, seg := range s.sndBuf {
+		send := false
+		switch {
+		case seg.xmit == 0:
+			send = true
+			seg.rto = s.rto
+		case !now.Before(seg.resendAt):
+			send = true
+			seg.rto = min(seg.rto*2, maxRTO)
+			s.stats.Timeouts++
+			s.stats.Retransmits++
+		case s.opts.FastResend > 0 && seg.fastack >= s.opts.FastResend && seg.xmit < fastLimit:
+			send = true
+			seg.fastack = 0
+			s.stats.FastRetransmits++
+			s.stats.Retransmits++
+		}
+		if !send {
+			continue
+		}
+
+		seg.xmit++
+		seg.ts = s.now(now)
+		seg.resendAt = now.Add(seg.rto)
+		s.stats.Sent++
+		emit(cmdPush, seg, seg.ts)
+		if seg.xmit >= s.opts.DeadLink {
+			err = ErrDeadLink
+		}
+	}
+
+	if len(pkt) > 0 {
+		out = append(out, pkt)
+	}
+	return out, err
+}
+
+// now 返回会话内毫秒时间戳
+func (s *Session) now(t time.Time) uint32 {
+	return uint32(t.Sub(s.epoch) / time.Millisecond)
+}
+
+// diff 序号回绕安全的比较
+func diff(a, b uint32) int32 {
+	return int32(a - b)
+}
@@ -0,0 +1,121 @@
+package arq
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+)
+
+func TestWriteBlocksWhenSendQueueFull(t *testing.T) {
+	s := New(Options{SendWindow: 4, RecvWindow: 4}, 64)
+	// 对端从不确认：窗口内 4 段在途，之后的数据段留在发送队列
+	s.Open(nil, boot.LayerIO{Down: func([]byte) error { return nil }, Up: func([]byte) {}, Fail: func(error) {}})
+
+	for i := 0; i < 8; i++ {
+		if err := s.Write([]byte("x")); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if st := s.Stats(); st.InFlight != 4 || st.Queued != 4 {
+		t.Fatalf("InFlight = %d, Queued = %d, want 4, 4", st.InFlight, st.Queued)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Write([]byte("x")) }()
+	select {
+	case err := <-done:
+		t.Fatalf("write with a full send queue returned %v, want it to block", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("blocked write returned %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("close did not release the blocked write")
+	}
+}
+
+func TestWriteResumesAfterAck(t *testing.T) {
+	a := New(Options{SendWindow: 2, RecvWindow: 2, Interval: time.Millisecond}, 64)
+	b := New(Options{SendWindow: 2, RecvWindow: 2, Interval: time.Millisecond}, 64)
+	got := make(chan []byte, 16)
+	a.Open(nil, boot.LayerIO{Down: func(p []byte) error { return b.Read(p) }, Up: func([]byte) {}, Fail: func(error) {}})
+	b.Open(nil, boot.LayerIO{Down: func(p []byte) error { go a.Read(p); return nil }, Up: func(m []byte) { got <- m }, Fail: func(error) {}})
+	defer a.Close()
+	defer b.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := a.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case m := <-got:
+			if m[0] != byte(i) {
+				t.Fatalf("message %d = %d", i, m[0])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("message %d not delivered", i)
+		}
+	}
+}
+
+// blockedWrite 填满发送队列后发起一次阻塞的写，返回其结果通道
+func blockedWrite(t *testing.T, s *Session) <-chan error {
+	t.Helper()
+	for i := 0; i < 2*s.opts.SendWindow; i++ {
+		if err := s.Write([]byte("x")); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Write([]byte("x")) }()
+	return done
+}
+
+func TestDeadLinkReleasesBlockedWrite(t *testing.T) {
+	s := New(Options{SendWindow: 2, RecvWindow: 2, Interval: time.Millisecond, DeadLink: 3}, 64)
+	failed := make(chan error, 1)
+	s.Open(nil, boot.LayerIO{Down: func([]byte) error { return nil }, Up: func([]byte) {}, Fail: func(err error) { failed <- err }})
+	defer s.Close()
+
+	done := blockedWrite(t, s)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrDeadLink) {
+			t.Fatalf("blocked write returned %v, want ErrDeadLink", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead link did not release the blocked write")
+	}
+	if err := <-failed; !errors.Is(err, ErrDeadLink) {
+		t.Fatalf("Fail called with %v", err)
+	}
+	if err := s.Write([]byte("x")); !errors.Is(err, ErrDeadLink) {
+		t.Fatalf("write after dead link returned %v", err)
+	}
+}
+
+func TestAbortReleasesBlockedWrite(t *testing.T) {
+	s := New(Options{SendWindow: 2, RecvWindow: 2}, 64)
+	s.Open(nil, boot.LayerIO{Down: func([]byte) error { return nil }, Up: func([]byte) {}, Fail: func(error) {}})
+	defer s.Close()
+
+	done := blockedWrite(t, s)
+	s.Abort()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("blocked write returned %v, want ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("abort did not release the blocked write")
+	}
+}
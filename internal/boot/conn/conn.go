@@ -3,6 +3,8 @@ package conn
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/yurazsb/uno/internal/boot"
@@ -12,7 +14,10 @@ import (
 	"github.com/yurazsb/uno/internal/framer"
 	"github.com/yurazsb/uno/internal/handler"
 	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/lifecycle"
 	"github.com/yurazsb/uno/pkg/attrs"
+	"github.com/yurazsb/uno/pkg/pool"
+	"github.com/yurazsb/uno/pkg/state"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -32,6 +37,21 @@ type Transport interface {
 	Stop(c *Conn)                    // 资源收尾
 }
 
+// interrupter 可中断阻塞读的传输层，连接关闭时无需等待读超时
+type interrupter interface {
+	Interrupt()
+}
+
+// aborter 可立即中止读写的传输层，用于强制关闭
+type aborter interface {
+	Abort()
+}
+
+// reactive 由事件循环驱动读取的传输层，连接不常驻写协程，读缓冲在无残留数据时释放
+type reactive interface {
+	Reactive()
+}
+
 // SendResult 发送结果
 type SendResult struct {
 	Err  error
@@ -42,6 +62,10 @@ type SendResult struct {
 type Conn struct {
 	T Transport
 
+	Client   bool      // 是否为客户端发起的连接
+	DialAddr string    // 客户端拨号的地址，运行时开启 TLS 时以其主机名作为默认 ServerName
+	Registry *Registry // 服务端连接管理器，启动时登记、关闭时移除
+
 	Id         string
 	Local      net.Addr
 	Remote     net.Addr
@@ -54,28 +78,48 @@ type Conn struct {
 	Hook hook.ConnHook
 
 	Ctx    context.Context
-	Cancel context.CancelFunc
+	Cancel context.CancelCauseFunc // 以断开原因关闭连接，本端主动关闭时为 nil，可经 context.Cause(Ctx) 读取
 
 	Wg *sync.WaitGroup
 
 	closed chan struct{}
 
-	msgCh chan *message
+	queue *sendQueue
 
-	framer  framer.Framer
-	decoder decoder.Decoder
-	encoder encoder.Encoder
+	framer framer.Framer                   // 由 rm 保护
+	dec    atomic.Pointer[decoder.Decoder] // 握手中可替换
+	enc    atomic.Pointer[encoder.Encoder] // 握手中可替换，未替换时指向配置中的 Encoder
 
 	chain *handler.Chain
 
+	layers []boot.Layer // 处理层，自上而下排列
+
+	cm     sync.Mutex
+	calls  map[uint64]chan rpcResult // 等待应答的 RPC 调用
+	callID atomic.Uint64
+
 	rm      sync.Mutex
 	readBuf bytes.Buffer
 
+	reactive bool // 传输层由事件循环驱动，写协程与读缓冲按需创建
+
+	hb *heartbeater // 心跳状态，未启用时为 nil
+	hs *shake       // 握手状态，未启用时为 nil
+
+	um   sync.Mutex // 串行化 Upgrade
+	held bool       // 开启 TLS 中，数据暂存于读缓冲不拆帧，由 rm 保护
+
+	sm    *state.Machine // 生命周期状态机
+	lm    sync.Mutex     // 串行化生命周期迁移
+	phase atomic.Int32   // 当前所处的生命周期阶段，不受自定义状态影响
+
 	startOnce sync.Once
 	closeOnce sync.Once
 
-	active atomic.Bool
-	last   atomic.Int64
+	draining atomic.Bool    // 排空中，不再处理新消息
+	inflight sync.WaitGroup // 在途的消息处理任务
+
+	last atomic.Int64
 }
 
 func NewConn(ctx context.Context, t Transport, cfg *conf.Config, hook hook.ConnHook) *Conn {
@@ -84,24 +128,46 @@ func NewConn(ctx context.Context, t Transport, cfg *conf.Config, hook hook.ConnH
 		Cfg:        cfg,
 		Hook:       hook,
 		Wg:         new(sync.WaitGroup),
-		msgCh:      make(chan *message, 1_000_000_000),
+		queue:      newSendQueue(cfg.SendQueueSize, cfg.SendQueueBytes, cfg.SendQueueHighWatermark, cfg.SendQueueLowWatermark),
 		closed:     make(chan struct{}),
+		calls:      make(map[uint64]chan rpcResult),
 		Attributes: attrs.New[any, any](true),
 	}
 
 	c.Id = cfg.IDGenerator()
 	c.Local = t.LocalAddr()
 	c.Remote = t.RemoteAddr()
-	c.Ctx, c.Cancel = context.WithCancel(ctx)
+	c.Ctx, c.Cancel = context.WithCancelCause(ctx)
 	c.Pool = cfg.Pool
+	if cfg.Ordered {
+		c.Pool = pool.NewSerialExecutor(cfg.Pool, func(r any) { c.Log.Error("conn %s task panic: %v", c.Id, r) })
+	}
 	c.Log = cfg.Logger
 	c.framer = cfg.Framer
-	c.decoder = cfg.Decoder
-	c.encoder = cfg.Encoder
+	if cfg.RPC {
+		c.framer = envelopeFramer
+	}
+	c.dec.Store(&cfg.Decoder)
+	c.enc.Store(&cfg.Encoder)
+	c.layers = newLayers(cfg, hook)
 	c.chain = handler.NewChain(cfg.Handlers...)
 	c.chain.Use(func(ctx handler.Context, next func()) {
 		hook.OnMessage(ctx.Conn(), ctx.Payload())
 	})
+	if cfg.Heartbeat != nil {
+		c.hb = newHeartbeater(c, *cfg.Heartbeat)
+	}
+	if cfg.Handshaker != nil {
+		c.hs = newShake(cfg.Handshaker)
+	}
+	if _, ok := t.(reactive); ok {
+		c.reactive = true
+		c.queue.spawn = c.spawnWriter
+	}
+	c.sm = newMachine(c)
+	if cfg.States != nil {
+		cfg.States(c, c.sm)
+	}
 
 	return c
 }
@@ -113,43 +179,158 @@ func (c *Conn) Context() context.Context     { return c.Ctx }
 func (c *Conn) LocalAddr() net.Addr          { return c.Local }
 func (c *Conn) RemoteAddr() net.Addr         { return c.Remote }
 func (c *Conn) Attrs() attrs.Attrs[any, any] { return c.Attributes }
-func (c *Conn) IsActive() bool               { return c.active.Load() }
+func (c *Conn) IsActive() bool               { return c.active() }
+func (c *Conn) IsClient() bool               { return c.Client }
 
 func (c *Conn) Send(msg any) <-chan error {
+	return c.SendContext(c.Ctx, msg)
+}
+
+// SendContext 发送消息，队列已满且策略为 QueueBlock 时最多阻塞至 ctx 结束
+func (c *Conn) SendContext(ctx context.Context, msg any) <-chan error {
+	return c.send(ctx, kindMessage, 0, msg)
+}
+
+// send 编码消息并入队，启用 RPC 时封装为信封帧
+func (c *Conn) send(ctx context.Context, kind byte, id uint64, msg any) <-chan error {
 	done := make(chan error, 1)
-	if !c.IsActive() {
-		done <- net.ErrClosed
+	buf, err := c.encode(kind, id, msg)
+	if err == nil {
+		err = c.push(ctx, buf, done, msg)
+	}
+	if err != nil {
+		done <- err
 		close(done)
-		return done
 	}
+	return done
+}
 
-	buf, err := c.encoder(c, msg)
+// encode 编码消息，启用 RPC 时封装为信封帧
+func (c *Conn) encode(kind byte, id uint64, msg any) ([]byte, error) {
+	buf, err := (*c.enc.Load())(c, msg)
 	if err != nil {
-		done <- fmt.Errorf("encoder error: %w", err)
-		close(done)
-		return done
+		return nil, fmt.Errorf("encoder error: %w", err)
+	}
+	if c.Cfg.RPC {
+		buf = envelope(kind, id, buf)
 	}
+	return buf, nil
+}
 
-	select {
-	case <-c.Ctx.Done():
-		done <- net.ErrClosed
-		close(done)
-		return done
-	case c.msgCh <- &message{buf: buf, done: done}:
-		c.dispatchSend(msg)
-		return done
+// push 已编码的消息入队，失败时同步返回错误（done 未被使用）
+func (c *Conn) push(ctx context.Context, buf []byte, done chan error, msg any) error {
+	if !c.IsActive() {
+		return net.ErrClosed
 	}
+	if err := c.enqueue(ctx, &message{buf: buf, done: done}, msg); err != nil {
+		return err
+	}
+	c.dispatchSend(msg)
+	return nil
 }
 
+// QueueLen 返回发送队列中的消息数与字节数
+func (c *Conn) QueueLen() (int, int) { return c.queue.len() }
+
+// ConnectionState 返回 TLS 连接状态，非 TLS 连接返回 false
+func (c *Conn) ConnectionState() (tls.ConnectionState, bool) {
+	if t, ok := c.T.(tlsTransport); ok {
+		return t.ConnectionState()
+	}
+	return tls.ConnectionState{}, false
+}
+
+// PeerCertificates 返回对端证书链，非 TLS 连接或对端未提供证书时返回 nil
+func (c *Conn) PeerCertificates() []*x509.Certificate {
+	state, ok := c.ConnectionState()
+	if !ok {
+		return nil
+	}
+	return state.PeerCertificates
+}
+
+// NegotiatedProtocol 返回 ALPN 协商结果，未协商时返回空字符串
+func (c *Conn) NegotiatedProtocol() string {
+	state, ok := c.ConnectionState()
+	if !ok {
+		return ""
+	}
+	return state.NegotiatedProtocol
+}
+
+// Layer 按名称查找处理层
+func (c *Conn) Layer(name string) (boot.Layer, bool) {
+	for _, l := range c.layers {
+		if l.Name() == name {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// Close 关闭连接，最多等待 CloseTimeout 写出发送队列，超时后强制中止传输层
 func (c *Conn) Close() {
 	c.closeOnce.Do(func() {
-		c.Cancel()
+		c.Cancel(nil)
 		select {
 		case <-c.closed:
-		case <-time.After(5 * time.Second):
+		case <-time.After(c.Cfg.CloseTimeout):
 			c.Log.Warn("force close conn %s", c.Id)
+			c.abort()
+		}
+	})
+}
+
+// Drain 排空并关闭连接：不再处理新消息，派发 OnShutdown，等待在途的消息处理完成、
+// 发送队列写出后关闭。ctx 结束时强制中止并返回 false。
+func (c *Conn) Drain(ctx context.Context) bool {
+	c.draining.Store(true)
+	c.enter(lifecycle.Draining)
+	// 等待正在拆帧的读协程退出临界区，此后不再新增在途任务
+	c.rm.Lock()
+	c.rm.Unlock()
+
+	if h, ok := c.Hook.(hook.ShutdownHook); ok {
+		c.submitTracked(func() { h.OnShutdown(c) })
+	}
+
+	idle := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(idle)
+	}()
+
+	drained, closing := false, false
+	c.closeOnce.Do(func() {
+		closing = true
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			c.Cancel(nil)
+			c.abort()
+			return
+		}
+
+		c.Cancel(nil)
+		select {
+		case <-c.closed:
+			drained = true
+		case <-ctx.Done():
+			c.abort()
 		}
 	})
+	if closing {
+		return drained
+	}
+
+	// 连接已在其他地方关闭
+	select {
+	case <-c.closed:
+		return true
+	case <-ctx.Done():
+		c.abort()
+		return false
+	}
 }
 
 // ---- 内部开放接口 ----
@@ -160,16 +341,44 @@ func (c *Conn) LastActive() time.Time { return time.Unix(0, c.last.Load()) }
 func (c *Conn) SubmitTask(task func()) {
 	ok := c.Pool.Submit(task)
 	if !ok {
-		c.dispatchError(fmt.Errorf("fail to submit task: %v", task))
+		c.dispatchError(errors.New("fail to submit task"))
 	}
 }
 
-func (c *Conn) Recv(chunk []byte) {
-	c.rm.Lock()
-	defer c.rm.Unlock()
+// submitTracked 提交计入在途数的任务，排空时等待其完成
+func (c *Conn) submitTracked(task func()) {
+	c.inflight.Add(1)
+	ok := c.Pool.Submit(func() {
+		defer c.inflight.Done()
+		task()
+	})
+	if !ok {
+		c.inflight.Done()
+		c.Log.Error("conn %s fail to submit task", c.Id)
+	}
+}
 
+// Recv 接收传输层数据，经过处理层后进入拆帧流程
+func (c *Conn) Recv(chunk []byte) {
 	// 刷新活跃时间
 	c.Touch()
+	if c.hb != nil {
+		c.hb.recvAt.Store(c.last.Load())
+	}
+
+	if len(c.layers) == 0 {
+		c.recv(chunk)
+		return
+	}
+
+	if err := c.layers[len(c.layers)-1].Read(chunk); err != nil {
+		c.dispatchRead(bytes.Clone(chunk), fmt.Errorf("layer error: %w", err))
+	}
+}
+
+func (c *Conn) recv(chunk []byte) {
+	c.rm.Lock()
+	defer c.rm.Unlock()
 
 	// 追加到粘包缓冲
 	if _, err := c.readBuf.Write(chunk); err != nil {
@@ -177,14 +386,38 @@ func (c *Conn) Recv(chunk []byte) {
 		return
 	}
 
-	// 拆帧
-	frames, rest, err := c.framer(c, c.readBuf.Bytes())
+	// 握手帧处理中或开启 TLS 中，数据暂存于缓冲，待其完成后按（可能已替换的）Framer 拆帧
+	if c.held || c.hs != nil && c.hs.hold() {
+		c.dispatchRead(bytes.Clone(chunk), nil)
+		return
+	}
+
+	frames, err := c.split()
 	if err != nil {
-		c.dispatchRead(bytes.Clone(chunk), fmt.Errorf("framer error: %w", err))
+		c.dispatchRead(bytes.Clone(chunk), err)
 		return
 	}
 
 	c.dispatchRead(bytes.Clone(chunk), nil)
+	c.deliver(frames)
+}
+
+// split 拆分读缓冲中的完整帧并保留剩余数据，调用方需持有 rm；
+// 拆帧失败时返回错误（启用 RPC 时信封帧错位后无法恢复，同时关闭连接）
+func (c *Conn) split() ([][]byte, error) {
+	frames, rest, err := c.framer(c, c.readBuf.Bytes())
+	if err != nil {
+		err = fmt.Errorf("framer error: %w", err)
+		if c.Cfg.RPC {
+			c.fail(err)
+		}
+		return nil, err
+	}
+
+	// 帧可能引用 readBuf，重置缓冲与异步解码前需拷贝
+	for i, frame := range frames {
+		frames[i] = bytes.Clone(frame)
+	}
 
 	// 适度回收：若缓冲非常大且剩余很小，重建缓冲以释放内存
 	const shrinkFactor = 4
@@ -193,57 +426,115 @@ func (c *Conn) Recv(chunk []byte) {
 		nb.Grow(len(rest))
 		_, _ = nb.Write(rest)
 		c.readBuf = nb
+	} else if len(rest) == 0 && c.reactive {
+		c.readBuf = bytes.Buffer{} // 空闲连接不保留读缓冲
 	} else {
 		c.readBuf.Reset()
 		_, _ = c.readBuf.Write(rest)
 	}
+	return frames, nil
+}
 
-	// 解码 & 派发
-	for _, frame := range frames {
-		fr := frame // 避免闭包变量复用
-		c.SubmitTask(func() {
-			msg, err := c.decoder(c, fr)
-			if err != nil {
-				c.dispatchError(fmt.Errorf("decoder error: %w", err))
-				return
-			}
+// deliver 解码并派发帧，握手阶段交由 Handshaker 处理；调用方需持有 rm
+func (c *Conn) deliver(frames [][]byte) {
+	if c.hs != nil && c.hs.pending(frames) {
+		c.nextHandshake()
+		return
+	}
 
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						c.dispatchError(fmt.Errorf("handler process panic: %v", r))
-					}
-				}()
+	for _, frame := range frames {
+		if c.Cfg.RPC {
+			c.recvEnvelope(frame)
+			continue
+		}
+		c.dispatchFrame(frame, nil)
+	}
+}
 
-				c.chain.Handler(handler.NewContext(c, msg))
-			}()
-		})
+// dispatchFrame 异步解码并执行处理器链，reply 非空时为请求帧；排空中的连接丢弃新消息
+func (c *Conn) dispatchFrame(frame []byte, reply func(msg any) <-chan error) {
+	if c.hb != nil && reply == nil && c.heartbeat(frame) {
+		return
 	}
+	if c.draining.Load() {
+		return
+	}
+
+	dec := *c.dec.Load() // 按拆帧时的 Decoder 解码，不受之后的升级影响
+	c.submitTracked(func() {
+		msg, err := dec(c, frame)
+		if err != nil {
+			c.dispatchError(fmt.Errorf("decoder error: %w", err))
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				c.dispatchError(fmt.Errorf("handler process panic: %v", r))
+			}
+		}()
+
+		if reply != nil {
+			c.chain.Handler(handler.NewRequestContext(c, msg, reply))
+		} else {
+			c.chain.Handler(handler.NewContext(c, msg))
+		}
+	})
 }
 
 func (c *Conn) Start(wg *sync.WaitGroup) {
 	c.startOnce.Do(func() {
+		// 先于启动登记，OnConnect 中即可查找与绑定；服务端已开始排空时不再启动
+		if c.Registry != nil && !c.Registry.add(c) {
+			c.Cancel(net.ErrClosed)
+			c.T.Stop(c)
+			c.enter(lifecycle.Closed)
+			close(c.closed) // 未启动即结束，Close 与 Drain 无需等待
+			return
+		}
+
+		wg.Add(1)         // 先于启动协程计入，避免与外部 Wait 竞争
 		go c.mainLoop(wg) // 开始主循环
-		go c.writeLoop()  // 开启写循环
-		c.T.Start(c)      // 启动传输层
+		if !c.reactive {
+			c.Wg.Add(1)
+			go c.writeLoop() // 开启写循环
+		}
+		c.openLayers() // 启动处理层
 
-		c.active.Store(true)
+		if c.hs != nil {
+			c.enter(lifecycle.Handshaking)
+		} else {
+			c.enter(lifecycle.Ready)
+		}
 		c.Touch()
 
-		c.dispatchConnect()
+		c.dispatchConnect() // 先于任何消息派发
+		if c.hs != nil {
+			c.beginHandshake() // 握手成功后派发 OnReady
+		} else {
+			c.dispatchReady()
+		}
+		c.T.Start(c) // 启动传输层
 	})
 }
 
 // mainLoop 连接主要工作循环，处理连接状态
 func (c *Conn) mainLoop(wg *sync.WaitGroup) {
-	wg.Add(1)
 	defer func() { // 最终结束处理
-		wg.Done()         // 结束当前占用的外部WG
-		c.T.Stop(c)       // 结束传输层
-		c.dispatchClose() // 触发关闭回调
-		close(c.closed)   // 触发关闭通道
+		wg.Done()                 // 结束当前占用的外部WG
+		c.closeLayers()           // 结束处理层
+		c.T.Stop(c)               // 结束传输层
+		c.closeHandshake()        // 不再有数据到达，结束未完成的握手
+		c.enter(lifecycle.Closed) // 进入关闭状态，OnClose 中可见
+		c.dispatchClose()         // 触发关闭回调
+		close(c.closed)           // 触发关闭通道
 	}()
 
+	var shakeCh <-chan time.Time
+	if c.hs != nil {
+		shakeCh = time.After(c.Cfg.HandshakeTimeout)
+	}
+
 	var tickCh <-chan time.Time
 	if c.Cfg.TickInterval > 0 {
 		ticker := time.NewTicker(c.Cfg.TickInterval)
@@ -251,6 +542,15 @@ func (c *Conn) mainLoop(wg *sync.WaitGroup) {
 		tickCh = ticker.C
 	}
 
+	// 心跳：按周期发送 ping，Timeout 后检查期间是否收到数据
+	var pingCh, ackCh <-chan time.Time
+	var pingSent time.Time
+	if c.hb != nil && c.hb.opts.Interval > 0 {
+		ping := time.NewTicker(c.hb.opts.Interval)
+		defer ping.Stop()
+		pingCh = ping.C
+	}
+
 	var idleNotified bool
 	var idleCh <-chan time.Time
 	if c.Cfg.IdleTimeout > 0 {
@@ -262,13 +562,32 @@ func (c *Conn) mainLoop(wg *sync.WaitGroup) {
 	for {
 		select {
 		case <-c.Ctx.Done():
-			close(c.msgCh) // 关闭消息队列
-			c.Wg.Wait()    // 等他其他工作线程结束
+			c.enter(lifecycle.Closing)
+			if c.Registry != nil {
+				c.Registry.remove(c) // 移除登记与用户绑定
+			}
+			c.queue.close() // 关闭消息队列
+			c.interrupt()   // 唤醒阻塞中的读协程
+			c.waitWorkers() // 等他其他工作线程结束
 			return
+		case <-shakeCh:
+			shakeCh = nil
+			c.handshakeTimeout()
 		case <-tickCh:
 			c.dispatchTick()
+		case <-pingCh:
+			if ackCh == nil { // 上一次 ping 尚未检查时不重复发送
+				pingSent = c.sendPing()
+				ackCh = time.After(c.hb.opts.Timeout)
+			}
+		case <-ackCh:
+			ackCh = nil
+			if err := c.checkPing(pingSent); err != nil {
+				c.fail(err)
+				c.abort() // 对端已失效，不再等待发送队列写出
+			}
 		case <-idleCh:
-			last := time.Unix(c.last.Load(), 0)
+			last := time.Unix(0, c.last.Load())
 			if time.Since(last) > c.Cfg.IdleTimeout {
 				if !idleNotified {
 					idleNotified = true
@@ -282,31 +601,225 @@ func (c *Conn) mainLoop(wg *sync.WaitGroup) {
 	}
 }
 
+// waitWorkers 等待工作协程退出：最多等待 CloseTimeout，超时后强制中止传输层与处理层，
+// 避免写协程阻塞在无法推进的处理层（如 ARQ 发送窗口）上；排空时由 Drain 的 ctx 限时
+func (c *Conn) waitWorkers() {
+	done := make(chan struct{})
+	go func() {
+		c.Wg.Wait()
+		close(done)
+	}()
+	if c.draining.Load() {
+		<-done
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(c.Cfg.CloseTimeout):
+		c.Log.Warn("force close conn %s", c.Id)
+		c.abort()
+		<-done
+	}
+}
+
 func (c *Conn) writeLoop() {
-	c.Wg.Add(1)
 	defer c.Wg.Done()
+	c.flush(c.queue.pop)
+}
 
-	for msg := range c.msgCh {
-		err := c.T.Write(c, msg.buf)
+// spawnWriter 启动按需写协程，写完队列中的消息后退出；由 sendQueue 持锁调用
+func (c *Conn) spawnWriter() {
+	c.Wg.Add(1)
+	go func() {
+		defer c.Wg.Done()
+		c.flush(c.queue.next)
+	}()
+}
 
-		c.Touch()                     // 刷新获取时间
-		c.dispatchWrite(msg.buf, err) // 调用写入回调
-		msg.done <- err               // 通知发送方
+// flush 逐条取出并写出消息，直到 next 返回 ok=false 或底层连接关闭
+func (c *Conn) flush(next func() (*message, bool, bool)) {
+	for {
+		msg, low, ok := next()
+		if !ok {
+			return
+		}
+		if low {
+			c.dispatchLowWatermark()
+		}
+
+		var err error
+		if msg.down != nil {
+			err = msg.down(msg.buf) // 处理层的帧，不派发写入回调
+			c.Touch()
+		} else {
+			err = c.write(msg.buf)
+			c.Touch()                     // 刷新获取时间
+			c.dispatchWrite(msg.buf, err) // 调用写入回调
+		}
+		msg.done <- err // 通知发送方
 		close(msg.done)
 
 		// 底层连接已关闭 结束循环
 		if errors.Is(err, net.ErrClosed) {
-			// 尝试 drain 剩余数据再退出
-			for m := range c.msgCh {
+			// drain 剩余数据再退出
+			for _, m := range c.queue.drain() {
 				m.done <- net.ErrClosed // 通知发送方连接已关闭
-				close(msg.done)
+				close(m.done)
+			}
+			c.Cancel(err) // 触发关闭信号
+			return
+		}
+	}
+}
+
+// enqueue 消息入队，队列已满时按 SendQueuePolicy 处理
+func (c *Conn) enqueue(ctx context.Context, m *message, msg any) error {
+	notified := false
+	for {
+		pushed, high, space, err := c.queue.push(m)
+		if err != nil {
+			return err
+		}
+		if pushed {
+			if high {
+				c.dispatchHighWatermark()
+			}
+			return nil
+		}
+
+		if !notified {
+			notified = true
+			c.dispatchBackpressure(msg)
+		}
+
+		switch c.Cfg.SendQueuePolicy {
+		case conf.QueueFailFast:
+			n, size := c.queue.len()
+			return &QueueFullError{Messages: n, Bytes: size}
+		case conf.QueueDropOldest:
+			if old, low := c.queue.dropOldest(); old != nil {
+				old.done <- ErrMessageDropped
+				close(old.done)
+				if low {
+					c.dispatchLowWatermark()
+				}
+				continue
+			}
+			// 队列中只有处理层的帧，等待空位
+			if err = c.waitSpace(ctx, space); err != nil {
+				return err
+			}
+		case conf.QueueClose:
+			n, size := c.queue.len()
+			err = &QueueFullError{Messages: n, Bytes: size}
+			c.queue.close() // 后续发送直接失败
+			c.fail(err)
+			return err
+		default:
+			if err = c.waitSpace(ctx, space); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// enqueueFrame 处理层的帧入队，不适用 SendQueuePolicy，队列已满时等待空位
+func (c *Conn) enqueueFrame(ctx context.Context, m *message) error {
+	for {
+		pushed, high, space, err := c.queue.push(m)
+		if err != nil {
+			return err
+		}
+		if pushed {
+			if high {
+				c.dispatchHighWatermark()
 			}
-			c.Cancel() // 触发关闭信号
-			break
+			return nil
+		}
+		if err = c.waitSpace(ctx, space); err != nil {
+			return err
 		}
 	}
 }
 
+// waitSpace 等待发送队列出现空位
+func (c *Conn) waitSpace(ctx context.Context, space <-chan struct{}) error {
+	select {
+	case <-space:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.Ctx.Done():
+		return net.ErrClosed
+	}
+}
+
+// write 经过处理层写出
+func (c *Conn) write(buf []byte) error {
+	if len(c.layers) == 0 {
+		return c.T.Write(c, buf)
+	}
+	return c.layers[0].Write(buf)
+}
+
+// openLayers 自上而下连接各处理层
+func (c *Conn) openLayers() {
+	for i, l := range c.layers {
+		up := c.recv
+		if i > 0 {
+			upper := c.layers[i-1]
+			up = func(buf []byte) {
+				if err := upper.Read(buf); err != nil {
+					c.dispatchError(fmt.Errorf("layer %s error: %w", upper.Name(), err))
+				}
+			}
+		}
+
+		down := func(buf []byte) error { return c.T.Write(c, buf) }
+		if i < len(c.layers)-1 {
+			down = c.layers[i+1].Write
+		}
+
+		queue := func(ctx context.Context, buf []byte, done chan error) error {
+			if done == nil {
+				done = make(chan error, 1)
+			}
+			return c.enqueueFrame(ctx, &message{buf: buf, done: done, down: down})
+		}
+
+		l.Open(c, boot.LayerIO{Up: up, Down: down, Fail: c.fail, Queue: queue})
+	}
+}
+
+func (c *Conn) closeLayers() {
+	for _, l := range c.layers {
+		l.Close()
+	}
+}
+
+func (c *Conn) interrupt() {
+	if t, ok := c.T.(interrupter); ok {
+		t.Interrupt()
+	}
+}
+
+func (c *Conn) abort() {
+	for _, l := range c.layers {
+		if a, ok := l.(aborter); ok {
+			a.Abort() // 唤醒阻塞在处理层中的写
+		}
+	}
+	if t, ok := c.T.(aborter); ok {
+		t.Abort()
+	}
+}
+
+// fail 触发错误回调并关闭连接
+func (c *Conn) fail(err error) {
+	c.dispatchError(err)
+	c.Cancel(err)
+}
+
 // ---- Hook 映射 ----
 func (c *Conn) dispatchConnect()        { c.SubmitTask(func() { c.Hook.OnConnect(c) }) }
 func (c *Conn) dispatchClose()          { c.SubmitTask(func() { c.Hook.OnClose(c) }) }
@@ -321,9 +834,23 @@ func (c *Conn) dispatchRead(buf []byte, err error) {
 	c.SubmitTask(func() { c.Hook.OnRead(c, buf, err) })
 }
 func (c *Conn) dispatchMessage(msg any) { c.SubmitTask(func() { c.Hook.OnMessage(c, msg) }) }
-
-// message 定义发送消息
-type message struct {
-	buf  []byte
-	done chan error
+func (c *Conn) dispatchReady() {
+	if h, ok := c.Hook.(hook.ReadyHook); ok {
+		c.SubmitTask(func() { h.OnReady(c) })
+	}
+}
+func (c *Conn) dispatchBackpressure(msg any) {
+	if h, ok := c.Hook.(hook.BackpressureHook); ok {
+		c.SubmitTask(func() { h.OnBackpressure(c, msg) })
+	}
+}
+func (c *Conn) dispatchHighWatermark() {
+	if h, ok := c.Hook.(hook.BackpressureHook); ok {
+		c.SubmitTask(func() { h.OnHighWatermark(c) })
+	}
+}
+func (c *Conn) dispatchLowWatermark() {
+	if h, ok := c.Hook.(hook.BackpressureHook); ok {
+		c.SubmitTask(func() { h.OnLowWatermark(c) })
+	}
 }
@@ -0,0 +1,146 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/reactor"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// EpollTransport 由事件循环驱动读取的流式传输层：套接字可读时才在循环协程中读取一次，
+// 数据交给按需启动的协程送入会话层，不占用常驻读协程与读缓冲；写入与 NETTransport 相同
+type EpollTransport struct {
+	*NETTransport
+
+	r  *reactor.Reactor
+	rc syscall.RawConn
+
+	mu      sync.Mutex
+	reg     *reactor.Registration // 注册失败退化为读协程时为 nil
+	stopped bool                  // 已取消注册，不再交付数据
+}
+
+// NewEpollConn 创建由事件循环驱动读取的连接。仅直接读取 *net.TCPConn / *net.UnixConn，
+// 其他连接（如 TLS / PROXY 包装，读取前可能已有缓冲数据）退化为 NewNETConn
+func NewEpollConn(ctx context.Context, raw net.Conn, cfg *conf.Config, hook hook.ConnHook, r *reactor.Reactor) *Conn {
+	var sc syscall.Conn
+	switch rc := raw.(type) {
+	case *net.TCPConn:
+		sc = rc
+	case *net.UnixConn:
+		sc = rc
+	default:
+		return NewNETConn(ctx, raw, cfg, hook)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return NewNETConn(ctx, raw, cfg, hook)
+	}
+	t := &EpollTransport{NETTransport: newNETTransport(raw, cfg), r: r, rc: rc}
+	c := NewConn(ctx, t, cfg, hook)
+	setPeerCred(c, raw)
+	return c
+}
+
+func (et *EpollTransport) Reactive() {}
+
+func (et *EpollTransport) Start(c *Conn) {
+	et.mu.Lock()
+	defer et.mu.Unlock() // 注册完成前到达的事件在 unregister 处等待
+	reg, err := et.r.Register(et.rc, func(buf []byte) { et.readable(c, buf) })
+	if err != nil {
+		c.Log.Warn("conn %s: %s, fallback to read goroutine", c.Id, err)
+		et.NETTransport.Start(c)
+		return
+	}
+	et.reg = reg
+}
+
+// readable 套接字可读时在事件循环协程中调用，只读取不处理：处理层、拆帧与任务提交都可能阻塞，
+// 数据交给按需启动的协程送入会话层，交付完成后才重新开启通知，保证顺序且不拖慢同一循环上的其他连接
+func (et *EpollTransport) readable(c *Conn, buf []byte) {
+	n, err := reactor.Read(et.rc, buf)
+	if n == 0 && err == nil {
+		et.resume() // 虚假唤醒
+		return
+	}
+	var chunk []byte
+	if n > 0 {
+		chunk = append([]byte(nil), buf[:n]...)
+	}
+
+	et.mu.Lock()
+	if et.stopped {
+		et.mu.Unlock()
+		return
+	}
+	c.Wg.Add(1) // 先于取消注册计入，关闭时等待交付结束
+	et.mu.Unlock()
+
+	go func() {
+		defer c.Wg.Done()
+		et.deliver(c, chunk, err)
+	}()
+}
+
+// deliver 将读到的数据送入会话层，读取出错时结束连接
+func (et *EpollTransport) deliver(c *Conn, chunk []byte, err error) {
+	if len(chunk) > 0 {
+		c.Recv(chunk)
+	}
+	if err == nil {
+		et.resume()
+		return
+	}
+
+	// 水平触发下对端关闭会持续可读，先取消注册再触发会话关闭
+	et.unregister()
+	if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+		c.dispatchError(err)
+	}
+	c.Cancel(err)
+}
+
+// resume 重新开启可读通知
+func (et *EpollTransport) resume() {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	if !et.stopped {
+		et.reg.Resume()
+	}
+}
+
+// unregister 取消事件循环注册，未注册（尚未启动或已退化为读协程）时返回 false
+func (et *EpollTransport) unregister() bool {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+	if et.reg == nil {
+		return false
+	}
+	et.stopped = true
+	et.reg.Close()
+	return true
+}
+
+func (et *EpollTransport) Stop(c *Conn) {
+	et.unregister() // 须先于关闭套接字，避免文件描述符复用后误删
+	_ = et.raw.Close()
+}
+
+// Interrupt 停止读取；退化为读协程时使阻塞中的读立即超时返回
+func (et *EpollTransport) Interrupt() {
+	if !et.unregister() {
+		et.NETTransport.Interrupt()
+	}
+}
+
+// Abort 立即关闭底层连接，未写出的数据被丢弃
+func (et *EpollTransport) Abort() {
+	et.unregister()
+	_ = et.raw.Close()
+}
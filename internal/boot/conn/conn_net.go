@@ -2,23 +2,47 @@ package conn
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/yurazsb/uno/internal/conf"
 	"github.com/yurazsb/uno/internal/hook"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type NETTransport struct {
 	raw net.Conn
 	cfg *conf.Config
+
+	local  net.Addr
+	remote net.Addr
+	state  atomic.Pointer[tls.ConnectionState] // TLS 握手结果，非 TLS 连接为 nil
+
+	tc   atomic.Pointer[tls.Conn] // 运行时开启的 TLS 连接，读写经由它进行
+	wm   sync.Mutex               // 写锁，开启 TLS 期间暂停写出
+	park atomic.Pointer[parking]  // 非 nil 时读协程暂停读取
+}
+
+// parking 暂停读协程：读协程关闭 parked 后等待 resume 关闭
+type parking struct {
+	parked chan struct{}
+	resume chan struct{}
 }
 
 func NewNETConn(ctx context.Context, raw net.Conn, cfg *conf.Config, hook hook.ConnHook) *Conn {
+	c := NewConn(ctx, newNETTransport(raw, cfg), cfg, hook)
+	setPeerCred(c, raw)
+	setProxyHeader(c, raw)
+	return c
+}
+
+func newNETTransport(raw net.Conn, cfg *conf.Config) *NETTransport {
 	// TCP 优化
-	if t, ok := raw.(*net.TCPConn); ok {
+	if t, ok := unwrap[*net.TCPConn](raw); ok {
 		if cfg.KeepAlive {
 			_ = t.SetKeepAlive(true)
 			if cfg.KeepAlivePeriod > 0 {
@@ -29,24 +53,37 @@ func NewNETConn(ctx context.Context, raw net.Conn, cfg *conf.Config, hook hook.C
 			_ = t.SetNoDelay(true)
 		}
 	}
-	t := &NETTransport{raw: raw, cfg: cfg}
-	c := NewConn(ctx, t, cfg, hook)
-	return c
+	nt := &NETTransport{raw: raw, cfg: cfg, local: raw.LocalAddr(), remote: raw.RemoteAddr()}
+	nt.state.Store(tlsState(raw))
+	return nt
+}
+
+// conn 当前读写的连接，开启 TLS 后为 TLS 连接
+func (nt *NETTransport) conn() net.Conn {
+	if tc := nt.tc.Load(); tc != nil {
+		return tc
+	}
+	return nt.raw
 }
 
 func (nt *NETTransport) LocalAddr() net.Addr {
-	return nt.raw.LocalAddr()
+	return nt.local
 }
 
 func (nt *NETTransport) RemoteAddr() net.Addr {
-	return nt.raw.RemoteAddr()
+	return nt.remote
 }
 
-func (nt *NETTransport) Write(c *Conn, buf []byte) error {
-	if nt.raw == nil {
-		return net.ErrClosed
+// ConnectionState 返回 TLS 连接状态，非 TLS 连接返回 false
+func (nt *NETTransport) ConnectionState() (tls.ConnectionState, bool) {
+	state := nt.state.Load()
+	if state == nil {
+		return tls.ConnectionState{}, false
 	}
+	return *state, true
+}
 
+func (nt *NETTransport) Write(c *Conn, buf []byte) error {
 	// UDP MTU 检查
 	if _, ok := nt.raw.(*net.UDPConn); ok && nt.cfg.MTU > 0 && len(buf) > nt.cfg.MTU {
 		return fmt.Errorf("udp: payload exceeds MTU")
@@ -58,8 +95,11 @@ func (nt *NETTransport) Write(c *Conn, buf []byte) error {
 		timeout = WriteTimeout
 	}
 
-	_ = nt.raw.SetWriteDeadline(time.Now().Add(timeout))
-	_, err := nt.raw.Write(buf)
+	nt.wm.Lock()
+	defer nt.wm.Unlock()
+	conn := nt.conn()
+	_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := conn.Write(buf)
 	return err
 }
 
@@ -76,8 +116,18 @@ func (nt *NETTransport) Start(c *Conn) {
 			default:
 			}
 
-			_ = nt.raw.SetReadDeadline(time.Now().Add(ReadTimeout))
-			n, err := nt.raw.Read(buf)
+			conn := nt.conn()
+			_ = conn.SetReadDeadline(time.Now().Add(ReadTimeout))
+			if p := nt.park.Load(); p != nil { // 设置读超时之后检查，避免覆盖暂停时的唤醒
+				close(p.parked)
+				select {
+				case <-p.resume:
+					continue
+				case <-c.Context().Done():
+					return
+				}
+			}
+			n, err := conn.Read(buf)
 			if n > 0 {
 				chunk := append([]byte(nil), buf[:n]...)
 				c.Recv(chunk)
@@ -86,7 +136,7 @@ func (nt *NETTransport) Start(c *Conn) {
 			if err != nil {
 				// 本端关闭 / 对端 EOF → 触发会话关闭信号
 				if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
-					c.Cancel()
+					c.Cancel(err)
 					return
 				}
 
@@ -98,7 +148,7 @@ func (nt *NETTransport) Start(c *Conn) {
 
 				// 不可恢复错误 → 触发 错误回调 和 会话关闭信号
 				c.dispatchError(err)
-				c.Cancel()
+				c.Cancel(err)
 				return
 			}
 		}
@@ -106,8 +156,15 @@ func (nt *NETTransport) Start(c *Conn) {
 }
 
 func (nt *NETTransport) Stop(c *Conn) {
-	if nt.raw != nil {
-		_ = nt.raw.Close()
-		nt.raw = nil
-	}
+	_ = nt.conn().Close()
+}
+
+// Interrupt 使阻塞中的读立即超时返回
+func (nt *NETTransport) Interrupt() {
+	_ = nt.raw.SetReadDeadline(time.Now())
+}
+
+// Abort 立即关闭底层连接，未写出的数据被丢弃
+func (nt *NETTransport) Abort() {
+	_ = nt.raw.Close()
 }
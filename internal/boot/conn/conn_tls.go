@@ -0,0 +1,118 @@
+package conn
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/yurazsb/uno/internal/conf"
+	"net"
+	"time"
+)
+
+// tlsTransport 支持 TLS 的传输层
+type tlsTransport interface {
+	ConnectionState() (tls.ConnectionState, bool)
+}
+
+// tlsState 获取已完成握手的 TLS 连接状态，非 TLS 连接返回 nil
+func tlsState(raw net.Conn) *tls.ConnectionState {
+	tc, ok := raw.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tc.ConnectionState()
+	return &state
+}
+
+// TLSServer 以服务端身份包装原始连接并完成 TLS 握手。
+// 握手失败时会关闭原始连接。
+func TLSServer(ctx context.Context, raw net.Conn, cfg *conf.Config) (*tls.Conn, error) {
+	tc := tls.Server(raw, cfg.TLSConfig)
+	return tc, tlsHandshake(ctx, tc, cfg)
+}
+
+// TLSClient 以客户端身份包装原始连接并完成 TLS 握手。
+// TLSConfig 未设置 ServerName 且未跳过校验时，使用 addr 中的主机名。
+// 握手失败时会关闭原始连接。
+func TLSClient(ctx context.Context, raw net.Conn, cfg *conf.Config, addr string) (*tls.Conn, error) {
+	conn := tls.Client(raw, clientTLSConfig(cfg.TLSConfig, addr))
+	return conn, tlsHandshake(ctx, conn, cfg)
+}
+
+// clientTLSConfig 未设置 ServerName 且未跳过校验时，以 addr 中的主机名作为 ServerName
+func clientTLSConfig(tc *tls.Config, addr string) *tls.Config {
+	if tc.ServerName == "" && !tc.InsecureSkipVerify {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		tc = tc.Clone()
+		tc.ServerName = host
+	}
+	return tc
+}
+
+func tlsHandshake(ctx context.Context, tc *tls.Conn, cfg *conf.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.TLSHandshakeTimeout)
+	defer cancel()
+
+	if err := tc.HandshakeContext(ctx); err != nil {
+		_ = tc.Close()
+		return fmt.Errorf("tls handshake failed: %w", err)
+	}
+	return nil
+}
+
+// startTLS 在明文连接上开启 TLS：暂停写出与读协程，以 prefix 返回的已读出但尚未拆帧的数据作为握手的开头，
+// 握手完成后读写经由 TLS 连接进行
+func (nt *NETTransport) startTLS(ctx context.Context, c *Conn, tc *tls.Config, prefix func() []byte) error {
+	nt.wm.Lock()
+	defer nt.wm.Unlock()
+
+	p := &parking{parked: make(chan struct{}), resume: make(chan struct{})}
+	nt.park.Store(p)
+	defer func() {
+		nt.park.Store(nil)
+		close(p.resume)
+	}()
+	_ = nt.raw.SetReadDeadline(time.Now()) // 唤醒阻塞中的读
+	select {
+	case <-p.parked:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.Ctx.Done():
+		return net.ErrClosed
+	}
+
+	_ = nt.raw.SetReadDeadline(time.Time{})
+	pc := &prefixConn{Conn: nt.raw, buf: prefix()}
+	var conn *tls.Conn
+	if c.Client {
+		conn = tls.Client(pc, clientTLSConfig(tc, c.DialAddr)) // 以拨号时的主机名而非解析后的对端 IP 校验证书
+	} else {
+		conn = tls.Server(pc, tc)
+	}
+	if err := tlsHandshake(ctx, conn, nt.cfg); err != nil {
+		return err
+	}
+	nt.tc.Store(conn)
+	nt.state.Store(tlsState(conn))
+	return nil
+}
+
+// prefixConn 先读出 buf 中的数据，再从底层连接读取
+type prefixConn struct {
+	net.Conn
+	buf []byte
+}
+
+func (pc *prefixConn) Read(b []byte) (int, error) {
+	if len(pc.buf) > 0 {
+		n := copy(b, pc.buf)
+		pc.buf = pc.buf[n:]
+		return n, nil
+	}
+	return pc.Conn.Read(b)
+}
+
+func (pc *prefixConn) NetConn() net.Conn { return pc.Conn }
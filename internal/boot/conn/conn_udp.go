@@ -6,8 +6,11 @@ import (
 	"github.com/yurazsb/uno/internal/boot"
 	"github.com/yurazsb/uno/internal/conf"
 	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/lifecycle"
+	"github.com/yurazsb/uno/internal/proxyproto"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,6 +21,14 @@ type udpKey struct {
 	port uint16
 }
 
+// sessionKey 生成伪连接索引键：UDP 地址使用 udpKey，其他地址（如 unixgram 路径）使用字符串
+func sessionKey(a net.Addr) any {
+	if ua, ok := a.(*net.UDPAddr); ok {
+		return ucKey(ua)
+	}
+	return a.String()
+}
+
 func ucKey(a *net.UDPAddr) udpKey {
 	var k udpKey
 	if ip4 := a.IP.To4(); ip4 != nil {
@@ -31,31 +42,66 @@ func ucKey(a *net.UDPAddr) udpKey {
 }
 
 type UDPSession struct {
-	raw     *net.UDPConn
+	raw     net.PacketConn
 	cfg     *conf.Config
 	hook    hook.ConnHook
 	log     boot.Logger
-	connMap sync.Map // udpKey -> *Conn
+	reg     *Registry    // 伪连接同时登记到服务端连接管理器，可按 ID 查找
+	connMap sync.Map     // sessionKey -> *Conn
+	batch   *batchWriter // 批量发送，未启用时为 nil
+
+	draining atomic.Bool // 优雅关闭中，不再创建新的伪连接
+}
+
+func NewUDPSession(raw net.PacketConn, cfg *conf.Config, hook hook.ConnHook, reg *Registry) *UDPSession {
+	us := &UDPSession{raw: raw, cfg: cfg, hook: hook, log: cfg.Logger, reg: reg}
+	if cfg.UDPBatchSize > 1 {
+		us.batch = newBatchWriter(raw, cfg)
+	}
+	return us
 }
 
-func NewUDPSession(raw *net.UDPConn, cfg *conf.Config, hook hook.ConnHook) *UDPSession {
-	return &UDPSession{raw: raw, cfg: cfg, hook: hook, log: cfg.Logger}
+// Close 停止批量发送，需在所有伪连接结束后调用
+func (us *UDPSession) Close() {
+	if us.batch != nil {
+		us.batch.close()
+	}
 }
 
-func (us *UDPSession) Delivery(ctx context.Context, wg *sync.WaitGroup, remote *net.UDPAddr, buf []byte) {
-	key := ucKey(remote)
+// Delivery 将数据报投递给对端的伪连接，不存在时创建；
+// hdr 为数据报携带的 PROXY 协议头部（可为 nil），创建伪连接时据此设置其 RemoteAddr，回包仍发往 remote
+func (us *UDPSession) Delivery(ctx context.Context, wg *sync.WaitGroup, remote net.Addr, hdr *proxyproto.Header, buf []byte) {
+	// 未绑定地址的 unixgram 发送方无法回包，直接丢弃
+	if remote == nil || remote.String() == "" {
+		return
+	}
+
+	key := sessionKey(remote)
 	val, ok := us.connMap.Load(key)
 
 	if !ok {
+		if us.draining.Load() {
+			return
+		}
 		// 为该 remote 创建一个伪连接
 		ut := newUDPChildTransport(us, us.raw, remote)
+		if hdr != nil {
+			ut.client = hdr.Source
+		}
 		uc := NewConn(ctx, ut, us.cfg, us.hook)
+		uc.Registry = us.reg
+		if hdr != nil {
+			uc.Attributes.Set(AttrProxyHeader, hdr)
+		}
 		actual, loaded := us.connMap.LoadOrStore(key, uc)
 		if loaded {
 			val = actual
 		} else {
 			val = uc
 			uc.Start(wg)
+			if uc.State() == lifecycle.Closed {
+				return // 服务端已开始排空，伪连接未启动即结束
+			}
 		}
 	}
 
@@ -68,6 +114,11 @@ func (us *UDPSession) Delivery(ctx context.Context, wg *sync.WaitGroup, remote *
 	}
 }
 
+// Drain 停止为新的对端创建伪连接，已有伪连接继续收发
+func (us *UDPSession) Drain() {
+	us.draining.Store(true)
+}
+
 func (us *UDPSession) Reaper(idle time.Duration) {
 	now := time.Now()
 	us.connMap.Range(func(k, v any) bool {
@@ -81,7 +132,7 @@ func (us *UDPSession) Reaper(idle time.Duration) {
 	})
 }
 
-func (us *UDPSession) Clear(remotes ...*net.UDPAddr) {
+func (us *UDPSession) Clear(remotes ...net.Addr) {
 	if len(remotes) == 0 {
 		us.connMap.Range(func(key, val interface{}) bool {
 			uc := val.(*Conn)
@@ -93,7 +144,7 @@ func (us *UDPSession) Clear(remotes ...*net.UDPAddr) {
 	}
 
 	for _, remote := range remotes {
-		key := ucKey(remote)
+		key := sessionKey(remote)
 		val, loaded := us.connMap.LoadAndDelete(key)
 		if loaded {
 			uc := val.(*Conn)
@@ -104,13 +155,14 @@ func (us *UDPSession) Clear(remotes ...*net.UDPAddr) {
 
 type UDPTransport struct {
 	session *UDPSession
-	raw     *net.UDPConn
-	remote  *net.UDPAddr
+	raw     net.PacketConn
+	remote  net.Addr
+	client  net.Addr // PROXY 协议头部中的客户端地址，为 nil 时即 remote
 	recvCh  chan []byte
 	cfg     *conf.Config
 }
 
-func newUDPChildTransport(us *UDPSession, raw *net.UDPConn, remote *net.UDPAddr) *UDPTransport {
+func newUDPChildTransport(us *UDPSession, raw net.PacketConn, remote net.Addr) *UDPTransport {
 	return &UDPTransport{
 		session: us,
 		raw:     raw,
@@ -125,6 +177,9 @@ func (ut *UDPTransport) LocalAddr() net.Addr {
 }
 
 func (ut *UDPTransport) RemoteAddr() net.Addr {
+	if ut.client != nil {
+		return ut.client
+	}
 	return ut.remote
 }
 
@@ -134,7 +189,7 @@ func (ut *UDPTransport) Write(c *Conn, buf []byte) error {
 	}
 
 	// UDP MTU 检查
-	if ut.cfg.MTU > 0 && len(buf) > ut.cfg.MTU {
+	if _, ok := ut.remote.(*net.UDPAddr); ok && ut.cfg.MTU > 0 && len(buf) > ut.cfg.MTU {
 		return fmt.Errorf("udp: payload exceeds MTU")
 	}
 
@@ -144,8 +199,12 @@ func (ut *UDPTransport) Write(c *Conn, buf []byte) error {
 		timeout = WriteTimeout
 	}
 
+	if ut.session != nil && ut.session.batch != nil {
+		return ut.session.batch.write(buf, ut.remote)
+	}
+
 	_ = ut.raw.SetWriteDeadline(time.Now().Add(timeout))
-	_, err := ut.raw.WriteToUDP(buf, ut.remote)
+	_, err := ut.raw.WriteTo(buf, ut.remote)
 	return err
 }
 
@@ -157,10 +216,7 @@ func (ut *UDPTransport) Start(c *Conn) {
 			select {
 			case <-c.Context().Done():
 				return
-			case buf, ok := <-ut.recvCh:
-				if !ok {
-					return
-				}
+			case buf := <-ut.recvCh:
 				c.Recv(buf) // Delivery 已拷贝过，无需二次 copy
 			}
 		}
@@ -170,8 +226,8 @@ func (ut *UDPTransport) Start(c *Conn) {
 func (ut *UDPTransport) Stop(c *Conn) {
 	// 从 session 的 map 删除（注意 key 类型一致）
 	if ut.session != nil && ut.remote != nil {
-		key := ucKey(ut.remote)
+		key := sessionKey(ut.remote)
 		ut.session.connMap.Delete(key)
 	}
-	close(ut.recvCh)
+	// 不关闭 recvCh：服务端读循环可能仍持有该伪连接并在投递中，读协程随 Context 结束退出
 }
@@ -0,0 +1,398 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const WSHandshakeTimeout time.Duration = 10 * time.Second
+const WSFrameTimeout time.Duration = 30 * time.Second
+
+// WebSocket 操作码（RFC 6455 5.2）
+const (
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+)
+
+// WebSocket 关闭状态码（RFC 6455 7.4.1）
+const (
+	wsCloseNormal          uint16 = 1000
+	wsCloseGoingAway       uint16 = 1001
+	wsCloseProtocolError   uint16 = 1002
+	wsCloseInvalidPayload  uint16 = 1007
+	wsCloseMessageTooLarge uint16 = 1009
+	wsCloseNoStatus        uint16 = 1005
+)
+
+// IsWSNetwork 判断是否为 WebSocket 网络类型
+func IsWSNetwork(network string) bool {
+	return network == "ws" || network == "wss"
+}
+
+// wsError 协议错误，携带需回发给对端的关闭状态码
+type wsError struct {
+	code uint16
+	msg  string
+}
+
+func (e *wsError) Error() string { return fmt.Sprintf("websocket: %s", e.msg) }
+
+// WSTransport WebSocket 传输层，每条 WebSocket 消息作为一次 Recv 交给 Framer。
+type WSTransport struct {
+	raw    net.Conn
+	br     *bufio.Reader // 握手阶段的读缓冲，可能已预读部分帧数据
+	cfg    *conf.Config
+	client bool // 客户端发送的帧需要掩码
+
+	local  net.Addr
+	remote net.Addr
+
+	state *tls.ConnectionState // wss 握手结果，ws 为 nil
+
+	wm        sync.Mutex
+	closeSent bool
+}
+
+// NewWSConn 基于已完成握手的连接创建 WebSocket 会话
+func NewWSConn(ctx context.Context, raw net.Conn, br *bufio.Reader, cfg *conf.Config, hook hook.ConnHook, client bool) *Conn {
+	t := &WSTransport{
+		raw:    raw,
+		br:     br,
+		cfg:    cfg,
+		client: client,
+		local:  raw.LocalAddr(),
+		remote: raw.RemoteAddr(),
+		state:  tlsState(raw),
+	}
+	c := NewConn(ctx, t, cfg, hook)
+	c.Client = client
+	setProxyHeader(c, raw)
+	return c
+}
+
+func (wt *WSTransport) LocalAddr() net.Addr {
+	return wt.local
+}
+
+func (wt *WSTransport) RemoteAddr() net.Addr {
+	return wt.remote
+}
+
+// ConnectionState 返回 wss 的 TLS 连接状态，ws 返回 false
+func (wt *WSTransport) ConnectionState() (tls.ConnectionState, bool) {
+	if wt.state == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *wt.state, true
+}
+
+func (wt *WSTransport) Write(c *Conn, buf []byte) error {
+	op := wsOpBinary
+	if wt.cfg.WSText {
+		op = wsOpText
+	}
+	return wt.writeFrame(op, buf)
+}
+
+func (wt *WSTransport) Start(c *Conn) {
+	c.Wg.Add(1)
+	go func() {
+		defer c.Wg.Done()
+
+		var msg []byte // 分片消息缓冲
+		var msgOp byte // 分片消息的首帧类型
+		var fragmented bool
+
+		for {
+			select {
+			case <-c.Context().Done():
+				return
+			default:
+			}
+
+			// 等待下一帧到达（短超时便于响应关闭）
+			_ = wt.raw.SetReadDeadline(time.Now().Add(ReadTimeout))
+			if _, err := wt.br.Peek(1); err != nil {
+				var ne net.Error
+				if errors.As(err, &ne) && ne.Timeout() {
+					continue
+				}
+				wt.fail(c, err)
+				return
+			}
+
+			// 帧已开始，整帧读取使用较长的超时
+			_ = wt.raw.SetReadDeadline(time.Now().Add(WSFrameTimeout))
+			fin, op, payload, err := wt.readFrame()
+			if err != nil {
+				wt.fail(c, err)
+				return
+			}
+
+			switch op {
+			case wsOpPing:
+				_ = wt.writeFrame(wsOpPong, payload)
+				continue
+			case wsOpPong:
+				c.Touch()
+				continue
+			case wsOpClose:
+				code, err := wsCloseCode(payload)
+				if err != nil {
+					wt.fail(c, err)
+					return
+				}
+				wt.close(code)
+				c.Cancel(io.EOF)
+				return
+			case wsOpContinuation:
+				if !fragmented {
+					wt.fail(c, &wsError{wsCloseProtocolError, "unexpected continuation frame"})
+					return
+				}
+			case wsOpText, wsOpBinary:
+				if fragmented {
+					wt.fail(c, &wsError{wsCloseProtocolError, "expected continuation frame"})
+					return
+				}
+				msgOp = op
+				msg = msg[:0]
+			default:
+				wt.fail(c, &wsError{wsCloseProtocolError, fmt.Sprintf("unknown opcode %d", op)})
+				return
+			}
+
+			if len(msg)+len(payload) > wt.cfg.WSMaxMessageSize {
+				wt.fail(c, &wsError{wsCloseMessageTooLarge, "message too large"})
+				return
+			}
+			msg = append(msg, payload...)
+			fragmented = !fin
+			if fragmented {
+				continue
+			}
+
+			if msgOp == wsOpText && !utf8.Valid(msg) {
+				wt.fail(c, &wsError{wsCloseInvalidPayload, "invalid utf-8 text message"})
+				return
+			}
+			c.Recv(append([]byte(nil), msg...))
+		}
+	}()
+}
+
+func (wt *WSTransport) Stop(c *Conn) {
+	wt.close(wsCloseGoingAway)
+	_ = wt.raw.Close()
+}
+
+// Interrupt 使阻塞中的读立即超时返回
+func (wt *WSTransport) Interrupt() {
+	_ = wt.raw.SetReadDeadline(time.Now())
+}
+
+// Abort 立即关闭底层连接，未写出的数据被丢弃
+func (wt *WSTransport) Abort() {
+	_ = wt.raw.Close()
+}
+
+// fail 处理读错误：对端关闭直接结束，协议错误回发关闭帧，其余错误触发错误回调
+func (wt *WSTransport) fail(c *Conn, err error) {
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) || c.Ctx.Err() != nil {
+		c.Cancel(err)
+		return
+	}
+
+	var we *wsError
+	if errors.As(err, &we) {
+		wt.close(we.code)
+	}
+	c.dispatchError(err)
+	c.Cancel(err)
+}
+
+// close 发送关闭帧（仅一次）
+func (wt *WSTransport) close(code uint16) {
+	wt.wm.Lock()
+	sent := wt.closeSent
+	wt.closeSent = true
+	wt.wm.Unlock()
+	if sent {
+		return
+	}
+
+	var payload []byte
+	if code != wsCloseNoStatus {
+		payload = binary.BigEndian.AppendUint16(nil, code)
+	}
+	_ = wt.write(wsOpClose, payload, time.Second)
+}
+
+// wsCloseCode 解析并校验对端关闭帧的状态码与原因（RFC 6455 5.5.1、7.4），作为回发的状态码；
+// 无状态码时按正常关闭回应
+func wsCloseCode(payload []byte) (uint16, error) {
+	if len(payload) == 0 {
+		return wsCloseNormal, nil
+	}
+	if len(payload) == 1 {
+		return 0, &wsError{wsCloseProtocolError, "invalid close frame"}
+	}
+	code := binary.BigEndian.Uint16(payload)
+	if !wsValidCloseCode(code) {
+		return 0, &wsError{wsCloseProtocolError, fmt.Sprintf("invalid close code %d", code)}
+	}
+	if !utf8.Valid(payload[2:]) {
+		return 0, &wsError{wsCloseInvalidPayload, "invalid utf-8 close reason"}
+	}
+	return code, nil
+}
+
+// wsValidCloseCode 可出现在关闭帧中的状态码：已定义的协议状态码与 3000-4999 的库、应用状态码。
+// 1004 保留，1005、1006、1015 仅用于本地报告，不得发送
+func wsValidCloseCode(code uint16) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	default:
+		return code >= 3000 && code <= 4999
+	}
+}
+
+// readFrame 读取并校验一帧，返回已去掩码的负载
+func (wt *WSTransport) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(wt.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0F
+	if head[0]&0x70 != 0 {
+		err = &wsError{wsCloseProtocolError, "reserved bits set"}
+		return
+	}
+
+	masked := head[1]&0x80 != 0
+	if masked == wt.client {
+		// 客户端发出的帧必须掩码，服务端发出的帧不得掩码
+		err = &wsError{wsCloseProtocolError, "invalid frame masking"}
+		return
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(wt.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(wt.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if op >= wsOpClose && (length > 125 || !fin) {
+		err = &wsError{wsCloseProtocolError, "invalid control frame"}
+		return
+	}
+	if length > uint64(wt.cfg.WSMaxMessageSize) {
+		err = &wsError{wsCloseMessageTooLarge, "frame too large"}
+		return
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(wt.br, key[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(wt.br, payload); err != nil {
+		return
+	}
+	if masked {
+		wsMask(key, payload)
+	}
+	return
+}
+
+// writeFrame 写出单帧（不分片）
+func (wt *WSTransport) writeFrame(op byte, payload []byte) error {
+	timeout := wt.cfg.WriteTimeout
+	if timeout <= 0 {
+		timeout = WriteTimeout
+	}
+
+	wt.wm.Lock()
+	closed := wt.closeSent
+	wt.wm.Unlock()
+	if closed {
+		return net.ErrClosed
+	}
+	return wt.write(op, payload, timeout)
+}
+
+func (wt *WSTransport) write(op byte, payload []byte, timeout time.Duration) error {
+	frame := make([]byte, 0, len(payload)+14)
+	frame = append(frame, 0x80|op)
+
+	var maskBit byte
+	if wt.client {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	if wt.client {
+		var key [4]byte
+		binary.BigEndian.PutUint32(key[:], rand.Uint32())
+		frame = append(frame, key[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		wsMask(key, frame[start:])
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	wt.wm.Lock()
+	defer wt.wm.Unlock()
+	_ = wt.raw.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := wt.raw.Write(frame)
+	return err
+}
+
+// wsMask 原地掩码/去掩码
+func wsMask(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
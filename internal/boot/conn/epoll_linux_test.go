@@ -0,0 +1,136 @@
+//go:build linux
+
+package conn
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/reactor"
+)
+
+// newEpollPair 建立 TCP 回环连接，服务端一侧为由 r 驱动读取的连接
+func newEpollPair(t *testing.T, r *reactor.Reactor, cfg *conf.Config, h *wsHook) (*Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.WithDefault()
+	c := NewEpollConn(context.Background(), raw, cfg, h, r)
+	if _, ok := c.T.(*EpollTransport); !ok {
+		t.Fatalf("transport = %T, want *EpollTransport", c.T)
+	}
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		_ = client.Close()
+		c.Close()
+		wg.Wait()
+	})
+	return c, client
+}
+
+// TestEpollBlockingRecv 会话层阻塞（如处理层、Framer）不占用事件循环，同一循环上的其他连接照常读取
+func TestEpollBlockingRecv(t *testing.T) {
+	r, err := reactor.New(1, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	gate := make(chan struct{})
+	var gateOnce sync.Once
+	release := func() { gateOnce.Do(func() { close(gate) }) }
+	defer release()
+	blocking := func(c boot.Conn, buf []byte) ([][]byte, []byte, error) {
+		<-gate
+		return framer.RawFramer()(c, buf)
+	}
+
+	ha := &wsHook{msgs: make(chan string, 16), errs: make(chan error, 16)}
+	hb := &wsHook{msgs: make(chan string, 16), errs: make(chan error, 16)}
+	_, ca := newEpollPair(t, r, &conf.Config{Framer: blocking}, ha)
+	_, cb := newEpollPair(t, r, &conf.Config{}, hb)
+
+	if _, err := ca.Write([]byte("a1")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond) // a1 已交付，Framer 阻塞中
+	if _, err := ca.Write([]byte("a2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cb.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-hb.msgs:
+		if got != "b" {
+			t.Fatalf("message = %q, want \"b\"", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("conn on the same loop stalled by a blocking Recv")
+	}
+
+	// 放行后按序交付
+	release()
+	var got string
+	for got != "a1a2" {
+		select {
+		case m := <-ha.msgs:
+			got += m
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %q, want \"a1a2\"", got)
+		}
+	}
+}
+
+// TestEpollEOF 对端关闭后连接结束，不派发错误
+func TestEpollEOF(t *testing.T) {
+	r, err := reactor.New(1, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	h := &wsHook{msgs: make(chan string, 16), errs: make(chan error, 16)}
+	c, client := newEpollPair(t, r, &conf.Config{}, h)
+	if _, err := client.Write([]byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+	select {
+	case <-c.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("conn not closed after peer EOF")
+	}
+	select {
+	case got := <-h.msgs:
+		if got != "bye" {
+			t.Fatalf("message = %q, want \"bye\"", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("data before EOF not delivered")
+	}
+	select {
+	case err := <-h.errs:
+		t.Fatalf("OnError(%v) on peer EOF", err)
+	default:
+	}
+}
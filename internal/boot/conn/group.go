@@ -0,0 +1,175 @@
+package conn
+
+import (
+	"context"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/encoder"
+	"sync"
+)
+
+// Group 广播组：成员连接关闭时自动退出，广播时按连接配置编码一次后写入各成员的发送队列
+type Group struct {
+	name string
+
+	mu      sync.RWMutex
+	members map[boot.Conn]func() bool // 成员 -> 取消自动退出
+}
+
+func NewGroup(name string) *Group {
+	return &Group{name: name, members: make(map[boot.Conn]func() bool)}
+}
+
+func (g *Group) Name() string { return g.name }
+
+func (g *Group) Join(c boot.Conn) bool {
+	if !c.IsActive() {
+		return false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.members[c]; ok {
+		return false
+	}
+	g.members[c] = context.AfterFunc(c.Context(), func() { g.Leave(c) })
+	return true
+}
+
+func (g *Group) Leave(c boot.Conn) {
+	g.mu.Lock()
+	stop, ok := g.members[c]
+	delete(g.members, c)
+	g.mu.Unlock()
+	if ok {
+		stop()
+	}
+}
+
+func (g *Group) Has(c boot.Conn) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.members[c]
+	return ok
+}
+
+func (g *Group) Count() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.members)
+}
+
+// Range 遍历成员快照，fn 中可安全地加入或退出
+func (g *Group) Range(fn func(c boot.Conn) bool) {
+	for _, c := range g.snapshot() {
+		if !fn(c) {
+			return
+		}
+	}
+}
+
+// Broadcast 广播消息。*Conn 成员按配置缓存编码结果，入队即计入 Sent；其余成员（如多路复用的流）经 SendContext 并发发送，
+// 等待发送完成后再计入 Sent 或 Failed，至 ctx 结束仍未完成的计入 Pending。
+// 发送队列已满时按各连接的 SendQueuePolicy 处理，QueueBlock 最多等待至 ctx 结束。
+func (g *Group) Broadcast(ctx context.Context, msg any, filter ...func(c boot.Conn) bool) boot.BroadcastResult {
+	type encoded struct {
+		buf []byte
+		err error
+	}
+	// 配置与 Encoder 均相同的连接共享编码结果，握手中替换过 Encoder 的连接单独编码
+	type codec struct {
+		cfg *conf.Config
+		enc *encoder.Encoder
+	}
+	cache := make(map[codec]encoded)
+
+	type pending struct {
+		conn boot.Conn
+		done <-chan error
+	}
+	var waits []pending
+
+	res := boot.BroadcastResult{Failed: make(map[boot.Conn]error)}
+members:
+	for _, m := range g.snapshot() {
+		for _, f := range filter {
+			if !f(m) {
+				continue members
+			}
+		}
+
+		c, ok := m.(*Conn)
+		if !ok {
+			// 非会话层连接无法共享编码结果，全部发起后统一等待
+			waits = append(waits, pending{conn: m, done: sendContext(ctx, m, msg)})
+			continue
+		}
+
+		key := codec{cfg: c.Cfg, enc: c.enc.Load()}
+		e, ok := cache[key]
+		if !ok {
+			e.buf, e.err = c.encode(kindMessage, 0, msg)
+			cache[key] = e
+		}
+		err := e.err
+		if err == nil {
+			err = c.push(ctx, e.buf, make(chan error, 1), msg)
+		}
+		if err != nil {
+			res.Failed[m] = err
+			continue
+		}
+		res.Sent++
+	}
+
+	for _, w := range waits {
+		var err error
+		select {
+		case err = <-w.done:
+		case <-ctx.Done():
+			// ctx 结束后只收取已完成的结果
+			select {
+			case err = <-w.done:
+			default:
+				res.Pending++
+				continue
+			}
+		}
+		if err != nil {
+			res.Failed[w.conn] = err
+			continue
+		}
+		res.Sent++
+	}
+	return res
+}
+
+func (g *Group) snapshot() []boot.Conn {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	conns := make([]boot.Conn, 0, len(g.members))
+	for c := range g.members {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// Except 广播过滤条件：排除指定连接（如消息发送者）
+func Except(conns ...boot.Conn) func(c boot.Conn) bool {
+	return func(c boot.Conn) bool {
+		for _, e := range conns {
+			if c == e {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// sendContext 连接实现 RPCConn 时以 ctx 限定等待时间，否则直接发送
+func sendContext(ctx context.Context, c boot.Conn, msg any) <-chan error {
+	if rc, ok := c.(boot.RPCConn); ok {
+		return rc.SendContext(ctx, msg)
+	}
+	return c.Send(msg)
+}
@@ -0,0 +1,159 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+)
+
+// countingConfig 返回统计编码次数的配置
+func countingConfig(calls *atomic.Int32) *conf.Config {
+	cfg := &conf.Config{}
+	cfg.Encoder = func(c boot.Conn, msg any) ([]byte, error) {
+		calls.Add(1)
+		return []byte(msg.(string)), nil
+	}
+	cfg.WithDefault()
+	return cfg
+}
+
+// startPipeConn 启动以 net.Pipe 为传输层的连接，对端收到的数据送入返回的通道
+func startPipeConn(t *testing.T, cfg *conf.Config) (*Conn, <-chan string) {
+	t.Helper()
+	a, b := net.Pipe()
+	c := NewNETConn(context.Background(), a, cfg, &hook.ConnEvent{})
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		c.Close()
+		_ = b.Close()
+		wg.Wait()
+	})
+
+	ch := make(chan string, 16)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := b.Read(buf)
+			if err != nil {
+				return
+			}
+			ch <- string(buf[:n])
+		}
+	}()
+	return c, ch
+}
+
+func expectPeer(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("peer read %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("peer did not receive %q", want)
+	}
+}
+
+// TestGroupBroadcastEncodesOnce 同一配置的成员共享一次编码结果，并各自写出
+func TestGroupBroadcastEncodesOnce(t *testing.T) {
+	var shared, other atomic.Int32
+	sharedCfg, otherCfg := countingConfig(&shared), countingConfig(&other)
+
+	g := NewGroup("room")
+	var peers []<-chan string
+	for i := 0; i < 3; i++ {
+		c, ch := startPipeConn(t, sharedCfg)
+		g.Join(c)
+		peers = append(peers, ch)
+	}
+	c, ch := startPipeConn(t, otherCfg)
+	g.Join(c)
+	peers = append(peers, ch)
+	sender, senderCh := startPipeConn(t, sharedCfg)
+	g.Join(sender)
+
+	res := g.Broadcast(context.Background(), "hello", Except(sender))
+	if res.Sent != 4 || len(res.Failed) != 0 || res.Pending != 0 {
+		t.Fatalf("Broadcast = %+v, want 4 sent", res)
+	}
+	if n := shared.Load(); n != 1 {
+		t.Fatalf("shared config encoded %d times, want 1", n)
+	}
+	if n := other.Load(); n != 1 {
+		t.Fatalf("other config encoded %d times, want 1", n)
+	}
+	for _, ch := range peers {
+		expectPeer(t, ch, "hello")
+	}
+	select {
+	case got := <-senderCh:
+		t.Fatalf("excluded sender received %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGroupBroadcastEncodeError(t *testing.T) {
+	cfg := &conf.Config{}
+	cfg.Encoder = func(c boot.Conn, msg any) ([]byte, error) { return nil, io.ErrUnexpectedEOF }
+	cfg.WithDefault()
+
+	g := NewGroup("room")
+	c, _ := startPipeConn(t, cfg)
+	g.Join(c)
+	res := g.Broadcast(context.Background(), "hello")
+	if res.Sent != 0 || !errors.Is(res.Failed[c], io.ErrUnexpectedEOF) {
+		t.Fatalf("Broadcast = %+v, want encode error", res)
+	}
+}
+
+// asyncConn 非会话层成员，发送结果由测试控制
+type asyncConn struct {
+	boot.Conn
+	result chan error
+}
+
+func (c *asyncConn) Send(msg any) <-chan error { return c.result }
+
+// TestGroupBroadcastWaitsNonConn 非会话层成员在发送完成后才计入 Sent
+func TestGroupBroadcastWaitsNonConn(t *testing.T) {
+	base, _ := startPipeConn(t, countingConfig(new(atomic.Int32)))
+	ok := &asyncConn{Conn: base, result: make(chan error, 1)}
+	bad := &asyncConn{Conn: base, result: make(chan error, 1)}
+	slow := &asyncConn{Conn: base, result: make(chan error, 1)}
+
+	g := NewGroup("room")
+	g.Join(ok)
+	g.Join(bad)
+	g.Join(slow)
+
+	time.AfterFunc(20*time.Millisecond, func() {
+		ok.result <- nil
+		bad.result <- io.ErrClosedPipe
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	res := g.Broadcast(ctx, "hello")
+	if res.Sent+len(res.Failed)+res.Pending != 3 {
+		t.Fatalf("Broadcast = %+v, want 3 members accounted", res)
+	}
+	if !errors.Is(res.Failed[bad], io.ErrClosedPipe) {
+		t.Fatalf("Failed = %v, want send error for bad member", res.Failed)
+	}
+	if _, failed := res.Failed[slow]; failed || res.Pending != 1 {
+		t.Fatalf("Broadcast = %+v, want slow member pending", res)
+	}
+	if res.Sent != 1 {
+		t.Fatalf("Sent = %d, want 1", res.Sent)
+	}
+}
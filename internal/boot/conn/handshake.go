@@ -0,0 +1,229 @@
+package conn
+
+import (
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/decoder"
+	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/handshake"
+	"github.com/yurazsb/uno/internal/lifecycle"
+	"net"
+	"sync/atomic"
+)
+
+const (
+	shaking int32 = iota
+	shakeAccepted
+	shakeFailed
+)
+
+// shake 连接的握手状态
+type shake struct {
+	h     handshake.Handshaker
+	state atomic.Int32  // shaking / shakeAccepted / shakeFailed
+	done  chan struct{} // 握手结束（成功或失败）后关闭
+	err   error         // 握手失败原因，先于关闭 done 写入
+
+	busy   bool     // Begin / Handle 执行中，由 rm 保护
+	queue  [][]byte // 等待处理的握手帧，由 rm 保护
+	closed bool     // 连接已关闭且不再有数据到达，由 rm 保护
+}
+
+func newShake(h handshake.Handshaker) *shake {
+	return &shake{h: h, done: make(chan struct{})}
+}
+
+// hold 握手帧处理中，新到达的数据暂不拆帧；调用方需持有 rm
+func (s *shake) hold() bool { return s.busy }
+
+// pending 握手阶段收下待处理的帧，握手失败时丢弃；握手成功后返回 false，按普通消息派发。调用方需持有 rm
+func (s *shake) pending(frames [][]byte) bool {
+	switch s.state.Load() {
+	case shakeAccepted:
+		return false
+	case shaking:
+		s.queue = append(s.queue, frames...)
+	}
+	return true
+}
+
+// Handshake 阻塞直到握手完成，未启用握手时立即返回 nil；握手失败或连接关闭时返回原因。
+// 对端发送拒绝原因后随即断开时，已收到的握手帧仍会交由 Handshaker 处理，返回其给出的原因
+func (c *Conn) Handshake() error {
+	if c.hs == nil {
+		return nil
+	}
+	<-c.hs.done
+	if c.hs.state.Load() == shakeAccepted {
+		return nil
+	}
+	return c.hs.err
+}
+
+// beginHandshake 进入握手阶段，调用 Begin
+func (c *Conn) beginHandshake() {
+	c.rm.Lock()
+	c.hs.busy = true
+	c.rm.Unlock()
+	c.handshakeTask(func(h handshake.Handshake) { c.hs.h.Begin(h) })
+}
+
+// nextHandshake 取出下一个握手帧交由 Handle 处理，调用方需持有 rm
+func (c *Conn) nextHandshake() {
+	hs := c.hs
+	if hs.busy || hs.state.Load() != shaking {
+		return
+	}
+	for {
+		if len(hs.queue) == 0 {
+			// 按当前 Framer 拆分处理期间暂存的数据
+			if c.readBuf.Len() == 0 {
+				return
+			}
+			frames, err := c.split()
+			if err != nil {
+				if !c.Cfg.RPC { // 启用 RPC 时 split 已关闭连接
+					c.failHandshake(err)
+				}
+				return
+			}
+			if len(frames) == 0 {
+				return
+			}
+			hs.queue = frames
+		}
+
+		frame := hs.queue[0]
+		hs.queue[0] = nil
+		hs.queue = hs.queue[1:]
+		if c.Cfg.RPC {
+			frame = frame[envelopeHeader:]
+		}
+		if c.hb != nil && c.heartbeat(frame) {
+			continue
+		}
+
+		hs.busy = true
+		c.handshakeTask(func(h handshake.Handshake) { hs.h.Handle(h, frame) })
+		return
+	}
+}
+
+// handshakeTask 在协程池中执行 Begin / Handle，结束后继续处理后续握手帧或握手成功后积压的数据
+func (c *Conn) handshakeTask(fn func(h handshake.Handshake)) {
+	c.SubmitTask(func() {
+		defer c.handshakeDone()
+		defer func() {
+			if r := recover(); r != nil {
+				c.rejectHandshake(fmt.Errorf("handshaker panic: %v", r))
+			}
+		}()
+		fn(handshakeCtx{c})
+	})
+}
+
+func (c *Conn) handshakeDone() {
+	c.rm.Lock()
+	defer c.rm.Unlock()
+	c.hs.busy = false
+	switch c.hs.state.Load() {
+	case shaking:
+		c.nextHandshake()
+		c.settleHandshake()
+	case shakeAccepted:
+		c.flushHandshake()
+	}
+}
+
+// closeHandshake 连接关闭且读取结束后调用，已收到的握手帧处理完毕后结束握手
+func (c *Conn) closeHandshake() {
+	if c.hs == nil {
+		return
+	}
+	c.rm.Lock()
+	defer c.rm.Unlock()
+	c.hs.closed = true
+	c.settleHandshake()
+}
+
+// settleHandshake 连接已关闭且没有待处理的握手帧时，以 net.ErrClosed 结束握手；调用方需持有 rm
+func (c *Conn) settleHandshake() {
+	hs := c.hs
+	if hs.closed && !hs.busy && hs.state.CompareAndSwap(shaking, shakeFailed) {
+		hs.err = net.ErrClosed
+		close(hs.done)
+	}
+}
+
+// flushHandshake 握手成功后按普通消息派发握手阶段积压的帧与数据，调用方需持有 rm
+func (c *Conn) flushHandshake() {
+	frames := c.hs.queue
+	c.hs.queue = nil
+	c.deliver(frames)
+	c.reframe()
+}
+
+func (c *Conn) acceptHandshake() {
+	if !c.hs.state.CompareAndSwap(shaking, shakeAccepted) {
+		return
+	}
+	close(c.hs.done)
+	c.enter(lifecycle.Ready)
+	c.dispatchReady()
+
+	c.rm.Lock()
+	defer c.rm.Unlock()
+	if !c.hs.busy { // 在 Handle 之外接受时由此派发积压的数据
+		c.flushHandshake()
+	}
+}
+
+func (c *Conn) rejectHandshake(reason error) {
+	err := handshake.ErrRejected
+	if reason != nil {
+		err = fmt.Errorf("%w: %w", handshake.ErrRejected, reason)
+	}
+	c.failHandshake(err)
+}
+
+func (c *Conn) handshakeTimeout() {
+	c.failHandshake(handshake.ErrTimeout)
+}
+
+func (c *Conn) failHandshake(err error) {
+	if !c.hs.state.CompareAndSwap(shaking, shakeFailed) {
+		return
+	}
+	c.hs.err = err
+	close(c.hs.done)
+	c.fail(err)
+}
+
+// handshakeCtx 实现 handshake.Handshake
+type handshakeCtx struct {
+	c *Conn
+}
+
+func (h handshakeCtx) Conn() boot.Conn { return h.c }
+
+func (h handshakeCtx) Send(msg any) <-chan error { return h.c.Send(msg) }
+
+func (h handshakeCtx) Decode(frame []byte) (any, error) { return (*h.c.dec.Load())(h.c, frame) }
+
+func (h handshakeCtx) SetFramer(f framer.Framer) {
+	if h.c.Cfg.RPC {
+		return
+	}
+	h.c.rm.Lock()
+	h.c.setFramer(f)
+	h.c.rm.Unlock()
+}
+
+func (h handshakeCtx) SetDecoder(d decoder.Decoder) { h.c.dec.Store(&d) }
+
+func (h handshakeCtx) SetEncoder(e encoder.Encoder) { h.c.enc.Store(&e) }
+
+func (h handshakeCtx) Accept() { h.c.acceptHandshake() }
+
+func (h handshakeCtx) Reject(reason error) { h.c.rejectHandshake(reason) }
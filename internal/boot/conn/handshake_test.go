@@ -0,0 +1,217 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/handshake"
+)
+
+// shakeHook 在 wsHook 的基础上记录连接生命周期回调
+type shakeHook struct {
+	wsHook
+	events chan string
+}
+
+func newShakeHook() *shakeHook {
+	return &shakeHook{wsHook: *newWSHook(), events: make(chan string, 8)}
+}
+
+func (h *shakeHook) OnConnect(c boot.Conn) { h.events <- "connect" }
+func (h *shakeHook) OnReady(c boot.Conn)   { h.events <- "ready" }
+func (h *shakeHook) OnClose(c boot.Conn)   { h.events <- "close" }
+
+func (h *shakeHook) expectEvent(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-h.events:
+		if got != want {
+			t.Fatalf("event %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event %q not dispatched", want)
+	}
+}
+
+// newShakeConn 启动配置了 Handshaker 的连接，返回对端与对端读到的数据（每次写出一项）
+func newShakeConn(t *testing.T, cfg *conf.Config, h *shakeHook) (*Conn, net.Conn, <-chan string) {
+	t.Helper()
+	cfg.WithDefault()
+	a, b := net.Pipe()
+	c := NewNETConn(context.Background(), a, cfg, h)
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		c.Close()
+		_ = b.Close()
+		wg.Wait()
+	})
+
+	peer := make(chan string, 16)
+	go func() {
+		defer close(peer)
+		buf := make([]byte, 1024)
+		for {
+			n, err := b.Read(buf)
+			if err != nil {
+				return
+			}
+			peer <- string(buf[:n])
+		}
+	}()
+	return c, b, peer
+}
+
+func expectPeerRead(t *testing.T, peer <-chan string, want string) {
+	t.Helper()
+	select {
+	case got, ok := <-peer:
+		if !ok {
+			t.Fatalf("peer closed, want %q", want)
+		}
+		if got != want {
+			t.Fatalf("peer read %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("peer did not receive %q", want)
+	}
+}
+
+func expectShakeErr(t *testing.T, c *Conn, want error) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- c.Handshake() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, want) {
+			t.Fatalf("Handshake = %v, want %v", err, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handshake did not return")
+	}
+}
+
+// versionShaker Begin 发送问候，收到 "v2" 时切换到 ";" 分隔、大写解码与 "v2:" 前缀编码后接受，否则拒绝
+type versionShaker struct{}
+
+func (versionShaker) Begin(h handshake.Handshake) { h.Send("hello") }
+
+func (versionShaker) Handle(h handshake.Handshake, frame []byte) {
+	if string(frame) != "v2" {
+		h.Send("denied")
+		h.Reject(errors.New("unsupported version " + string(frame)))
+		return
+	}
+	h.SetFramer(framer.DelimiterFramer([]byte(";")))
+	h.SetDecoder(func(c boot.Conn, frame []byte) (any, error) { return bytes.ToUpper(frame), nil })
+	h.SetEncoder(func(c boot.Conn, msg any) ([]byte, error) { return []byte("v2:" + msg.(string)), nil })
+	h.Send("ok")
+	h.Accept()
+}
+
+// TestHandshakeNegotiatesCodec 握手切换 Framer / Decoder / Encoder，与握手帧同时到达的数据按新的编解码处理
+func TestHandshakeNegotiatesCodec(t *testing.T) {
+	h := newShakeHook()
+	cfg := &conf.Config{Framer: framer.LineFramer(), Handshaker: versionShaker{}}
+	c, peer, reads := newShakeConn(t, cfg, h)
+
+	h.expectEvent(t, "connect")
+	expectPeerRead(t, reads, "hello")
+	if _, err := peer.Write([]byte("v2\nab;cd;")); err != nil {
+		t.Fatal(err)
+	}
+	expectPeerRead(t, reads, "v2:ok")
+	expectShakeErr(t, c, nil)
+	h.expectEvent(t, "ready")
+
+	for _, want := range []string{"AB", "CD"} {
+		select {
+		case got := <-h.msgs:
+			if got != want {
+				t.Fatalf("message %q, want %q", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %q not dispatched", want)
+		}
+	}
+	if _, err := peer.Write([]byte("ef;")); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-h.msgs; got != "EF" {
+		t.Fatalf("message %q, want EF", got)
+	}
+	if err := <-c.Send("bye"); err != nil {
+		t.Fatal(err)
+	}
+	expectPeerRead(t, reads, "v2:bye")
+}
+
+// TestHandshakeRejected 拒绝时先写出拒绝原因再关闭，不派发 OnReady，握手阶段的后续数据不进入 OnMessage
+func TestHandshakeRejected(t *testing.T) {
+	h := newShakeHook()
+	cfg := &conf.Config{Framer: framer.LineFramer(), Handshaker: versionShaker{}}
+	c, peer, reads := newShakeConn(t, cfg, h)
+
+	h.expectEvent(t, "connect")
+	expectPeerRead(t, reads, "hello")
+	if _, err := peer.Write([]byte("v1\nmsg\n")); err != nil {
+		t.Fatal(err)
+	}
+	expectPeerRead(t, reads, "denied")
+	expectShakeErr(t, c, handshake.ErrRejected)
+	h.expectEvent(t, "close")
+
+	if _, ok := <-reads; ok {
+		t.Fatal("peer read data after the reject reason")
+	}
+	if err := context.Cause(c.Ctx); !errors.Is(err, handshake.ErrRejected) {
+		t.Fatalf("close cause = %v, want ErrRejected", err)
+	}
+	select {
+	case err := <-h.errs:
+		if !errors.Is(err, handshake.ErrRejected) {
+			t.Fatalf("OnError = %v, want ErrRejected", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError not dispatched")
+	}
+	select {
+	case msg := <-h.msgs:
+		t.Fatalf("message %q dispatched after a rejected handshake", msg)
+	case ev := <-h.events:
+		t.Fatalf("unexpected event %q", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestHandshakeTimeout 未在 HandshakeTimeout 内完成握手时以 ErrTimeout 关闭连接
+func TestHandshakeTimeout(t *testing.T) {
+	h := newShakeHook()
+	cfg := &conf.Config{Handshaker: versionShaker{}, HandshakeTimeout: 30 * time.Millisecond}
+	start := time.Now()
+	c, _, reads := newShakeConn(t, cfg, h)
+
+	h.expectEvent(t, "connect")
+	expectPeerRead(t, reads, "hello")
+	expectShakeErr(t, c, handshake.ErrTimeout)
+	if elapsed := time.Since(start); elapsed < cfg.HandshakeTimeout {
+		t.Fatalf("timed out after %v", elapsed)
+	}
+	h.expectEvent(t, "close")
+	if err := context.Cause(c.Ctx); !errors.Is(err, handshake.ErrTimeout) {
+		t.Fatalf("close cause = %v, want ErrTimeout", err)
+	}
+	select {
+	case ev := <-h.events:
+		t.Fatalf("unexpected event %q", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
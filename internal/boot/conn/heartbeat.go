@@ -0,0 +1,115 @@
+package conn
+
+import (
+	"bytes"
+	"github.com/yurazsb/uno/internal/heartbeat"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeater 连接的心跳状态
+type heartbeater struct {
+	opts heartbeat.Options
+
+	ping []byte // 写出的 ping / pong 线上字节，启用 RPC 时为信封帧
+	pong []byte
+
+	pingFrame []byte // 本端 Framer 从 ping / pong 中拆出的帧，用于识别入站心跳
+	pongFrame []byte
+
+	sentAt atomic.Int64 // 等待 pong 的 ping 发送时间（UnixNano），0 表示未在等待
+	recvAt atomic.Int64 // 最后一次收到数据的时间（UnixNano）
+	rtt    atomic.Int64 // 最近一次测得的往返时延
+
+	misses int // 连续未应答次数，仅由主循环访问
+}
+
+func newHeartbeater(c *Conn, opts heartbeat.Options) *heartbeater {
+	opts.WithDefault()
+	hb := &heartbeater{opts: opts, ping: opts.Ping, pong: opts.Pong, pingFrame: opts.Ping, pongFrame: opts.Pong}
+	if c.Cfg.RPC {
+		hb.ping = envelope(kindMessage, 0, opts.Ping)
+		hb.pong = envelope(kindMessage, 0, opts.Pong)
+		return hb
+	}
+	hb.reframe(c)
+	return hb
+}
+
+// reframe 按连接当前的 Framer 重新计算入站心跳帧，替换 Framer 后由持有 rm 的调用方调用
+func (hb *heartbeater) reframe(c *Conn) {
+	if c.Cfg.RPC {
+		return
+	}
+	hb.pingFrame = frameOf(c, hb.opts.Ping)
+	hb.pongFrame = frameOf(c, hb.opts.Pong)
+}
+
+// frameOf 用连接的 Framer 拆分完整的线上字节，恰好得到一帧时返回该帧，否则按原样比较
+func frameOf(c *Conn, wire []byte) []byte {
+	frames, rest, err := c.framer(c, bytes.Clone(wire))
+	if err != nil || len(frames) != 1 || len(rest) != 0 {
+		return wire
+	}
+	return bytes.Clone(frames[0])
+}
+
+// RTT 最近一次 ping / pong 测得的往返时延，未启用心跳或尚未测得时为 0
+func (c *Conn) RTT() time.Duration {
+	if c.hb == nil {
+		return 0
+	}
+	return time.Duration(c.hb.rtt.Load())
+}
+
+// heartbeat 识别入站的 ping / pong 帧：ping 自动回复 pong，pong 测量往返时延；返回 true 时帧不再派发
+func (c *Conn) heartbeat(frame []byte) bool {
+	hb := c.hb
+	switch {
+	case bytes.Equal(frame, hb.pingFrame):
+		c.sendHeartbeat(hb.pong)
+		return true
+	case bytes.Equal(frame, hb.pongFrame):
+		if at := hb.sentAt.Swap(0); at != 0 {
+			hb.rtt.Store(time.Now().UnixNano() - at)
+		}
+		return true
+	}
+	return false
+}
+
+// sendPing 由主循环按周期调用，发送 ping 并返回发送时间
+func (c *Conn) sendPing() time.Time {
+	now := time.Now()
+	c.hb.sentAt.Store(now.UnixNano())
+	c.sendHeartbeat(c.hb.ping)
+	return now
+}
+
+// checkPing 由主循环在 ping 发送 Timeout 后调用：期间收到过数据则清零未应答次数，
+// 否则累加，达到 MaxMisses 时返回 *heartbeat.TimeoutError
+func (c *Conn) checkPing(sent time.Time) error {
+	hb := c.hb
+	last := hb.recvAt.Load()
+	if last >= sent.UnixNano() {
+		hb.misses = 0
+		return nil
+	}
+	hb.misses++
+	if hb.misses < hb.opts.MaxMisses {
+		return nil
+	}
+	err := &heartbeat.TimeoutError{Misses: hb.misses}
+	if last != 0 {
+		err.LastRecv = time.Unix(0, last)
+	}
+	return err
+}
+
+// sendHeartbeat 心跳帧直接入队，不经过 Encoder、OnSend 与队列满策略；队列已满时跳过本次发送
+func (c *Conn) sendHeartbeat(buf []byte) {
+	pushed, high, _, _ := c.queue.push(&message{buf: buf, done: make(chan error, 1)})
+	if pushed && high {
+		c.dispatchHighWatermark()
+	}
+}
@@ -0,0 +1,168 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/heartbeat"
+)
+
+func newHeartbeatConn(t *testing.T, raw net.Conn, opts heartbeat.Options, h *wsHook) *Conn {
+	t.Helper()
+	cfg := &conf.Config{Heartbeat: &opts}
+	cfg.WithDefault()
+	c := NewNETConn(context.Background(), raw, cfg, h)
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		c.Close()
+		_ = raw.Close()
+		wg.Wait()
+	})
+	return c
+}
+
+func newWSHook() *wsHook {
+	return &wsHook{msgs: make(chan string, 64), errs: make(chan error, 64)}
+}
+
+// TestHeartbeatRTT 两端互发 ping 并自动应答，测得往返时延，心跳帧不派发给 OnMessage
+func TestHeartbeatRTT(t *testing.T) {
+	opts := heartbeat.Options{Interval: 20 * time.Millisecond, Timeout: 20 * time.Millisecond}
+	a, b := net.Pipe()
+	ha, hb := newWSHook(), newWSHook()
+	ca := newHeartbeatConn(t, a, opts, ha)
+	cb := newHeartbeatConn(t, b, opts, hb)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ca.RTT() == 0 || cb.RTT() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("RTT not measured: %v, %v", ca.RTT(), cb.RTT())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if rtt := ca.RTT(); rtt < 0 || rtt > time.Second {
+		t.Fatalf("RTT = %v", rtt)
+	}
+
+	// 超过 MaxMisses 个周期后连接仍然存活
+	time.Sleep(5 * opts.Interval)
+	if !ca.IsActive() || !cb.IsActive() {
+		t.Fatal("conn closed although pongs were answered")
+	}
+	for _, h := range []*wsHook{ha, hb} {
+		select {
+		case msg := <-h.msgs:
+			t.Fatalf("heartbeat frame dispatched as message %q", msg)
+		case err := <-h.errs:
+			t.Fatalf("unexpected error: %v", err)
+		default:
+		}
+	}
+}
+
+// TestHeartbeatDeadPeer 对端不应答也不发送数据时，连续 MaxMisses 次未应答后以 ErrTimeout 关闭连接
+func TestHeartbeatDeadPeer(t *testing.T) {
+	opts := heartbeat.Options{Interval: 10 * time.Millisecond, Timeout: 10 * time.Millisecond, MaxMisses: 3}
+	a, b := net.Pipe()
+	h := newWSHook()
+	start := time.Now()
+	c := newHeartbeatConn(t, a, opts, h)
+
+	// 只读取不应答
+	pings := make(chan string, 64)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := b.Read(buf)
+			if err != nil {
+				return
+			}
+			pings <- string(buf[:n])
+		}
+	}()
+
+	select {
+	case <-c.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("conn not closed after missed pongs")
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(opts.MaxMisses)*opts.Interval {
+		t.Fatalf("closed after %v, before %d intervals", elapsed, opts.MaxMisses)
+	}
+	var te *heartbeat.TimeoutError
+	if err := context.Cause(c.Ctx); !errors.Is(err, heartbeat.ErrTimeout) || !errors.As(err, &te) {
+		t.Fatalf("close cause = %v, want heartbeat timeout", err)
+	}
+	if te.Misses != opts.MaxMisses || !te.LastRecv.IsZero() {
+		t.Fatalf("TimeoutError = %+v", te)
+	}
+	if got := <-pings; got != "ping" {
+		t.Fatalf("peer read %q, want ping", got)
+	}
+}
+
+// TestHeartbeatTrafficCounts 对端不应答 pong 但持续发送业务数据时不判定失效
+func TestHeartbeatTrafficCounts(t *testing.T) {
+	opts := heartbeat.Options{Interval: 10 * time.Millisecond, Timeout: 10 * time.Millisecond, MaxMisses: 2}
+	a, b := net.Pipe()
+	h := newWSHook()
+	c := newHeartbeatConn(t, a, opts, h)
+
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			if _, err := b.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		if _, err := b.Write([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		if msg := <-h.msgs; msg != "data" {
+			t.Fatalf("message %q, want data", msg)
+		}
+		time.Sleep(3 * time.Millisecond)
+	}
+	if !c.IsActive() {
+		t.Fatalf("conn closed although data kept arriving: %v", context.Cause(c.Ctx))
+	}
+	if c.RTT() != 0 {
+		t.Fatalf("RTT = %v without any pong", c.RTT())
+	}
+}
+
+// TestHeartbeatAnswer 不主动发送 ping 的一端仍自动应答对端的 ping
+func TestHeartbeatAnswer(t *testing.T) {
+	a, b := net.Pipe()
+	h := newWSHook()
+	newHeartbeatConn(t, a, heartbeat.Options{Interval: -1}, h)
+
+	if _, err := b.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	_ = b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := b.Read(buf)
+	if err != nil || string(buf[:n]) != "pong" {
+		t.Fatalf("read %q, %v, want pong", buf[:n], err)
+	}
+
+	// 自身不发送 ping
+	_ = b.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := b.Read(buf); err == nil {
+		t.Fatalf("unexpected %q from a conn that does not ping", buf[:n])
+	}
+	select {
+	case msg := <-h.msgs:
+		t.Fatalf("ping dispatched as message %q", msg)
+	default:
+	}
+}
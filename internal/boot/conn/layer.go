@@ -0,0 +1,54 @@
+package conn
+
+import (
+	"github.com/yurazsb/uno/internal/arq"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/fragment"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/mux"
+)
+
+// IsPacketNetwork 判断是否为数据报网络类型
+func IsPacketNetwork(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	default:
+		return false
+	}
+}
+
+// newLayers 按配置创建处理层，自上而下排列
+func newLayers(cfg *conf.Config, hook hook.ConnHook) []boot.Layer {
+	var layers []boot.Layer
+
+	// 数据报网络上的多路复用依赖 ARQ 提供可靠有序传输
+	if cfg.Mux != nil && (!IsPacketNetwork(cfg.Network) || cfg.ARQ != nil) {
+		layers = append(layers, mux.New(*cfg.Mux, mux.Defaults{
+			StreamConfig: mux.StreamConfig{
+				Framer:   cfg.Framer,
+				Decoder:  cfg.Decoder,
+				Encoder:  cfg.Encoder,
+				Handlers: cfg.Handlers,
+				Hook:     hook,
+			},
+			Pool:    cfg.Pool,
+			Ordered: cfg.Ordered,
+			Logger:  cfg.Logger,
+		}))
+	}
+
+	if !IsPacketNetwork(cfg.Network) {
+		return layers
+	}
+
+	// ARQ 自带分段重组，启用时无需分片层
+	switch {
+	case cfg.ARQ != nil:
+		layers = append(layers, arq.New(*cfg.ARQ, cfg.MTU))
+	case cfg.Fragment != nil:
+		layers = append(layers, fragment.New(*cfg.Fragment, cfg.MTU))
+	}
+	return layers
+}
@@ -0,0 +1,397 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/handler"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/mux"
+)
+
+// muxHook 记录流的打开、关闭与消息，消息记为 "label:payload"（连接自身的消息 label 为空）
+type muxHook struct {
+	hook.ConnEvent
+	msgs   chan string
+	opened chan *mux.Stream
+	closed chan *mux.Stream
+}
+
+func newMuxHook() *muxHook {
+	return &muxHook{msgs: make(chan string, 64), opened: make(chan *mux.Stream, 16), closed: make(chan *mux.Stream, 16)}
+}
+
+func (h *muxHook) OnConnect(c boot.Conn) {
+	if st, ok := c.(*mux.Stream); ok {
+		h.opened <- st
+	}
+}
+
+func (h *muxHook) OnClose(c boot.Conn) {
+	if st, ok := c.(*mux.Stream); ok {
+		h.closed <- st
+	}
+}
+
+func (h *muxHook) OnMessage(c boot.Conn, msg any) {
+	label := ""
+	if st, ok := c.(*mux.Stream); ok {
+		label = st.Label()
+	}
+	h.msgs <- label + ":" + string(msg.([]byte))
+}
+
+// newMuxConn 基于 raw 创建启用多路复用的连接并启动
+func newMuxConn(t *testing.T, raw net.Conn, client bool, cfg *conf.Config, h hook.ConnHook) *Conn {
+	t.Helper()
+	if cfg.Mux == nil {
+		cfg.Mux = &mux.Options{}
+	}
+	cfg.WithDefault()
+	c := NewNETConn(context.Background(), raw, cfg, h)
+	c.Client = client
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		c.Close()
+		wg.Wait()
+	})
+	return c
+}
+
+// newMuxPair 以 net.Pipe 连接的客户端与服务端
+func newMuxPair(t *testing.T, opts mux.Options) (client, server *Conn, ch, sh *muxHook) {
+	t.Helper()
+	a, b := net.Pipe()
+	ch, sh = newMuxHook(), newMuxHook()
+	copts := opts
+	client = newMuxConn(t, a, true, &conf.Config{Mux: &copts}, ch)
+	server = newMuxConn(t, b, false, &conf.Config{Mux: &opts}, sh)
+	return
+}
+
+func session(t *testing.T, c *Conn) *mux.Session {
+	t.Helper()
+	s, ok := mux.SessionOf(c)
+	if !ok {
+		t.Fatal("mux layer not enabled")
+	}
+	return s
+}
+
+func openStream(t *testing.T, c *Conn, label string) *mux.Stream {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	st, err := session(t, c).OpenStream(ctx, label, mux.StreamConfig{})
+	if err != nil {
+		t.Fatalf("open stream %q: %v", label, err)
+	}
+	return st
+}
+
+func recvStream(t *testing.T, ch <-chan *mux.Stream) *mux.Stream {
+	t.Helper()
+	select {
+	case st := <-ch:
+		return st
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream event not received")
+		return nil
+	}
+}
+
+func expectMuxMsg(t *testing.T, h *muxHook, want string) {
+	t.Helper()
+	select {
+	case got := <-h.msgs:
+		if got != want {
+			t.Fatalf("message = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive %q", want)
+	}
+}
+
+func sendErr(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("send did not complete")
+		return nil
+	}
+}
+
+// waitStreams 等待会话中的流数降至 n
+func waitStreams(t *testing.T, c *Conn, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for session(t, c).NumStreams() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("NumStreams = %d, want %d", session(t, c).NumStreams(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMuxOpenAndClose(t *testing.T) {
+	client, server, ch, sh := newMuxPair(t, mux.Options{})
+
+	cst := openStream(t, client, "chat")
+	sst := recvStream(t, sh.opened)
+	if recvStream(t, ch.opened) != cst {
+		t.Fatal("OnConnect on the client is not for the opened stream")
+	}
+	if cst.StreamID()%2 != 1 || sst.StreamID() != cst.StreamID() || sst.Label() != "chat" {
+		t.Fatalf("client stream %d, server stream %d %q", cst.StreamID(), sst.StreamID(), sst.Label())
+	}
+	if sst.Parent() != server {
+		t.Fatal("server stream parent is not the conn")
+	}
+
+	// 服务端打开的流使用偶数 ID
+	sst2 := openStream(t, server, "push")
+	if sst2.StreamID()%2 != 0 {
+		t.Fatalf("server opened stream %d", sst2.StreamID())
+	}
+	recvStream(t, ch.opened)
+	recvStream(t, sh.opened)
+
+	if err := sendErr(t, cst.Send([]byte("hi"))); err != nil {
+		t.Fatal(err)
+	}
+	expectMuxMsg(t, sh, "chat:hi")
+	if err := sendErr(t, sst.Send([]byte("yo"))); err != nil {
+		t.Fatal(err)
+	}
+	expectMuxMsg(t, ch, "chat:yo")
+	if err := sendErr(t, client.Send([]byte("conn"))); err != nil {
+		t.Fatal(err)
+	}
+	expectMuxMsg(t, sh, ":conn")
+
+	// 关闭先发出已入队的消息
+	cst.Send([]byte("last"))
+	cst.Close()
+	expectMuxMsg(t, sh, "chat:last")
+	if recvStream(t, sh.closed) != sst {
+		t.Fatal("OnClose on the server is not for the closed stream")
+	}
+	if err := sendErr(t, cst.Send([]byte("late"))); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("send on closed stream = %v, want net.ErrClosed", err)
+	}
+	waitStreams(t, client, 1)
+	waitStreams(t, server, 1)
+
+	// 连接关闭时结束其余的流
+	client.Close()
+	recvStream(t, sh.closed)
+	recvStream(t, ch.closed)
+	recvStream(t, ch.closed)
+}
+
+func TestMuxRefused(t *testing.T) {
+	client, _, _, _ := newMuxPair(t, mux.Options{
+		Accept: func(c boot.Conn, label string) (mux.StreamConfig, error) {
+			return mux.StreamConfig{}, errors.New("no")
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := session(t, client).OpenStream(ctx, "chat", mux.StreamConfig{}); !errors.Is(err, mux.ErrRefused) {
+		t.Fatalf("open = %v, want ErrRefused", err)
+	}
+	waitStreams(t, client, 0)
+}
+
+func TestMuxMaxStreams(t *testing.T) {
+	client, _, _, _ := newMuxPair(t, mux.Options{MaxStreams: 1})
+	openStream(t, client, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := session(t, client).OpenStream(ctx, "b", mux.StreamConfig{}); !errors.Is(err, mux.ErrTooManyStream) {
+		t.Fatalf("open = %v, want ErrTooManyStream", err)
+	}
+}
+
+func TestMuxReset(t *testing.T) {
+	client, server, ch, sh := newMuxPair(t, mux.Options{})
+	cst := openStream(t, client, "chat")
+	sst := recvStream(t, sh.opened)
+	recvStream(t, ch.opened)
+
+	cst.Reset()
+	if recvStream(t, sh.closed) != sst {
+		t.Fatal("OnClose on the server is not for the reset stream")
+	}
+	recvStream(t, ch.closed)
+	if err := sendErr(t, sst.Send([]byte("x"))); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("send on reset stream = %v, want net.ErrClosed", err)
+	}
+	waitStreams(t, client, 0)
+	waitStreams(t, server, 0)
+
+	// 连接不受影响
+	if err := sendErr(t, client.Send([]byte("conn"))); err != nil {
+		t.Fatal(err)
+	}
+	expectMuxMsg(t, sh, ":conn")
+}
+
+// TestMuxWindow 接收方处理完成前不归还窗口，发送方在用尽窗口后阻塞
+func TestMuxWindow(t *testing.T) {
+	const size = 64 << 10
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var received int
+	client, _, _, _ := newMuxPair(t, mux.Options{
+		Accept: func(c boot.Conn, label string) (mux.StreamConfig, error) {
+			return mux.StreamConfig{Handlers: []handler.Handler{func(ctx handler.Context, next func()) {
+				<-gate
+				mu.Lock()
+				received += len(ctx.Payload().([]byte))
+				mu.Unlock()
+			}}}, nil
+		},
+	})
+	st := openStream(t, client, "bulk")
+
+	// 默认窗口 256KB：前 4 条消息写出，第 5 条等待窗口
+	var dones []<-chan error
+	for i := 0; i < 6; i++ {
+		dones = append(dones, st.Send(bytes.Repeat([]byte{'x'}, size)))
+	}
+	for i := 0; i < 4; i++ {
+		if err := sendErr(t, dones[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case err := <-dones[4]:
+		t.Fatalf("send beyond the window completed: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(gate)
+	for _, done := range dones[4:] {
+		if err := sendErr(t, done); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := received
+		mu.Unlock()
+		if n == 6*size {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d bytes, want %d", n, 6*size)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// 原始帧：[version][type][flags][streamID][length][payload]
+const (
+	testTypeData byte   = 0
+	testFlagSYN  uint16 = 1
+)
+
+func muxFrame(typ byte, flags uint16, id uint32, payload []byte) []byte {
+	b := []byte{0, typ}
+	b = binary.BigEndian.AppendUint16(b, flags)
+	b = binary.BigEndian.AppendUint32(b, id)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// TestMuxStreamFramesUseSendQueue 流的帧与连接自身的消息共享发送队列，对端不读时同样产生背压；
+// 队列中的帧不受 QueueDropOldest 丢弃
+func TestMuxStreamFramesUseSendQueue(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy conf.QueuePolicy
+		err    error // 队列被流的帧占满时连接自身消息的发送结果
+	}{
+		{"fail fast", conf.QueueFailFast, ErrSendQueueFull},
+		{"drop oldest", conf.QueueDropOldest, context.DeadlineExceeded},
+		{"block", conf.QueueBlock, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, peer := net.Pipe()
+			defer peer.Close()
+			h := newMuxHook()
+			c := newMuxConn(t, a, false, &conf.Config{SendQueueSize: 4, SendQueuePolicy: tt.policy}, h)
+
+			// 对端打开流 1 后不再读取：确认帧阻塞在写出中，其后的帧留在队列里
+			go func() { _, _ = peer.Write(muxFrame(testTypeData, testFlagSYN, 1, []byte("chat"))) }()
+			st := recvStream(t, h.opened)
+
+			var dones []<-chan error
+			deadline := time.Now().Add(5 * time.Second)
+			for n, _ := c.QueueLen(); n < 4; n, _ = c.QueueLen() {
+				if time.Now().After(deadline) {
+					t.Fatalf("queue length = %d, want 4", n)
+				}
+				dones = append(dones, st.Send([]byte("x")))
+				time.Sleep(10 * time.Millisecond)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if err := sendErr(t, c.SendContext(ctx, []byte("conn"))); !errors.Is(err, tt.err) {
+				t.Fatalf("conn send = %v, want %v", err, tt.err)
+			}
+
+			// 对端恢复读取后全部写出
+			go func() { _, _ = io.Copy(io.Discard, peer) }()
+			for _, done := range dones {
+				if err := sendErr(t, done); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMuxRejectsLocalParity(t *testing.T) {
+	tests := []struct {
+		name   string
+		client bool
+		id     uint32
+	}{
+		{"server receives even id", false, 2},
+		{"client receives odd id", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, peer := net.Pipe()
+			defer peer.Close()
+			go func() { _, _ = io.Copy(io.Discard, peer) }()
+			c := newMuxConn(t, a, tt.client, &conf.Config{}, newMuxHook())
+
+			_, _ = peer.Write(muxFrame(testTypeData, testFlagSYN, tt.id, []byte("chat")))
+			select {
+			case <-c.closed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("conn not closed")
+			}
+			if err := context.Cause(c.Ctx); !errors.Is(err, mux.ErrProtocol) {
+				t.Fatalf("close cause = %v, want ErrProtocol", err)
+			}
+		})
+	}
+}
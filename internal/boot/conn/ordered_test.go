@@ -0,0 +1,101 @@
+package conn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/hook"
+)
+
+// orderHook 记录回调顺序，并检查同一连接的回调是否并发执行
+type orderHook struct {
+	hook.ConnEvent
+
+	mu       sync.Mutex
+	events   []string
+	inflight atomic.Int32
+	overlap  atomic.Bool
+	closed   chan struct{}
+}
+
+func (h *orderHook) record(ev string, work time.Duration) {
+	if h.inflight.Add(1) > 1 {
+		h.overlap.Store(true)
+	}
+	time.Sleep(work)
+	h.mu.Lock()
+	h.events = append(h.events, ev)
+	h.mu.Unlock()
+	h.inflight.Add(-1)
+}
+
+// OnConnect 故意耗时，未串行派发时首批消息会先于其完成
+func (h *orderHook) OnConnect(c boot.Conn) { h.record("connect", 20*time.Millisecond) }
+
+func (h *orderHook) OnMessage(c boot.Conn, msg any) {
+	payload := string(msg.([]byte))
+	var n int
+	_, _ = fmt.Sscan(payload, &n)
+	h.record(payload, time.Duration(n%3)*time.Millisecond)
+}
+
+func (h *orderHook) OnClose(c boot.Conn) {
+	h.record("close", 0)
+	close(h.closed)
+}
+
+// TestOrderedDispatch 开启 Ordered 后，同一连接并发到达的消息按 OnConnect → OnMessage... → OnClose 的顺序逐个处理
+func TestOrderedDispatch(t *testing.T) {
+	const n = 200
+	cfg := &conf.Config{Ordered: true, Framer: framer.LineFramer()}
+	cfg.WithDefault()
+	h := &orderHook{closed: make(chan struct{})}
+
+	a, b := net.Pipe()
+	c := NewNETConn(context.Background(), a, cfg, h)
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	defer wg.Wait()
+
+	// 分多次写出，同一次读取中可能包含多条消息
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+		if i%7 == 6 || i == n-1 {
+			if _, err := b.Write([]byte(sb.String())); err != nil {
+				t.Fatal(err)
+			}
+			sb.Reset()
+		}
+	}
+	_ = b.Close()
+
+	select {
+	case <-h.closed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("OnClose not called")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	want := []string{"connect"}
+	for i := 0; i < n; i++ {
+		want = append(want, fmt.Sprint(i))
+	}
+	want = append(want, "close")
+	if strings.Join(h.events, ",") != strings.Join(want, ",") {
+		t.Fatalf("events = %v, want %v", h.events, want)
+	}
+	if h.overlap.Load() {
+		t.Fatal("callbacks of one conn ran concurrently")
+	}
+}
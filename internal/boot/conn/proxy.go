@@ -0,0 +1,34 @@
+package conn
+
+import (
+	"github.com/yurazsb/uno/internal/proxyproto"
+	"net"
+)
+
+// AttrProxyHeader PROXY 协议头部（*proxyproto.Header）属性键，仅携带头部的服务端连接可用，
+// 可用于读取 TLV 扩展字段。
+const AttrProxyHeader = "proxy.header"
+
+// unwrap 沿 NetConn() 链查找指定类型的底层连接（如 TLS、PROXY 协议包装下的 *net.TCPConn）
+func unwrap[T any](raw net.Conn) (T, bool) {
+	for {
+		if t, ok := raw.(T); ok {
+			return t, true
+		}
+		w, ok := raw.(interface{ NetConn() net.Conn })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		raw = w.NetConn()
+	}
+}
+
+// setProxyHeader 将 PROXY 协议头部写入连接属性
+func setProxyHeader(c *Conn, raw net.Conn) {
+	pc, ok := unwrap[*proxyproto.Conn](raw)
+	if !ok || pc.Header() == nil {
+		return
+	}
+	c.Attributes.Set(AttrProxyHeader, pc.Header())
+}
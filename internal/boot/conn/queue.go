@@ -0,0 +1,208 @@
+package conn
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var (
+	ErrSendQueueFull  = errors.New("send queue full")
+	ErrMessageDropped = errors.New("message dropped from send queue")
+)
+
+// QueueFullError 发送队列已满错误，可通过 errors.Is(err, ErrSendQueueFull) 判断
+type QueueFullError struct {
+	Messages int // 队列中的消息数
+	Bytes    int // 队列中的字节数
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("send queue full: %d messages, %d bytes", e.Messages, e.Bytes)
+}
+
+func (e *QueueFullError) Is(target error) bool { return target == ErrSendQueueFull }
+
+// message 定义发送消息
+type message struct {
+	buf  []byte
+	done chan error
+	down func([]byte) error // 处理层经 LayerIO.Queue 入队的帧直接写向其下层，为 nil 时为上层消息
+}
+
+// sendQueue 有界发送队列，按消息数与字节数限制容量，并跟踪高低水位
+type sendQueue struct {
+	size     int // 最大消息数
+	maxBytes int // 最大字节数，0 表示不限制
+	high     int // 高水位，0 表示不启用
+	low      int // 低水位
+
+	mu     sync.Mutex
+	items  []*message
+	bytes  int
+	closed bool
+	above  bool          // 是否处于高水位之上
+	ready  chan struct{} // 有新消息或队列关闭
+	space  chan struct{} // 有空位时关闭并替换，用于唤醒所有阻塞的发送方
+	wait   bool          // 是否有发送方在等待空位
+
+	spawn   func() // 非空时不常驻写协程，入队时若无写协程运行则调用以启动一个
+	running bool   // 按需启动的写协程是否在运行
+}
+
+func newSendQueue(size, maxBytes, high, low int) *sendQueue {
+	return &sendQueue{
+		size:     size,
+		maxBytes: maxBytes,
+		high:     high,
+		low:      low,
+		ready:    make(chan struct{}, 1),
+		space:    make(chan struct{}),
+	}
+}
+
+// push 尝试入队。队列已满时返回 pushed=false 与空位通知通道；
+// high 表示本次入队使队列升至高水位。
+func (q *sendQueue) push(m *message) (pushed, high bool, space <-chan struct{}, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false, false, nil, net.ErrClosed
+	}
+	if q.full(len(m.buf)) {
+		q.wait = true
+		return false, false, q.space, nil
+	}
+
+	q.items = append(q.items, m)
+	q.bytes += len(m.buf)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	if q.spawn != nil && !q.running {
+		q.running = true
+		q.spawn() // 持锁调用，先于 close 发生，关闭时等待写协程的一方不会漏掉它
+	}
+
+	if q.high > 0 && !q.above && q.bytes >= q.high {
+		q.above = true
+		high = true
+	}
+	return true, high, nil, nil
+}
+
+// pop 阻塞取出队首消息，队列关闭且为空时返回 ok=false；
+// low 表示本次出队使队列回落至低水位。
+func (q *sendQueue) pop() (m *message, low, ok bool) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			m, low = q.shift()
+			q.mu.Unlock()
+			return m, low, true
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return nil, false, false
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
+}
+
+// next 非阻塞取出队首消息，供按需启动的写协程使用；队列为空时返回 ok=false，写协程随即退出
+func (q *sendQueue) next() (m *message, low, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		q.running = false
+		return nil, false, false
+	}
+	m, low = q.shift()
+	return m, low, true
+}
+
+// dropOldest 丢弃最早入队的上层消息；处理层的帧不丢弃（丢弃会破坏其协议状态），队列中只有帧时返回 nil
+func (q *sendQueue) dropOldest() (m *message, low bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, it := range q.items {
+		if it.down == nil {
+			return q.remove(i)
+		}
+	}
+	return nil, false
+}
+
+// close 关闭队列，已入队的消息仍可取出
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.ready)
+	close(q.space)
+}
+
+// drain 关闭队列并取出全部剩余消息
+func (q *sendQueue) drain() []*message {
+	q.close()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	q.bytes = 0
+	return items
+}
+
+// len 当前消息数与字节数
+func (q *sendQueue) len() (int, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items), q.bytes
+}
+
+func (q *sendQueue) full(n int) bool {
+	if len(q.items) >= q.size {
+		return true
+	}
+	// 空队列总能容纳一条消息，避免超大消息永远无法发送
+	return q.maxBytes > 0 && len(q.items) > 0 && q.bytes+n > q.maxBytes
+}
+
+// shift 移除队首消息并唤醒等待空位的发送方，调用方需持有锁
+func (q *sendQueue) shift() (m *message, low bool) {
+	return q.remove(0)
+}
+
+// remove 移除第 i 条消息并唤醒等待空位的发送方，调用方需持有锁
+func (q *sendQueue) remove(i int) (m *message, low bool) {
+	m = q.items[i]
+	if i == 0 {
+		q.items[0] = nil
+		q.items = q.items[1:]
+	} else {
+		copy(q.items[i:], q.items[i+1:])
+		q.items[len(q.items)-1] = nil
+		q.items = q.items[:len(q.items)-1]
+	}
+	q.bytes -= len(m.buf)
+
+	if q.wait && !q.closed {
+		close(q.space)
+		q.space = make(chan struct{})
+		q.wait = false
+	}
+
+	if q.above && q.bytes <= q.low {
+		q.above = false
+		low = true
+	}
+	return m, low
+}
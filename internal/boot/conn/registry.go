@@ -0,0 +1,231 @@
+package conn
+
+import (
+	"context"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/hook"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry 服务端连接管理器：连接启动时登记、关闭时移除，并维护用户与连接的一对一绑定
+type Registry struct {
+	mu    sync.RWMutex
+	conns map[string]*Conn  // 连接 ID -> 连接
+	users map[string]*Conn  // 用户 -> 连接
+	uids  map[string]string // 连接 ID -> 用户
+
+	draining bool // 排空已开始，不再登记新连接
+
+	gm     sync.Mutex
+	groups map[string]*Group // 广播组
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		conns:  make(map[string]*Conn),
+		users:  make(map[string]*Conn),
+		uids:   make(map[string]string),
+		groups: make(map[string]*Group),
+	}
+}
+
+func (r *Registry) Get(id string) (boot.Conn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.conns[id]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func (r *Registry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.conns)
+}
+
+// Range 遍历连接快照，fn 中可安全地关闭连接或修改绑定
+func (r *Registry) Range(fn func(c boot.Conn) bool) {
+	for _, c := range r.snapshot() {
+		if !fn(c) {
+			return
+		}
+	}
+}
+
+// CloseIf 并发关闭满足条件的连接，等待全部关闭后返回关闭数量
+func (r *Registry) CloseIf(pred func(c boot.Conn) bool) int {
+	var wg sync.WaitGroup
+	n := 0
+	for _, c := range r.snapshot() {
+		if !pred(c) {
+			continue
+		}
+		n++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Close()
+		}()
+	}
+	wg.Wait()
+	return n
+}
+
+// Bind 将用户绑定到连接。该用户已绑定其他连接时解除旧绑定，
+// 触发旧连接的 OnKick 后将其关闭并返回；c 未登记（如已关闭）时忽略。
+func (r *Registry) Bind(uid string, c boot.Conn) boot.Conn {
+	r.mu.Lock()
+	nc, ok := r.conns[c.ID()]
+	if !ok || boot.Conn(nc) != c {
+		r.mu.Unlock()
+		return nil
+	}
+
+	// 连接改绑其他用户
+	if prev, ok := r.uids[nc.Id]; ok && prev != uid {
+		delete(r.users, prev)
+	}
+
+	old := r.users[uid]
+	if old == nc {
+		r.mu.Unlock()
+		return nil
+	}
+	if old != nil {
+		delete(r.uids, old.Id)
+	}
+	r.users[uid] = nc
+	r.uids[nc.Id] = uid
+	r.mu.Unlock()
+
+	if old == nil {
+		return nil
+	}
+	old.kick(uid)
+	return old
+}
+
+func (r *Registry) Unbind(c boot.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unbind(c.ID())
+}
+
+func (r *Registry) User(uid string) (boot.Conn, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.users[uid]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func (r *Registry) UserOf(c boot.Conn) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	uid, ok := r.uids[c.ID()]
+	return uid, ok
+}
+
+func (r *Registry) Group(name string) boot.Group {
+	r.gm.Lock()
+	defer r.gm.Unlock()
+	g, ok := r.groups[name]
+	if !ok {
+		g = NewGroup(name)
+		r.groups[name] = g
+	}
+	return g
+}
+
+func (r *Registry) RemoveGroup(name string) {
+	r.gm.Lock()
+	defer r.gm.Unlock()
+	delete(r.groups, name)
+}
+
+// Drain 并发排空所有已登记的连接，返回正常排空与被强制关闭的数量
+func (r *Registry) Drain(ctx context.Context) boot.ShutdownReport {
+	// 与 add 互斥：此后完成握手的连接不再登记，快照之外不会有遗漏的连接
+	r.mu.Lock()
+	r.draining = true
+	conns := make([]*Conn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mu.Unlock()
+
+	var drained, killed atomic.Int32
+	var wg sync.WaitGroup
+	for _, c := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Drain(ctx) {
+				drained.Add(1)
+			} else {
+				killed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return boot.ShutdownReport{Drained: int(drained.Load()), Killed: int(killed.Load())}
+}
+
+// ---- 连接内部调用 ----
+
+// add 登记连接，排空开始后返回 false
+func (r *Registry) add(c *Conn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return false
+	}
+	r.conns[c.Id] = c
+	return true
+}
+
+func (r *Registry) remove(c *Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conns[c.Id] != c {
+		return
+	}
+	delete(r.conns, c.Id)
+	r.unbind(c.Id)
+}
+
+func (r *Registry) unbind(id string) {
+	if uid, ok := r.uids[id]; ok {
+		delete(r.uids, id)
+		delete(r.users, uid)
+	}
+}
+
+func (r *Registry) snapshot() []boot.Conn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conns := make([]boot.Conn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// kick 派发 OnKick 后关闭连接，OnKick 中发送的消息在关闭前写出
+func (c *Conn) kick(uid string) {
+	task := func() {
+		if h, ok := c.Hook.(hook.KickHook); ok {
+			h.OnKick(c, uid)
+		}
+		c.Close()
+	}
+	// 协程池拒绝时仍需关闭连接
+	if !c.Pool.Submit(task) {
+		go task()
+	}
+}
@@ -0,0 +1,250 @@
+package conn
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/lifecycle"
+)
+
+type countHook struct {
+	hook.ConnEvent
+	connects atomic.Int32
+}
+
+func (h *countHook) OnConnect(c boot.Conn) { h.connects.Add(1) }
+
+func newTestConn(t *testing.T, h hook.ConnHook) (*Conn, net.Conn) {
+	t.Helper()
+	cfg := &conf.Config{}
+	cfg.WithDefault()
+	a, b := net.Pipe()
+	t.Cleanup(func() { _ = b.Close() })
+	return NewNETConn(context.Background(), a, cfg, h), b
+}
+
+func TestDrainRefusesLateConn(t *testing.T) {
+	reg := NewRegistry()
+	h := &countHook{}
+	var wg sync.WaitGroup
+
+	early, _ := newTestConn(t, h)
+	early.Registry = reg
+	early.Start(&wg)
+	if _, ok := reg.conns[early.Id]; !ok {
+		t.Fatal("conn started before drain is not registered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if report := reg.Drain(ctx); report.Drained != 1 || report.Killed != 0 {
+		t.Fatalf("drain report = %+v, want 1 drained", report)
+	}
+
+	// 排空开始后才完成握手的连接：不登记、不派发 OnConnect，立即处于关闭状态
+	late, peer := newTestConn(t, h)
+	late.Registry = reg
+	late.Start(&wg)
+	if _, ok := reg.conns[late.Id]; ok {
+		t.Fatal("conn started after drain was registered")
+	}
+	if got := late.State(); got != lifecycle.Closed {
+		t.Fatalf("late conn state = %v, want Closed", got)
+	}
+	if err := context.Cause(late.Ctx); err != net.ErrClosed {
+		t.Fatalf("late conn cause = %v, want net.ErrClosed", err)
+	}
+	if _, err := peer.Write([]byte{0}); err == nil {
+		t.Fatal("late conn transport is still open")
+	}
+	if n := h.connects.Load(); n != 1 {
+		t.Fatalf("OnConnect called %d times, want 1", n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		late.Close()
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close of refused conn blocked")
+	}
+}
+
+type kickHook struct {
+	hook.ConnEvent
+	kicked chan string // "连接 ID:用户"
+}
+
+func (h *kickHook) OnKick(c boot.Conn, uid string) { h.kicked <- c.ID() + ":" + uid }
+
+// startRegistered 启动登记到 reg 的连接
+func startRegistered(t *testing.T, reg *Registry, h hook.ConnHook) *Conn {
+	t.Helper()
+	c, _ := newTestConn(t, h)
+	c.Registry = reg
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		c.Close()
+		wg.Wait()
+	})
+	return c
+}
+
+func waitClosed(t *testing.T, c *Conn) {
+	t.Helper()
+	select {
+	case <-c.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("conn %s not closed", c.Id)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	reg := NewRegistry()
+	h := &countHook{}
+	c1 := startRegistered(t, reg, h)
+	c2 := startRegistered(t, reg, h)
+
+	if got, ok := reg.Get(c1.Id); !ok || got != boot.Conn(c1) {
+		t.Fatalf("Get(%s) = %v, %v", c1.Id, got, ok)
+	}
+	if _, ok := reg.Get("missing"); ok {
+		t.Fatal("Get of unknown id succeeded")
+	}
+	if n := reg.Count(); n != 2 {
+		t.Fatalf("Count = %d, want 2", n)
+	}
+	seen := map[string]bool{}
+	reg.Range(func(c boot.Conn) bool {
+		seen[c.ID()] = true
+		return true
+	})
+	if !seen[c1.Id] || !seen[c2.Id] {
+		t.Fatalf("Range visited %v", seen)
+	}
+	visits := 0
+	reg.Range(func(c boot.Conn) bool {
+		visits++
+		return false
+	})
+	if visits != 1 {
+		t.Fatalf("Range continued after false: %d visits", visits)
+	}
+
+	if n := reg.CloseIf(func(c boot.Conn) bool { return c.ID() == c2.Id }); n != 1 {
+		t.Fatalf("CloseIf = %d, want 1", n)
+	}
+	if _, ok := reg.Get(c2.Id); ok {
+		t.Fatal("closed conn is still registered")
+	}
+	if n := reg.Count(); n != 1 {
+		t.Fatalf("Count after CloseIf = %d, want 1", n)
+	}
+}
+
+func TestRegistryBind(t *testing.T) {
+	reg := NewRegistry()
+	h := &kickHook{kicked: make(chan string, 4)}
+	c1 := startRegistered(t, reg, h)
+	c2 := startRegistered(t, reg, h)
+
+	if old := reg.Bind("alice", c1); old != nil {
+		t.Fatalf("first Bind returned %v", old)
+	}
+	if old := reg.Bind("alice", c1); old != nil {
+		t.Fatalf("repeated Bind returned %v", old)
+	}
+	if got, ok := reg.User("alice"); !ok || got != boot.Conn(c1) {
+		t.Fatalf("User(alice) = %v, %v", got, ok)
+	}
+	if uid, ok := reg.UserOf(c1); !ok || uid != "alice" {
+		t.Fatalf("UserOf(c1) = %q, %v", uid, ok)
+	}
+	if _, ok := reg.User("bob"); ok {
+		t.Fatal("User of unbound uid succeeded")
+	}
+
+	// 连接改绑其他用户：旧用户不再指向该连接，不踢出
+	if old := reg.Bind("bob", c1); old != nil {
+		t.Fatalf("rebinding conn returned %v", old)
+	}
+	if _, ok := reg.User("alice"); ok {
+		t.Fatal("alice is still bound after c1 was rebound to bob")
+	}
+	if uid, _ := reg.UserOf(c1); uid != "bob" {
+		t.Fatalf("UserOf(c1) = %q, want bob", uid)
+	}
+
+	reg.Unbind(c1)
+	if _, ok := reg.User("bob"); ok {
+		t.Fatal("bob is still bound after Unbind")
+	}
+	if _, ok := reg.UserOf(c1); ok {
+		t.Fatal("c1 still has a user after Unbind")
+	}
+	select {
+	case k := <-h.kicked:
+		t.Fatalf("unexpected kick %s", k)
+	default:
+	}
+
+	// 关闭的连接自动解绑，之后的 Bind 被忽略
+	reg.Bind("carol", c2)
+	c2.Close()
+	waitClosed(t, c2)
+	if _, ok := reg.User("carol"); ok {
+		t.Fatal("carol is still bound to a closed conn")
+	}
+	if old := reg.Bind("carol", c2); old != nil {
+		t.Fatalf("Bind of closed conn returned %v", old)
+	}
+	if _, ok := reg.User("carol"); ok {
+		t.Fatal("closed conn was bound")
+	}
+}
+
+func TestRegistryKick(t *testing.T) {
+	reg := NewRegistry()
+	h := &kickHook{kicked: make(chan string, 4)}
+	c1 := startRegistered(t, reg, h)
+	c2 := startRegistered(t, reg, h)
+
+	reg.Bind("alice", c1)
+	if old := reg.Bind("alice", c2); old != boot.Conn(c1) {
+		t.Fatalf("Bind to new conn returned %v, want the old conn", old)
+	}
+	select {
+	case k := <-h.kicked:
+		if want := c1.Id + ":alice"; k != want {
+			t.Fatalf("kicked %s, want %s", k, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnKick not called")
+	}
+	waitClosed(t, c1)
+
+	if got, ok := reg.User("alice"); !ok || got != boot.Conn(c2) {
+		t.Fatalf("User(alice) = %v, %v, want c2", got, ok)
+	}
+	if _, ok := reg.UserOf(c1); ok {
+		t.Fatal("kicked conn still has a user")
+	}
+	if _, ok := reg.Get(c1.Id); ok {
+		t.Fatal("kicked conn is still registered")
+	}
+	if c2.State() == lifecycle.Closed {
+		t.Fatal("new conn was closed")
+	}
+}
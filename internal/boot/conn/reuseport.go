@@ -0,0 +1,50 @@
+package conn
+
+import (
+	"context"
+	"net"
+)
+
+// ListenReusePort 在同一地址上打开 n 个设置 SO_REUSEPORT 的流式监听器，由内核在其间分发新连接；
+// 地址端口为 0 时其余监听器绑定到第一个监听器获得的端口。不支持 SO_REUSEPORT 的平台只打开一个监听器
+func ListenReusePort(ctx context.Context, network, address string, n int) ([]net.Listener, error) {
+	if !ReusePortSupported {
+		n = 1
+	}
+	lc := net.ListenConfig{Control: reusePortControl}
+	lns := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		ln, err := lc.Listen(ctx, network, address)
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		lns = append(lns, ln)
+		address = ln.Addr().String()
+	}
+	return lns, nil
+}
+
+// ListenPacketReusePort 在同一地址上打开 n 个设置 SO_REUSEPORT 的数据报套接字，
+// 内核按四元组哈希分发数据报，同一对端固定落在同一个套接字上。不支持 SO_REUSEPORT 的平台只打开一个套接字
+func ListenPacketReusePort(ctx context.Context, network, address string, n int) ([]net.PacketConn, error) {
+	if !ReusePortSupported {
+		n = 1
+	}
+	lc := net.ListenConfig{Control: reusePortControl}
+	pcs := make([]net.PacketConn, 0, n)
+	for i := 0; i < n; i++ {
+		pc, err := lc.ListenPacket(ctx, network, address)
+		if err != nil {
+			for _, p := range pcs {
+				_ = p.Close()
+			}
+			return nil, err
+		}
+		pcs = append(pcs, pc)
+		address = pc.LocalAddr().String()
+	}
+	return pcs, nil
+}
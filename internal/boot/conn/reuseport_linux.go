@@ -0,0 +1,6 @@
+//go:build linux && (386 || amd64 || arm)
+
+package conn
+
+// soReusePort syscall 包在这些平台上未导出 SO_REUSEPORT
+const soReusePort = 0xf
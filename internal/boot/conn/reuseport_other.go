@@ -0,0 +1,13 @@
+//go:build !(aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package conn
+
+import "syscall"
+
+// ReusePortSupported 当前平台不支持 SO_REUSEPORT，ListenReusePort / ListenPacketReusePort 退化为单个套接字
+const ReusePortSupported = false
+
+// reusePortControl 当前平台无需设置
+func reusePortControl(network, address string, rc syscall.RawConn) error {
+	return nil
+}
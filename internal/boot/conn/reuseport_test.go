@@ -0,0 +1,176 @@
+package conn
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wantShards 当前平台下请求 n 个套接字时应得到的数量
+func wantShards(n int) int {
+	if !ReusePortSupported {
+		return 1
+	}
+	return n
+}
+
+// TestListenReusePort 各监听器共享同一端口；Linux 下内核按四元组哈希把新连接分发到所有监听器
+func TestListenReusePort(t *testing.T) {
+	const n = 4
+	lns, err := ListenReusePort(context.Background(), "tcp", "127.0.0.1:0", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, ln := range lns {
+			_ = ln.Close()
+		}
+	}()
+	if len(lns) != wantShards(n) {
+		t.Fatalf("opened %d listeners, want %d", len(lns), wantShards(n))
+	}
+	addr := lns[0].Addr().String()
+	for _, ln := range lns[1:] {
+		if ln.Addr().String() != addr {
+			t.Fatalf("listener on %s, want %s", ln.Addr(), addr)
+		}
+	}
+
+	accepted := make([]int, len(lns))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, ln := range lns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				accepted[i]++
+				mu.Unlock()
+				_ = c.Close()
+			}
+		}()
+	}
+
+	const conns = 64
+	for i := 0; i < conns; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = c.Close()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		total := 0
+		for _, a := range accepted {
+			total += a
+		}
+		mu.Unlock()
+		if total == conns {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("accepted %d of %d connections", total, conns)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+	wg.Wait()
+
+	if runtime.GOOS == "linux" {
+		for i, a := range accepted {
+			if a == 0 {
+				t.Fatalf("listener %d accepted nothing: %v", i, accepted)
+			}
+		}
+	}
+}
+
+// TestListenPacketReusePort 数据报按四元组分发：同一对端固定落在同一个套接字上，Linux 下各套接字均有对端
+func TestListenPacketReusePort(t *testing.T) {
+	const n = 4
+	pcs, err := ListenPacketReusePort(context.Background(), "udp", "127.0.0.1:0", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, pc := range pcs {
+			_ = pc.Close()
+		}
+	}()
+	if len(pcs) != wantShards(n) {
+		t.Fatalf("opened %d sockets, want %d", len(pcs), wantShards(n))
+	}
+	addr := pcs[0].LocalAddr().String()
+
+	type recv struct {
+		shard int
+		from  string
+	}
+	got := make(chan recv, 1024)
+	for i, pc := range pcs {
+		go func() {
+			buf := make([]byte, 64)
+			for {
+				_, from, err := pc.ReadFrom(buf)
+				if err != nil {
+					return
+				}
+				got <- recv{i, from.String()}
+			}
+		}()
+	}
+
+	const peers, rounds = 64, 3
+	var clients []net.Conn
+	for i := 0; i < peers; i++ {
+		c, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		clients = append(clients, c)
+	}
+	for r := 0; r < rounds; r++ {
+		for _, c := range clients {
+			if _, err := c.Write([]byte("x")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	shardOf := make(map[string]int)
+	perShard := make([]int, len(pcs))
+	for i := 0; i < peers*rounds; i++ {
+		select {
+		case r := <-got:
+			if s, ok := shardOf[r.from]; ok && s != r.shard {
+				t.Fatalf("peer %s moved from socket %d to %d", r.from, s, r.shard)
+			}
+			if _, ok := shardOf[r.from]; !ok {
+				perShard[r.shard]++
+			}
+			shardOf[r.from] = r.shard
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d datagrams", i, peers*rounds)
+		}
+	}
+	if runtime.GOOS == "linux" {
+		for i, p := range perShard {
+			if p == 0 {
+				t.Fatalf("socket %d has no peers: %v", i, perShard)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package conn
+
+import "syscall"
+
+// ReusePortSupported 当前平台支持 SO_REUSEPORT
+const ReusePortSupported = true
+
+// reusePortControl 在绑定前设置 SO_REUSEPORT
+func reusePortControl(network, address string, rc syscall.RawConn) error {
+	var serr error
+	err := rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
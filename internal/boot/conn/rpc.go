@@ -0,0 +1,135 @@
+package conn
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"net"
+)
+
+// 信封帧格式（启用 RPC 后替代 Framer）：
+//
+//	[kind 1][id 8][length 4][body]
+//
+// kind 为普通消息、请求或应答，id 用于关联请求与应答，body 为 Encoder 的输出。
+const (
+	kindMessage byte = 0
+	kindRequest byte = 1
+	kindReply   byte = 2
+
+	envelopeHeader  = 13
+	EnvelopeMaxSize = 16 << 20
+)
+
+var (
+	ErrRPCDisabled      = errors.New("rpc not enabled")
+	ErrEnvelopeTooLarge = errors.New("envelope too large")
+	ErrEnvelopeKind     = errors.New("unknown envelope kind")
+)
+
+// rpcResult 应答结果
+type rpcResult struct {
+	msg any
+	err error
+}
+
+// Call 发送请求并等待对端通过 Context.Reply 应答。
+// ctx 结束时返回 ctx.Err()，连接关闭时返回 net.ErrClosed。
+func (c *Conn) Call(ctx context.Context, msg any) (any, error) {
+	if !c.Cfg.RPC {
+		return nil, ErrRPCDisabled
+	}
+
+	id := c.callID.Add(1)
+	ch := make(chan rpcResult, 1)
+	c.cm.Lock()
+	c.calls[id] = ch
+	c.cm.Unlock()
+	defer func() {
+		c.cm.Lock()
+		delete(c.calls, id)
+		c.cm.Unlock()
+	}()
+
+	sent := c.send(ctx, kindRequest, id, msg)
+	for {
+		select {
+		case err := <-sent:
+			if err != nil {
+				return nil, err
+			}
+			sent = nil
+		case r := <-ch:
+			return r.msg, r.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.Ctx.Done():
+			return nil, net.ErrClosed
+		}
+	}
+}
+
+// reply 返回应答函数，用于请求的处理上下文
+func (c *Conn) reply(id uint64) func(msg any) <-chan error {
+	return func(msg any) <-chan error {
+		return c.send(c.Ctx, kindReply, id, msg)
+	}
+}
+
+// recvEnvelope 按信封类型派发：应答直接在读协程中解码并唤醒调用方，避免有序模式下处理器内 Call 死锁
+func (c *Conn) recvEnvelope(frame []byte) {
+	kind := frame[0]
+	id := binary.BigEndian.Uint64(frame[1:])
+	body := frame[envelopeHeader:]
+
+	switch kind {
+	case kindMessage:
+		c.dispatchFrame(body, nil)
+	case kindRequest:
+		c.dispatchFrame(body, c.reply(id))
+	case kindReply:
+		// 取出即删除：重复的应答找不到调用方而被丢弃，读协程不会阻塞在已有结果的通道上
+		c.cm.Lock()
+		ch, ok := c.calls[id]
+		delete(c.calls, id)
+		c.cm.Unlock()
+		if !ok {
+			c.Log.Debug("conn %s drop reply %d: no pending call", c.Id, id)
+			return
+		}
+		msg, err := (*c.dec.Load())(c, body)
+		if err != nil {
+			err = fmt.Errorf("decoder error: %w", err)
+		}
+		ch <- rpcResult{msg: msg, err: err}
+	default:
+		c.dispatchError(fmt.Errorf("%w: %d", ErrEnvelopeKind, kind))
+	}
+}
+
+// envelope 封装信封帧
+func envelope(kind byte, id uint64, body []byte) []byte {
+	buf := make([]byte, envelopeHeader, envelopeHeader+len(body))
+	buf[0] = kind
+	binary.BigEndian.PutUint64(buf[1:], id)
+	binary.BigEndian.PutUint32(buf[9:], uint32(len(body)))
+	return append(buf, body...)
+}
+
+// envelopeFramer 按信封帧拆分字节流，返回的帧包含信封头
+func envelopeFramer(c boot.Conn, buf []byte) (frames [][]byte, remaining []byte, err error) {
+	for len(buf) >= envelopeHeader {
+		n := int(binary.BigEndian.Uint32(buf[9:]))
+		if n > EnvelopeMaxSize {
+			return frames, buf, fmt.Errorf("%w: %d bytes", ErrEnvelopeTooLarge, n)
+		}
+		if len(buf) < envelopeHeader+n {
+			break
+		}
+		frames = append(frames, buf[:envelopeHeader+n])
+		buf = buf[envelopeHeader+n:]
+	}
+	return frames, buf, nil
+}
@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"context"
+	"github.com/yurazsb/uno/internal/lifecycle"
+	"github.com/yurazsb/uno/pkg/state"
+)
+
+// newMachine 创建连接的生命周期状态机：同步执行迁移，状态生效之后的钩子经连接的协程池执行
+func newMachine(c *Conn) *state.Machine {
+	return state.NewMachine(c.Id, lifecycle.Connecting,
+		state.WithSync(),
+		state.WithExecutor(c.Pool.Submit),
+		state.WithFinal(lifecycle.Closing, lifecycle.Closed), // 进入 Closing 后不再接受自定义迁移
+	)
+}
+
+// State 当前状态：生命周期状态或应用注册的自定义状态
+func (c *Conn) State() state.State { return c.sm.Snapshot().State }
+
+// Machine 连接的状态机，可注册自定义状态、迁移与钩子
+func (c *Conn) Machine() *state.Machine { return c.sm }
+
+// enter 进入生命周期状态 s，只前进不后退：已处于 s 或更靠后的阶段时忽略
+func (c *Conn) enter(s state.State) {
+	c.lm.Lock()
+	defer c.lm.Unlock()
+	if state.State(c.phase.Load()) >= s {
+		return
+	}
+	c.phase.Store(int32(s))
+	_ = c.sm.Force(context.Background(), s)
+}
+
+// active 已启动且尚未进入 Closing
+func (c *Conn) active() bool {
+	p := state.State(c.phase.Load())
+	return p > lifecycle.Connecting && p < lifecycle.Closing
+}
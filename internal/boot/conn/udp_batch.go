@@ -0,0 +1,130 @@
+package conn
+
+import (
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/mmsg"
+	"net"
+	"sync"
+	"time"
+)
+
+// batchReq 一次待批量发送的写入
+type batchReq struct {
+	buf  []byte
+	addr net.Addr
+	done chan error
+}
+
+var batchReqPool = sync.Pool{New: func() any { return &batchReq{done: make(chan error, 1)} }}
+
+// batchWriter 汇集同一套接字上各伪连接的并发写入，每次系统调用发送一批数据报
+type batchWriter struct {
+	raw  net.PacketConn
+	bc   mmsg.Conn
+	cfg  *conf.Config
+	size int
+	reqs chan *batchReq
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBatchWriter(raw net.PacketConn, cfg *conf.Config) *batchWriter {
+	w := &batchWriter{
+		raw:  raw,
+		bc:   mmsg.New(raw),
+		cfg:  cfg,
+		size: cfg.UDPBatchSize,
+		reqs: make(chan *batchReq, cfg.UDPBatchSize*4),
+		stop: make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// write 提交写入并等待其所在批次发送完成
+func (w *batchWriter) write(buf []byte, addr net.Addr) error {
+	r := batchReqPool.Get().(*batchReq)
+	r.buf, r.addr = buf, addr
+	select {
+	case w.reqs <- r:
+	case <-w.stop:
+		r.buf, r.addr = nil, nil
+		batchReqPool.Put(r)
+		return net.ErrClosed
+	}
+	select {
+	case err := <-r.done:
+		r.buf, r.addr = nil, nil
+		batchReqPool.Put(r)
+		return err
+	case <-w.stop:
+		return net.ErrClosed // 请求可能仍被发送协程持有，不再复用
+	}
+}
+
+func (w *batchWriter) loop() {
+	defer w.wg.Done()
+
+	reqs := make([]*batchReq, 0, w.size)
+	ms := make([]mmsg.Message, w.size)
+	for {
+		select {
+		case r := <-w.reqs:
+			reqs = append(reqs[:0], r)
+		case <-w.stop:
+			return
+		}
+		// 取走已排队的写入，凑成一批
+	fill:
+		for len(reqs) < w.size {
+			select {
+			case r := <-w.reqs:
+				reqs = append(reqs, r)
+			default:
+				break fill
+			}
+		}
+		w.flush(reqs, ms[:len(reqs)])
+	}
+}
+
+// flush 发送一批数据报并逐个通知结果，失败的数据报跳过后继续发送剩余部分
+func (w *batchWriter) flush(reqs []*batchReq, ms []mmsg.Message) {
+	for i, r := range reqs {
+		ms[i] = mmsg.Message{Buf: r.buf, Addr: r.addr}
+	}
+
+	timeout := w.cfg.WriteTimeout
+	if timeout <= 0 {
+		timeout = WriteTimeout
+	}
+	_ = w.raw.SetWriteDeadline(time.Now().Add(timeout))
+
+	for sent := 0; sent < len(reqs); {
+		n, err := w.bc.WriteBatch(ms[sent:])
+		for _, r := range reqs[sent : sent+n] {
+			r.done <- nil
+		}
+		sent += n
+		if sent < len(reqs) {
+			reqs[sent].done <- err
+			sent++
+		}
+	}
+	clear(ms)
+}
+
+// close 停止发送协程，尚未提交的写入返回 net.ErrClosed
+func (w *batchWriter) close() {
+	close(w.stop)
+	w.wg.Wait()
+	for {
+		select {
+		case r := <-w.reqs:
+			r.done <- net.ErrClosed
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package conn
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+// Unix 对端凭证属性键（SO_PEERCRED），仅 Linux 下的 unix/unixpacket 连接可用
+const (
+	AttrPeerUID = "unix.peer.uid"
+	AttrPeerGID = "unix.peer.gid"
+	AttrPeerPID = "unix.peer.pid"
+)
+
+// IsUnixNetwork 判断是否为 Unix 域套接字网络类型
+func IsUnixNetwork(network string) bool {
+	switch network {
+	case "unix", "unixpacket", "unixgram":
+		return true
+	default:
+		return false
+	}
+}
+
+// RemoveStaleSocket 删除残留的套接字文件，避免监听时报地址已被占用。
+// 仅当 path 存在且为套接字文件时删除，抽象命名空间（@ 开头）直接忽略。
+func RemoveStaleSocket(path string) error {
+	if path == "" || strings.HasPrefix(path, "@") {
+		return nil
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// ChmodSocket 设置套接字文件权限，mode 为 0 时保持系统默认
+func ChmodSocket(path string, mode os.FileMode) error {
+	if mode == 0 || path == "" || strings.HasPrefix(path, "@") {
+		return nil
+	}
+	return os.Chmod(path, mode)
+}
+
+// setPeerCred 将对端凭证写入连接属性
+func setPeerCred(c *Conn, raw net.Conn) {
+	uc, ok := unwrap[*net.UnixConn](raw)
+	if !ok {
+		return
+	}
+	uid, gid, pid, ok := peerCred(uc)
+	if !ok {
+		return
+	}
+	c.Attributes.Set(AttrPeerUID, uid)
+	c.Attributes.Set(AttrPeerGID, gid)
+	c.Attributes.Set(AttrPeerPID, pid)
+}
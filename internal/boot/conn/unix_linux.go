@@ -0,0 +1,26 @@
+//go:build linux
+
+package conn
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCred 通过 SO_PEERCRED 读取对端进程凭证
+func peerCred(uc *net.UnixConn) (uid, gid uint32, pid int32, ok bool) {
+	rc, err := uc.SyscallConn()
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	var cred *syscall.Ucred
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		cred, serr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil || serr != nil || cred == nil {
+		return 0, 0, 0, false
+	}
+	return cred.Uid, cred.Gid, cred.Pid, true
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package conn
+
+import "net"
+
+// peerCred 非 Linux 平台不支持 SO_PEERCRED
+func peerCred(uc *net.UnixConn) (uid, gid uint32, pid int32, ok bool) {
+	return 0, 0, 0, false
+}
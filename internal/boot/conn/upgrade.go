@@ -0,0 +1,132 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/upgrade"
+	"net"
+)
+
+// Upgrade 运行时替换编解码并可选开启 TLS，详见 upgrade.Options
+func (c *Conn) Upgrade(ctx context.Context, opts upgrade.Options) error {
+	if opts.Framer != nil && c.Cfg.RPC {
+		return fmt.Errorf("%w: framer cannot be replaced in RPC mode", upgrade.ErrUnsupported)
+	}
+	var nt *NETTransport
+	if opts.TLS != nil {
+		var err error
+		if nt, err = c.plainStream(); err != nil {
+			return err
+		}
+		if c.Client && c.DialAddr == "" && opts.TLS.ServerName == "" && !opts.TLS.InsecureSkipVerify {
+			return upgrade.ErrServerName
+		}
+	}
+
+	c.um.Lock()
+	defer c.um.Unlock()
+	if !c.IsActive() {
+		return net.ErrClosed
+	}
+
+	// 确认消息以旧的 Encoder 编码
+	var reply []byte
+	if opts.Reply != nil {
+		var err error
+		if reply, err = c.encode(kindMessage, 0, opts.Reply); err != nil {
+			return err
+		}
+	}
+
+	// 替换与拆帧互斥：开启 TLS 时此后读出的数据暂存于读缓冲，作为握手的开头
+	c.rm.Lock()
+	if opts.Framer != nil {
+		c.setFramer(opts.Framer)
+	}
+	if opts.Decoder != nil {
+		c.dec.Store(&opts.Decoder)
+	}
+	if opts.Encoder != nil {
+		c.enc.Store(&opts.Encoder)
+	}
+	if nt != nil {
+		c.held = true
+	} else {
+		c.reframe()
+	}
+	c.rm.Unlock()
+
+	if reply != nil {
+		done := make(chan error, 1)
+		if err := c.push(ctx, reply, done, opts.Reply); err != nil {
+			return err
+		}
+		if nt != nil { // 确认以明文写出后才能开始握手
+			select {
+			case err := <-done:
+				if err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	if nt == nil {
+		return nil
+	}
+
+	if err := nt.startTLS(ctx, c, opts.TLS, c.takeReadBuf); err != nil {
+		c.fail(fmt.Errorf("upgrade: %w", err))
+		return err
+	}
+	c.rm.Lock()
+	c.held = false
+	c.reframe()
+	c.rm.Unlock()
+	return nil
+}
+
+// plainStream 返回可开启 TLS 的明文流式传输层
+func (c *Conn) plainStream() (*NETTransport, error) {
+	nt, ok := c.T.(*NETTransport) // 不含事件循环、WebSocket 与 UDP 伪连接
+	if !ok || len(c.layers) > 0 || IsPacketNetwork(nt.local.Network()) {
+		return nil, fmt.Errorf("%w: TLS requires a stream connection served by a read goroutine", upgrade.ErrUnsupported)
+	}
+	if nt.state.Load() != nil {
+		return nil, fmt.Errorf("%w: connection is already TLS", upgrade.ErrUnsupported)
+	}
+	return nt, nil
+}
+
+// setFramer 替换 Framer 并按其重新识别心跳帧，调用方需持有 rm
+func (c *Conn) setFramer(f framer.Framer) {
+	c.framer = f
+	if c.hb != nil {
+		c.hb.reframe(c)
+	}
+}
+
+// reframe 按当前 Framer 拆分并派发读缓冲中尚未拆帧的数据，调用方需持有 rm
+func (c *Conn) reframe() {
+	if c.readBuf.Len() == 0 {
+		return
+	}
+	frames, err := c.split()
+	if err != nil {
+		c.dispatchRead(nil, err)
+		return
+	}
+	c.deliver(frames)
+}
+
+// takeReadBuf 取出读缓冲中尚未拆帧的数据
+func (c *Conn) takeReadBuf() []byte {
+	c.rm.Lock()
+	defer c.rm.Unlock()
+	buf := bytes.Clone(c.readBuf.Bytes())
+	c.readBuf = bytes.Buffer{}
+	return buf
+}
@@ -0,0 +1,150 @@
+package conn
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/conf"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AttrWSRequest WebSocket 握手请求（*http.Request）属性键，仅服务端连接可用，
+// 可用于读取路径、查询参数与鉴权头。
+const AttrWSRequest = "ws.request"
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WSAccept 完成服务端 HTTP/1.1 Upgrade 握手。
+// 握手失败时回写对应的 HTTP 错误响应，调用方负责关闭连接。
+func WSAccept(raw net.Conn, cfg *conf.Config) (*bufio.Reader, *http.Request, error) {
+	_ = raw.SetDeadline(time.Now().Add(WSHandshakeTimeout))
+	defer func() { _ = raw.SetDeadline(time.Time{}) }()
+
+	br := bufio.NewReaderSize(raw, cfg.ReadBufferSize)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("websocket: read handshake request: %w", err)
+	}
+
+	reject := func(status int, reason string) (*bufio.Reader, *http.Request, error) {
+		_, _ = fmt.Fprintf(raw, "HTTP/1.1 %d %s\r\nConnection: close\r\nSec-WebSocket-Version: 13\r\n\r\n", status, http.StatusText(status))
+		return nil, nil, fmt.Errorf("websocket: %s", reason)
+	}
+
+	if req.Method != http.MethodGet {
+		return reject(http.StatusMethodNotAllowed, "handshake method must be GET")
+	}
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return reject(http.StatusBadRequest, "missing upgrade headers")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return reject(http.StatusUpgradeRequired, "unsupported version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return reject(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if cfg.WSPath != "" && req.URL.Path != cfg.WSPath {
+		return reject(http.StatusNotFound, "path not found: "+req.URL.Path)
+	}
+	if cfg.WSCheckOrigin != nil && !cfg.WSCheckOrigin(req) {
+		return reject(http.StatusForbidden, "origin not allowed")
+	}
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n\r\n"
+	if _, err = raw.Write([]byte(resp)); err != nil {
+		return nil, nil, fmt.Errorf("websocket: write handshake response: %w", err)
+	}
+	return br, req, nil
+}
+
+// WSHandshake 完成客户端 HTTP/1.1 Upgrade 握手
+func WSHandshake(raw net.Conn, cfg *conf.Config, u *url.URL) (*bufio.Reader, error) {
+	_ = raw.SetDeadline(time.Now().Add(WSHandshakeTimeout))
+	defer func() { _ = raw.SetDeadline(time.Time{}) }()
+
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	path := u.RequestURI()
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := raw.Write([]byte(req)); err != nil {
+		return nil, fmt.Errorf("websocket: write handshake request: %w", err)
+	}
+
+	br := bufio.NewReaderSize(raw, cfg.ReadBufferSize)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		return nil, fmt.Errorf("websocket: read handshake response: %w", err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("websocket: bad handshake status: %s", resp.Status)
+	}
+	if !headerContains(resp.Header, "Connection", "upgrade") || !headerContains(resp.Header, "Upgrade", "websocket") {
+		return nil, errors.New("websocket: missing upgrade headers")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAcceptKey(key) {
+		return nil, errors.New("websocket: invalid Sec-WebSocket-Accept")
+	}
+	return br, nil
+}
+
+// ParseWSURL 解析 WebSocket 拨号地址，支持 "ws://host:port/path" 与 "host:port" 两种写法
+func ParseWSURL(network, addr string) (*url.URL, error) {
+	if !strings.Contains(addr, "://") {
+		addr = network + "://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains 判断逗号分隔的头部值中是否包含 token（大小写不敏感）
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,410 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+)
+
+type wsHook struct {
+	hook.ConnEvent
+	msgs chan string
+	errs chan error
+}
+
+func (h *wsHook) OnMessage(c boot.Conn, msg any) { h.msgs <- string(msg.([]byte)) }
+func (h *wsHook) OnError(c boot.Conn, err error) { h.errs <- err }
+
+// wsFrame 组装一帧，mask 为 true 时以固定掩码键掩码负载
+func wsFrame(fin bool, op byte, payload []byte, mask bool) []byte {
+	b := []byte{op}
+	if fin {
+		b[0] |= 0x80
+	}
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, maskBit|byte(n))
+	case n <= 0xFFFF:
+		b = append(b, maskBit|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, maskBit|127)
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	p := append([]byte(nil), payload...)
+	if mask {
+		key := [4]byte{1, 2, 3, 4}
+		b = append(b, key[:]...)
+		wsMask(key, p)
+	}
+	return append(b, p...)
+}
+
+// clientFrame 客户端发出的单帧（已掩码）
+func clientFrame(op byte, payload string) []byte {
+	return wsFrame(true, op, []byte(payload), true)
+}
+
+func closePayload(code uint16, reason string) string {
+	return string(binary.BigEndian.AppendUint16(nil, code)) + reason
+}
+
+// newWSTestConn 创建服务端 WebSocket 连接，返回以客户端身份读取其输出的对端
+func newWSTestConn(t *testing.T, maxMessageSize int) (*Conn, net.Conn, *WSTransport, *wsHook) {
+	t.Helper()
+	cfg := &conf.Config{WSMaxMessageSize: maxMessageSize}
+	cfg.WithDefault()
+	a, b := net.Pipe()
+	h := &wsHook{msgs: make(chan string, 16), errs: make(chan error, 16)}
+	c := NewWSConn(context.Background(), a, bufio.NewReader(a), cfg, h, false)
+	peer := &WSTransport{raw: b, br: bufio.NewReader(b), cfg: cfg, client: true}
+	var wg sync.WaitGroup
+	c.Start(&wg)
+	t.Cleanup(func() {
+		_ = b.Close()
+		c.Close()
+		wg.Wait()
+	})
+	return c, b, peer, h
+}
+
+// readServerFrame 读取服务端发出的下一帧
+func readServerFrame(t *testing.T, peer *WSTransport) (byte, []byte) {
+	t.Helper()
+	_ = peer.raw.SetReadDeadline(time.Now().Add(5 * time.Second))
+	fin, op, payload, err := peer.readFrame()
+	if err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if !fin {
+		t.Fatalf("server sent a fragmented frame (op %d)", op)
+	}
+	return op, payload
+}
+
+// expectClose 读取服务端的关闭帧并校验状态码，随后连接关闭
+func expectClose(t *testing.T, c *Conn, peer *WSTransport, code uint16) {
+	t.Helper()
+	op, payload := readServerFrame(t, peer)
+	if op != wsOpClose {
+		t.Fatalf("frame op = %d, want close", op)
+	}
+	if len(payload) < 2 || binary.BigEndian.Uint16(payload) != code {
+		t.Fatalf("close payload = % x, want code %d", payload, code)
+	}
+	select {
+	case <-c.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("conn not closed after close frame")
+	}
+}
+
+func expectMsg(t *testing.T, h *wsHook, want string) {
+	t.Helper()
+	select {
+	case got := <-h.msgs:
+		if got != want {
+			t.Fatalf("message = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive %q", want)
+	}
+}
+
+// TestWSAcceptKey RFC 6455 1.3 的示例
+func TestWSAcceptKey(t *testing.T) {
+	if got := wsAcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("accept key = %s", got)
+	}
+}
+
+func TestWSHandshake(t *testing.T) {
+	cfg := &conf.Config{WSPath: "/ws"}
+	cfg.WithDefault()
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	type result struct {
+		req *http.Request
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		_, req, err := WSAccept(a, cfg)
+		done <- result{req, err}
+	}()
+
+	u, _ := ParseWSURL("ws", "example.com/ws?token=1")
+	if _, err := WSHandshake(b, cfg, u); err != nil {
+		t.Fatalf("client handshake: %v", err)
+	}
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("server handshake: %v", r.err)
+	}
+	if r.req.URL.Path != "/ws" || r.req.URL.Query().Get("token") != "1" || r.req.Host != "example.com:80" {
+		t.Fatalf("request = %s %s", r.req.Host, r.req.URL)
+	}
+}
+
+func TestWSHandshakeInvalidAccept(t *testing.T) {
+	cfg := &conf.Config{}
+	cfg.WithDefault()
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	go func() {
+		_, _ = http.ReadRequest(bufio.NewReader(a))
+		_, _ = io.WriteString(a, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n\r\n")
+	}()
+	u, _ := ParseWSURL("ws", "example.com")
+	if _, err := WSHandshake(b, cfg, u); err == nil || !strings.Contains(err.Error(), "Sec-WebSocket-Accept") {
+		t.Fatalf("handshake error = %v, want invalid Sec-WebSocket-Accept", err)
+	}
+}
+
+func TestWSAcceptRejects(t *testing.T) {
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	request := func(method, path string, header ...string) string {
+		h := map[string]string{"Upgrade": "websocket", "Connection": "Upgrade", "Sec-WebSocket-Version": "13", "Sec-WebSocket-Key": key}
+		for i := 0; i+1 < len(header); i += 2 {
+			h[header[i]] = header[i+1]
+		}
+		s := method + " " + path + " HTTP/1.1\r\nHost: example.com\r\n"
+		for k, v := range h {
+			if v != "" {
+				s += k + ": " + v + "\r\n"
+			}
+		}
+		return s + "\r\n"
+	}
+	tests := []struct {
+		name   string
+		req    string
+		status int
+	}{
+		{"method", request("POST", "/ws"), http.StatusMethodNotAllowed},
+		{"missing upgrade", request("GET", "/ws", "Upgrade", ""), http.StatusBadRequest},
+		{"missing connection", request("GET", "/ws", "Connection", "keep-alive"), http.StatusBadRequest},
+		{"version", request("GET", "/ws", "Sec-WebSocket-Version", "8"), http.StatusUpgradeRequired},
+		{"missing key", request("GET", "/ws", "Sec-WebSocket-Key", ""), http.StatusBadRequest},
+		{"short key", request("GET", "/ws", "Sec-WebSocket-Key", "c2hvcnQ="), http.StatusBadRequest},
+		{"path", request("GET", "/other"), http.StatusNotFound},
+		{"origin", request("GET", "/ws", "Origin", "http://evil.example"), http.StatusForbidden},
+		{"accepted", request("GET", "/ws", "Connection", "keep-alive, Upgrade"), http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &conf.Config{WSPath: "/ws", WSCheckOrigin: func(r *http.Request) bool {
+				return r.Header.Get("Origin") != "http://evil.example"
+			}}
+			cfg.WithDefault()
+			a, b := net.Pipe()
+			defer a.Close()
+			defer b.Close()
+
+			errc := make(chan error, 1)
+			go func() {
+				_, _, err := WSAccept(a, cfg)
+				errc <- err
+			}()
+			go func() { _, _ = io.WriteString(b, tt.req) }()
+
+			_ = b.SetReadDeadline(time.Now().Add(5 * time.Second))
+			resp, err := http.ReadResponse(bufio.NewReader(b), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if err := <-errc; (err == nil) != (tt.status == http.StatusSwitchingProtocols) {
+				t.Fatalf("WSAccept error = %v", err)
+			}
+			if tt.status == http.StatusSwitchingProtocols && resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Fatalf("Sec-WebSocket-Accept = %q", resp.Header.Get("Sec-WebSocket-Accept"))
+			}
+		})
+	}
+}
+
+func TestWSMessages(t *testing.T) {
+	c, raw, peer, h := newWSTestConn(t, 0)
+
+	go func() { _, _ = raw.Write(clientFrame(wsOpText, "hello")) }()
+	expectMsg(t, h, "hello")
+
+	// 分片消息，中间穿插控制帧
+	go func() {
+		_, _ = raw.Write(wsFrame(false, wsOpBinary, []byte("hel"), true))
+		_, _ = raw.Write(clientFrame(wsOpPing, "p"))
+		_, _ = raw.Write(wsFrame(false, wsOpContinuation, []byte("lo "), true))
+		_, _ = raw.Write(wsFrame(true, wsOpContinuation, []byte("world"), true))
+	}()
+	if op, payload := readServerFrame(t, peer); op != wsOpPong || string(payload) != "p" {
+		t.Fatalf("reply to ping = op %d %q, want pong \"p\"", op, payload)
+	}
+	expectMsg(t, h, "hello world")
+
+	// 空的分片与空消息
+	go func() {
+		_, _ = raw.Write(wsFrame(false, wsOpText, nil, true))
+		_, _ = raw.Write(wsFrame(true, wsOpContinuation, nil, true))
+	}()
+	expectMsg(t, h, "")
+
+	// 未请求的 pong 只刷新活跃时间
+	before := c.last.Load()
+	time.Sleep(2 * time.Millisecond)
+	go func() { _, _ = raw.Write(clientFrame(wsOpPong, "")) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.last.Load() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("pong did not touch the conn")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 服务端发出不掩码的二进制帧
+	c.Send([]byte("out"))
+	if op, payload := readServerFrame(t, peer); op != wsOpBinary || string(payload) != "out" {
+		t.Fatalf("sent frame = op %d %q", op, payload)
+	}
+}
+
+func TestWSProtocolErrors(t *testing.T) {
+	const max = 16
+	tests := []struct {
+		name string
+		in   []byte
+		code uint16
+	}{
+		{"unmasked client frame", wsFrame(true, wsOpText, []byte("hi"), false), wsCloseProtocolError},
+		{"reserved bits", func() []byte { b := clientFrame(wsOpText, "hi"); b[0] |= 0x40; return b }(), wsCloseProtocolError},
+		{"unknown opcode", clientFrame(0x3, "hi"), wsCloseProtocolError},
+		{"unexpected continuation", clientFrame(wsOpContinuation, "hi"), wsCloseProtocolError},
+		{"expected continuation", append(wsFrame(false, wsOpText, []byte("a"), true), clientFrame(wsOpText, "b")...), wsCloseProtocolError},
+		{"fragmented control frame", wsFrame(false, wsOpPing, nil, true), wsCloseProtocolError},
+		{"oversize control frame", wsFrame(true, wsOpPing, make([]byte, 126), true), wsCloseProtocolError},
+		{"oversize frame", clientFrame(wsOpBinary, strings.Repeat("x", max+1)), wsCloseMessageTooLarge},
+		{"oversize fragmented message", append(wsFrame(false, wsOpBinary, make([]byte, max), true),
+			wsFrame(true, wsOpContinuation, []byte("x"), true)...), wsCloseMessageTooLarge},
+		{"invalid utf-8 text", clientFrame(wsOpText, "\xff\xfe"), wsCloseInvalidPayload},
+		{"invalid utf-8 across fragments", append(wsFrame(false, wsOpText, []byte("\xe4\xbd"), true),
+			wsFrame(true, wsOpContinuation, []byte("x"), true)...), wsCloseInvalidPayload},
+		{"one byte close payload", clientFrame(wsOpClose, "\x03"), wsCloseProtocolError},
+		{"close code below 1000", clientFrame(wsOpClose, closePayload(999, "")), wsCloseProtocolError},
+		{"reserved close code 1004", clientFrame(wsOpClose, closePayload(1004, "")), wsCloseProtocolError},
+		{"close code 1005", clientFrame(wsOpClose, closePayload(wsCloseNoStatus, "")), wsCloseProtocolError},
+		{"close code 1006", clientFrame(wsOpClose, closePayload(1006, "")), wsCloseProtocolError},
+		{"close code 1015", clientFrame(wsOpClose, closePayload(1015, "")), wsCloseProtocolError},
+		{"unassigned close code", clientFrame(wsOpClose, closePayload(2000, "")), wsCloseProtocolError},
+		{"close code above 4999", clientFrame(wsOpClose, closePayload(5000, "")), wsCloseProtocolError},
+		{"invalid utf-8 close reason", clientFrame(wsOpClose, closePayload(wsCloseNormal, "\xff")), wsCloseInvalidPayload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, raw, peer, h := newWSTestConn(t, max)
+			go func() { _, _ = raw.Write(tt.in) }()
+			expectClose(t, c, peer, tt.code)
+
+			var we *wsError
+			if err := context.Cause(c.Ctx); !errors.As(err, &we) || we.code != tt.code {
+				t.Fatalf("close cause = %v, want code %d", err, tt.code)
+			}
+			select {
+			case <-h.errs:
+			case <-time.After(5 * time.Second):
+				t.Fatal("OnError not called")
+			}
+			select {
+			case msg := <-h.msgs:
+				t.Fatalf("received %q from an invalid frame", msg)
+			default:
+			}
+		})
+	}
+}
+
+func TestWSClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		code    uint16
+	}{
+		{"no status", "", wsCloseNormal},
+		{"normal", closePayload(wsCloseNormal, "bye"), wsCloseNormal},
+		{"going away", closePayload(wsCloseGoingAway, ""), wsCloseGoingAway},
+		{"internal error", closePayload(1011, ""), 1011},
+		{"library code", closePayload(3000, ""), 3000},
+		{"application code", closePayload(4999, "再见"), 4999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, raw, peer, h := newWSTestConn(t, 0)
+			go func() { _, _ = raw.Write(clientFrame(wsOpClose, tt.payload)) }()
+			expectClose(t, c, peer, tt.code)
+
+			if err := context.Cause(c.Ctx); err != io.EOF {
+				t.Fatalf("close cause = %v, want io.EOF", err)
+			}
+			select {
+			case err := <-h.errs:
+				t.Fatalf("OnError(%v) on a valid close", err)
+			default:
+			}
+		})
+	}
+}
+
+// TestWSLocalClose 本端关闭时发送 1001 关闭帧
+func TestWSLocalClose(t *testing.T) {
+	c, _, peer, _ := newWSTestConn(t, 0)
+	go c.Close()
+	expectClose(t, c, peer, wsCloseGoingAway)
+	if _, err := c.T.(*WSTransport).raw.Write([]byte{0}); err == nil {
+		t.Fatal("transport still open after close")
+	}
+}
+
+func TestParseWSURL(t *testing.T) {
+	tests := []struct {
+		network, addr, want string
+	}{
+		{"ws", "example.com", "ws://example.com:80/"},
+		{"wss", "example.com", "wss://example.com:443/"},
+		{"ws", "127.0.0.1:8080/chat?room=1", "ws://127.0.0.1:8080/chat?room=1"},
+		{"ws", "wss://example.com/x", "wss://example.com:443/x"},
+	}
+	for _, tt := range tests {
+		u, err := ParseWSURL(tt.network, tt.addr)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.network, tt.addr, err)
+		}
+		if u.String() != tt.want {
+			t.Errorf("%s %s = %s, want %s", tt.network, tt.addr, u, tt.want)
+		}
+	}
+	if _, err := ParseWSURL("ws", "http://example.com"); err == nil {
+		t.Fatal("http scheme accepted")
+	}
+}
@@ -2,15 +2,67 @@ package boot
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"github.com/yurazsb/uno/pkg/attrs"
+	"github.com/yurazsb/uno/pkg/state"
 	"net"
+	"time"
 )
 
 type Server interface {
 	Addr() net.Addr
 	Context() context.Context
 	IsRunning() bool
+	Conns() ConnManager
 	Stop()
+	// Shutdown 优雅关闭：停止接受新连接，等待各连接处理完已收到的消息并发出发送队列中的消息后关闭；
+	// ctx 结束时强制关闭剩余连接并返回 ctx.Err()
+	Shutdown(ctx context.Context) (ShutdownReport, error)
+	// Wait 阻塞直到服务循环退出（Stop、Shutdown、ctx 取消或出错），返回其退出错误
+	Wait() error
+	// Err 服务循环的退出错误，运行中或正常停止时为 nil
+	Err() error
+}
+
+// ShutdownReport 优雅关闭结果
+type ShutdownReport struct {
+	Drained int // 正常排空后关闭的连接数
+	Killed  int // 截止时间到达时被强制关闭的连接数
+}
+
+// ConnManager 服务端连接管理器，连接启动时登记、关闭时移除
+type ConnManager interface {
+	Get(id string) (Conn, bool)            // 按连接 ID 查找
+	Count() int                            // 当前连接数
+	Range(fn func(c Conn) bool)            // 遍历连接，fn 返回 false 时停止
+	CloseIf(pred func(c Conn) bool) int    // 关闭满足条件的连接，返回关闭数量
+	Bind(uid string, c Conn) (kicked Conn) // 绑定用户，该用户已绑定的其他连接被踢下线并返回
+	Unbind(c Conn)                         // 解除连接的用户绑定
+	User(uid string) (Conn, bool)          // 按用户查找连接
+	UserOf(c Conn) (string, bool)          // 获取连接绑定的用户
+	Group(name string) Group               // 获取广播组，不存在时创建
+	RemoveGroup(name string)               // 移除广播组（成员连接不受影响）
+}
+
+// Group 广播组（房间），成员连接关闭时自动退出
+type Group interface {
+	Name() string
+	Join(c Conn) bool           // 加入，连接已关闭或已是成员时返回 false
+	Leave(c Conn)               // 退出
+	Has(c Conn) bool            // 是否为成员
+	Count() int                 // 成员数
+	Range(fn func(c Conn) bool) // 遍历成员，fn 返回 false 时停止
+	// Broadcast 向满足所有 filter 的成员发送消息：相同配置的连接只编码一次，
+	// 编码结果直接进入各成员的发送队列；返回入队结果，写出结果经各连接的 OnWrite 回调通知
+	Broadcast(ctx context.Context, msg any, filter ...func(c Conn) bool) BroadcastResult
+}
+
+// BroadcastResult 广播结果
+type BroadcastResult struct {
+	Sent    int            // 成功入队（非会话层成员为发送完成）的成员数
+	Failed  map[Conn]error // 编码、入队或发送失败的成员及原因
+	Pending int            // ctx 结束时仍未完成发送的非会话层成员数（如多路复用的流）
 }
 
 type Client interface {
@@ -28,6 +80,52 @@ type Conn interface {
 	Close()
 }
 
+// RPCConn 由连接实现，可通过类型断言限定发送的等待时间或发起请求
+type RPCConn interface {
+	SendContext(ctx context.Context, msg any) <-chan error    // 发送消息，队列已满且策略为 QueueBlock 时最多阻塞至 ctx 结束
+	Call(ctx context.Context, msg any) (reply any, err error) // 发起请求并等待应答，需启用 RPC
+}
+
+// TLSConn 由启用 TLS 的连接实现，可通过类型断言获取握手信息
+type TLSConn interface {
+	ConnectionState() (tls.ConnectionState, bool) // TLS 连接状态，非 TLS 连接返回 false
+	PeerCertificates() []*x509.Certificate        // 对端证书链
+	NegotiatedProtocol() string                   // ALPN 协商结果
+}
+
+// HeartbeatConn 由连接实现，可通过类型断言获取心跳测得的往返时延
+type HeartbeatConn interface {
+	RTT() time.Duration // 最近一次 ping / pong 的往返时延，未启用心跳或尚未测得时为 0
+}
+
+// StateConn 由连接实现，可通过类型断言获取生命周期状态机
+type StateConn interface {
+	State() state.State      // 当前状态：生命周期状态或应用注册的自定义状态
+	Machine() *state.Machine // 连接的状态机，可注册自定义状态、迁移与钩子
+}
+
+// LayerIO 处理层与相邻层之间的数据通道
+type LayerIO struct {
+	Up   func(buf []byte)       // 向上层投递数据
+	Down func(buf []byte) error // 向下层写出数据
+	Fail func(err error)        // 报告不可恢复错误，触发错误回调并关闭连接
+
+	// Queue 经连接的发送队列向下层写出本层自行发起的数据（如多路复用的流帧），与上层消息共享队列容量与水位。
+	// 入队失败时同步返回错误（done 未被使用），否则写出结果送入 done 后关闭，done 为 nil 时丢弃结果。
+	// 不适用 SendQueuePolicy：队列已满时等待空位至 ctx 结束，已入队的数据也不会被丢弃。
+	Queue func(ctx context.Context, buf []byte, done chan error) error
+}
+
+// Layer 位于传输层与会话层之间的可插拔处理层（如可靠传输、分片重组）。
+// 每个连接持有独立实例：接收数据自下而上经过 Read，发送数据自上而下经过 Write。
+type Layer interface {
+	Name() string
+	Open(c Conn, io LayerIO) // 连接启动时调用
+	Read(chunk []byte) error // 处理来自下层的数据
+	Write(buf []byte) error  // 处理来自上层的数据
+	Close()                  // 连接关闭时调用
+}
+
 type Attrs = attrs.Attrs[any, any]
 
 type Pool interface {
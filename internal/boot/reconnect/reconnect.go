@@ -0,0 +1,488 @@
+package reconnect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/lifecycle"
+	"github.com/yurazsb/uno/internal/upgrade"
+	"github.com/yurazsb/uno/pkg/attrs"
+	"github.com/yurazsb/uno/pkg/state"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDisconnected = errors.New("reconnect: not connected")
+	ErrBufferFull   = errors.New("reconnect: send buffer full")
+	ErrGiveUp       = errors.New("reconnect: max attempts exceeded")
+)
+
+// Options 自动重连参数
+type Options struct {
+	// InitialDelay 首次重连前的等待时间。
+	// 如果为 0，默认 500 毫秒。
+	InitialDelay time.Duration
+
+	// MaxDelay 重连等待时间上限。
+	// 如果为 0，默认 30 秒。
+	MaxDelay time.Duration
+
+	// Multiplier 每次失败后等待时间的增长倍数。
+	// 如果小于 1，默认 2。
+	Multiplier float64
+
+	// Jitter 等待时间的随机抖动比例（0~1），例如 0.2 表示 ±20%。
+	// 如果为 0，不抖动。
+	Jitter float64
+
+	// MaxAttempts 单次断线后的最大重连次数，超出后放弃并关闭连接。
+	// 如果为 0，无限重连。
+	MaxAttempts int
+
+	// BufferSize 断线期间最多缓冲的待发送消息数，重连成功后按序发出。
+	// 如果为 0，断线期间 Send 立即返回 ErrDisconnected。
+	BufferSize int
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.InitialDelay <= 0 {
+		o.InitialDelay = 500 * time.Millisecond
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 30 * time.Second
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = 2
+	}
+	if o.Jitter < 0 {
+		o.Jitter = 0
+	}
+	if o.Jitter > 1 {
+		o.Jitter = 1
+	}
+}
+
+// Dialer 建立一条底层连接，ctx 为连接的父上下文
+type Dialer func(ctx context.Context, hook hook.ConnHook) (boot.Conn, error)
+
+// Client 自动重连客户端
+type Client struct {
+	ctx  context.Context
+	opts Options
+	hook hook.ConnHook
+	dial Dialer
+}
+
+func NewClient(ctx context.Context, opts Options, hook hook.ConnHook, dial Dialer) *Client {
+	opts.WithDefault()
+	return &Client{
+		ctx:  ctx,
+		opts: opts,
+		hook: hook,
+		dial: dial,
+	}
+}
+
+// Dial 建立首个连接并返回稳定的连接门面，首次拨号失败直接返回错误
+func (cl *Client) Dial() (boot.Conn, error) {
+	c := &Conn{
+		opts:       cl.opts,
+		hook:       cl.hook,
+		dial:       cl.dial,
+		attributes: attrs.New[any, any](true),
+		done:       make(chan struct{}),
+	}
+	c.ctx, c.cancel = context.WithCancel(cl.ctx)
+	c.proxy = &proxy{c: c}
+
+	cur, err := c.dial(c.ctx, c.proxy)
+	if err != nil {
+		c.cancel()
+		return nil, err
+	}
+	c.id = cur.ID()
+	c.current = cur
+
+	go c.run(cur)
+	return c, nil
+}
+
+// pending 断线期间缓冲的消息
+type pending struct {
+	msg  any
+	done chan error
+}
+
+// Conn 自动重连连接门面，底层连接断开后按退避策略重新拨号。
+// ID、Context、Attrs 在整个生命周期内保持不变，回调中的连接参数均为门面本身。
+// TLSConn、HeartbeatConn、StateConn、upgrade.Conn 的方法作用于当前底层连接，重连后以新连接为准。
+type Conn struct {
+	id         string
+	attributes boot.Attrs
+
+	opts  Options
+	hook  hook.ConnHook
+	proxy *proxy
+	dial  Dialer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	current boot.Conn // 当前底层连接，断线期间为 nil
+	buffer  []pending
+
+	closeOnce sync.Once
+}
+
+func (c *Conn) ID() string               { return c.id }
+func (c *Conn) Context() context.Context { return c.ctx }
+func (c *Conn) Attrs() boot.Attrs        { return c.attributes }
+
+func (c *Conn) LocalAddr() net.Addr {
+	if cur := c.Current(); cur != nil {
+		return cur.LocalAddr()
+	}
+	return nil
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	if cur := c.Current(); cur != nil {
+		return cur.RemoteAddr()
+	}
+	return nil
+}
+
+// IsActive 当前是否有可用的底层连接
+func (c *Conn) IsActive() bool {
+	cur := c.Current()
+	return cur != nil && cur.IsActive()
+}
+
+// Current 返回当前底层连接，断线期间返回 nil
+func (c *Conn) Current() boot.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current
+}
+
+// Layer 按名称查找当前底层连接的处理层
+func (c *Conn) Layer(name string) (boot.Layer, bool) {
+	lc, ok := c.Current().(interface {
+		Layer(name string) (boot.Layer, bool)
+	})
+	if !ok {
+		return nil, false
+	}
+	return lc.Layer(name)
+}
+
+// ConnectionState 当前底层连接的 TLS 状态，断线期间返回 false
+func (c *Conn) ConnectionState() (tls.ConnectionState, bool) {
+	if tc, ok := c.Current().(boot.TLSConn); ok {
+		return tc.ConnectionState()
+	}
+	return tls.ConnectionState{}, false
+}
+
+// PeerCertificates 当前底层连接的对端证书链，断线期间返回 nil
+func (c *Conn) PeerCertificates() []*x509.Certificate {
+	if tc, ok := c.Current().(boot.TLSConn); ok {
+		return tc.PeerCertificates()
+	}
+	return nil
+}
+
+// NegotiatedProtocol 当前底层连接的 ALPN 协商结果，断线期间返回空字符串
+func (c *Conn) NegotiatedProtocol() string {
+	if tc, ok := c.Current().(boot.TLSConn); ok {
+		return tc.NegotiatedProtocol()
+	}
+	return ""
+}
+
+// RTT 当前底层连接的心跳往返时延，断线期间为 0
+func (c *Conn) RTT() time.Duration {
+	if hc, ok := c.Current().(boot.HeartbeatConn); ok {
+		return hc.RTT()
+	}
+	return 0
+}
+
+// State 当前底层连接的状态；断线重连期间为 Connecting，门面关闭后为 Closed
+func (c *Conn) State() state.State {
+	if sc, ok := c.Current().(boot.StateConn); ok {
+		return sc.State()
+	}
+	if c.ctx.Err() != nil {
+		return lifecycle.Closed
+	}
+	return lifecycle.Connecting
+}
+
+// Machine 当前底层连接的状态机，断线期间返回 nil；每条底层连接持有独立的状态机
+func (c *Conn) Machine() *state.Machine {
+	if sc, ok := c.Current().(boot.StateConn); ok {
+		return sc.Machine()
+	}
+	return nil
+}
+
+// Upgrade 升级当前底层连接，断线期间返回 ErrDisconnected；重连建立的新连接不继承升级
+func (c *Conn) Upgrade(ctx context.Context, opts upgrade.Options) error {
+	cur := c.Current()
+	if cur == nil {
+		if c.ctx.Err() != nil {
+			return net.ErrClosed
+		}
+		return ErrDisconnected
+	}
+	uc, ok := cur.(upgrade.Conn)
+	if !ok {
+		return upgrade.ErrUnsupported
+	}
+	return uc.Upgrade(ctx, opts)
+}
+
+func (c *Conn) Send(msg any) <-chan error {
+	return c.SendContext(c.ctx, msg)
+}
+
+// SendContext 已连接时直接发送；断线期间按 BufferSize 缓冲或立即失败
+func (c *Conn) SendContext(ctx context.Context, msg any) <-chan error {
+	c.mu.Lock()
+	cur := c.current
+	if cur != nil {
+		c.mu.Unlock()
+		if rc, ok := cur.(boot.RPCConn); ok {
+			return rc.SendContext(ctx, msg)
+		}
+		return cur.Send(msg)
+	}
+	defer c.mu.Unlock()
+
+	done := make(chan error, 1)
+	switch {
+	case c.ctx.Err() != nil:
+		done <- net.ErrClosed
+		close(done)
+	case c.opts.BufferSize <= 0:
+		done <- ErrDisconnected
+		close(done)
+	case len(c.buffer) >= c.opts.BufferSize:
+		done <- ErrBufferFull
+		close(done)
+	default:
+		c.buffer = append(c.buffer, pending{msg: msg, done: done})
+	}
+	return done
+}
+
+// Call 经当前底层连接发起请求，断线期间返回 ErrDisconnected
+func (c *Conn) Call(ctx context.Context, msg any) (any, error) {
+	cur := c.Current()
+	if cur == nil {
+		if c.ctx.Err() != nil {
+			return nil, net.ErrClosed
+		}
+		return nil, ErrDisconnected
+	}
+	rc, ok := cur.(boot.RPCConn)
+	if !ok {
+		return nil, fmt.Errorf("reconnect: %T does not support Call", cur)
+	}
+	return rc.Call(ctx, msg)
+}
+
+// Close 停止重连并关闭底层连接
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		<-c.done
+	})
+}
+
+// run 监视底层连接，断开后重连，直到门面关闭或放弃重连
+func (c *Conn) run(cur boot.Conn) {
+	defer close(c.done)
+	defer c.failBuffer()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			cur.Close()
+			return
+		case <-cur.Context().Done():
+		}
+
+		c.mu.Lock()
+		c.current = nil
+		c.mu.Unlock()
+
+		cur = c.redial(context.Cause(cur.Context()))
+		if cur == nil {
+			c.cancel()
+			return
+		}
+	}
+}
+
+// redial 按退避策略重新拨号，成功后回调 OnReconnected 并发出缓冲消息
+func (c *Conn) redial(cause error) boot.Conn {
+	rh, _ := c.hook.(hook.ReconnectHook)
+
+	delay := c.opts.InitialDelay
+	for attempt := 1; c.opts.MaxAttempts <= 0 || attempt <= c.opts.MaxAttempts; attempt++ {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(c.jitter(delay)):
+		}
+		delay = min(time.Duration(float64(delay)*c.opts.Multiplier), c.opts.MaxDelay)
+
+		if rh != nil {
+			c.safe(func() { rh.OnReconnecting(c, attempt, cause) })
+		}
+
+		nc, err := c.dial(c.ctx, c.proxy)
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		if rh != nil {
+			c.safe(func() { rh.OnReconnected(nc, attempt) })
+		}
+		c.flush(nc)
+		return nc
+	}
+
+	c.safe(func() { c.hook.OnError(c, fmt.Errorf("%w: %w", ErrGiveUp, cause)) })
+	return nil
+}
+
+// flush 切换到新连接并按序发出缓冲消息
+func (c *Conn) flush(nc boot.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	buffer := c.buffer
+	c.buffer = nil
+	c.current = nc
+
+	if len(buffer) == 0 {
+		return
+	}
+
+	results := make([]<-chan error, len(buffer))
+	for i, p := range buffer {
+		results[i] = nc.Send(p.msg)
+	}
+	go func() {
+		for i, p := range buffer {
+			p.done <- <-results[i]
+			close(p.done)
+		}
+	}()
+}
+
+// failBuffer 门面关闭时通知所有缓冲消息
+func (c *Conn) failBuffer() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range c.buffer {
+		p.done <- net.ErrClosed
+		close(p.done)
+	}
+	c.buffer = nil
+	c.current = nil
+}
+
+func (c *Conn) jitter(d time.Duration) time.Duration {
+	if c.opts.Jitter == 0 {
+		return d
+	}
+	f := 1 + c.opts.Jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(d) * f)
+}
+
+// safe 执行用户回调，捕获 panic 并通过 OnError 报告
+func (c *Conn) safe(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.hook.OnError(c, fmt.Errorf("reconnect hook panic: %v", r))
+		}
+	}()
+	fn()
+}
+
+// proxy 将底层连接的回调转发给用户，连接参数替换为门面
+type proxy struct {
+	c *Conn
+}
+
+// of 返回回调中的连接参数；多路复用的流不属于门面，原样传递
+func (p *proxy) of(c boot.Conn) boot.Conn {
+	if _, ok := c.(interface{ Parent() boot.Conn }); ok {
+		return c
+	}
+	return p.c
+}
+
+func (p *proxy) OnConnect(c boot.Conn)                 { p.c.hook.OnConnect(p.of(c)) }
+func (p *proxy) OnClose(c boot.Conn)                   { p.c.hook.OnClose(p.of(c)) }
+func (p *proxy) OnError(c boot.Conn, err error)        { p.c.hook.OnError(p.of(c), err) }
+func (p *proxy) OnTick(c boot.Conn)                    { p.c.hook.OnTick(p.of(c)) }
+func (p *proxy) OnIdle(c boot.Conn)                    { p.c.hook.OnIdle(p.of(c)) }
+func (p *proxy) OnSend(c boot.Conn, msg any)           { p.c.hook.OnSend(p.of(c), msg) }
+func (p *proxy) OnMessage(c boot.Conn, msg any)        { p.c.hook.OnMessage(p.of(c), msg) }
+func (p *proxy) OnRead(c boot.Conn, b []byte, e error) { p.c.hook.OnRead(p.of(c), b, e) }
+func (p *proxy) OnWrite(c boot.Conn, b []byte, e error) {
+	p.c.hook.OnWrite(p.of(c), b, e)
+}
+
+func (p *proxy) OnReady(c boot.Conn) {
+	if h, ok := p.c.hook.(hook.ReadyHook); ok {
+		h.OnReady(p.of(c))
+	}
+}
+
+func (p *proxy) OnKick(c boot.Conn, uid string) {
+	if h, ok := p.c.hook.(hook.KickHook); ok {
+		h.OnKick(p.of(c), uid)
+	}
+}
+
+func (p *proxy) OnShutdown(c boot.Conn) {
+	if h, ok := p.c.hook.(hook.ShutdownHook); ok {
+		h.OnShutdown(p.of(c))
+	}
+}
+
+func (p *proxy) OnBackpressure(c boot.Conn, msg any) {
+	if h, ok := p.c.hook.(hook.BackpressureHook); ok {
+		h.OnBackpressure(p.of(c), msg)
+	}
+}
+
+func (p *proxy) OnHighWatermark(c boot.Conn) {
+	if h, ok := p.c.hook.(hook.BackpressureHook); ok {
+		h.OnHighWatermark(p.of(c))
+	}
+}
+
+func (p *proxy) OnLowWatermark(c boot.Conn) {
+	if h, ok := p.c.hook.(hook.BackpressureHook); ok {
+		h.OnLowWatermark(p.of(c))
+	}
+}
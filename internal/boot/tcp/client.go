@@ -2,6 +2,7 @@ package tcp
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/yurazsb/uno/internal/boot"
 	"github.com/yurazsb/uno/internal/boot/conn"
@@ -12,6 +13,7 @@ import (
 )
 
 type Client struct {
+	raw     net.Conn // 外部传入的已建立连接，不再自行拨号
 	address string
 	ctx     context.Context
 	cfg     *conf.Config
@@ -31,13 +33,123 @@ func NewClient(ctx context.Context, cfg conf.Config, hook hook.ConnHook, addr st
 	}
 }
 
+// NewConnClient 基于已建立的连接创建客户端，Dial 在其上完成 TLS / WebSocket 握手（如已配置）后返回会话；
+// 连接只能使用一次
+func NewConnClient(ctx context.Context, cfg conf.Config, hook hook.ConnHook, raw net.Conn) *Client {
+	c := NewClient(ctx, cfg, hook, raw.RemoteAddr().String())
+	c.raw = raw
+	return c
+}
+
 func (c Client) Dial() (boot.Conn, error) {
-	network := c.cfg.Network
+	if conn.IsWSNetwork(c.cfg.Network) {
+		return c.dialWS()
+	}
+
+	raw, err := c.dial(c.cfg.Network, c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debug("Dial conn: " + raw.RemoteAddr().String())
+
+	var nc *conn.Conn
+	if c.cfg.TLSConfig != nil {
+		tc, err := conn.TLSClient(c.ctx, raw, c.cfg, c.address)
+		if err != nil {
+			return nil, err
+		}
+		nc = conn.NewNETConn(c.ctx, tc, c.cfg, c.hook)
+	} else {
+		nc = conn.NewNETConn(c.ctx, raw, c.cfg, c.hook)
+	}
+	nc.Client = true
+	if c.raw == nil {
+		nc.DialAddr = c.address // 外部传入的连接无拨号地址
+	}
+	nc.Start(c.wg)
+	if err = nc.Handshake(); err != nil { // 配置 Handshaker 时等待握手成功
+		nc.Close()
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// dialWS 建立 WebSocket 连接，地址形如 "ws://host:port/path" 或 "host:port"
+func (c Client) dialWS() (boot.Conn, error) {
+	u, err := conn.ParseWSURL(c.cfg.Network, c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := c.dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debug("Dial conn: " + u.String())
+
+	var nr net.Conn = raw
+	if u.Scheme == "wss" || c.cfg.TLSConfig != nil {
+		cfg := *c.cfg
+		if cfg.TLSConfig == nil {
+			cfg.TLSConfig = &tls.Config{}
+		}
+		tc, err := conn.TLSClient(c.ctx, raw, &cfg, u.Host)
+		if err != nil {
+			return nil, err
+		}
+		nr = tc
+	}
+
+	br, err := conn.WSHandshake(nr, c.cfg, u)
+	if err != nil {
+		_ = nr.Close()
+		return nil, err
+	}
+
+	nc := conn.NewWSConn(c.ctx, nr, br, c.cfg, c.hook, true)
+	nc.Start(c.wg)
+	if err = nc.Handshake(); err != nil { // 配置 Handshaker 时等待握手成功
+		nc.Close()
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// dial 按网络类型建立底层连接
+func (c Client) dial(network, address string) (net.Conn, error) {
+	if c.raw != nil {
+		return c.raw, nil
+	}
+
+	if conn.IsUnixNetwork(network) {
+		rAddr, err := net.ResolveUnixAddr(network, address)
+		if err != nil {
+			return nil, fmt.Errorf("resolve unix addr failed: %w", err)
+		}
+
+		var lAddr *net.UnixAddr
+		if c.cfg.LocalAddr != "" {
+			lAddr, err = net.ResolveUnixAddr(network, c.cfg.LocalAddr)
+			if err != nil {
+				return nil, fmt.Errorf("resolve local unix addr failed: %w", err)
+			}
+		}
+
+		raw, err := net.DialUnix(network, lAddr, rAddr)
+		if err != nil {
+			return nil, fmt.Errorf("dial unix failed: %w", err)
+		}
+		return raw, nil
+	}
 
 	// 解析远程地址
-	rAddr, err := net.ResolveTCPAddr(network, c.address)
+	rAddr, err := net.ResolveTCPAddr(network, address)
 	if err != nil {
-		return nil, fmt.Errorf("resolve udp addr failed: %w", err)
+		return nil, fmt.Errorf("resolve tcp addr failed: %w", err)
 	}
 
 	// 解析本地地址（可选）
@@ -45,7 +157,7 @@ func (c Client) Dial() (boot.Conn, error) {
 	if c.cfg.LocalAddr != "" {
 		lAddr, err = net.ResolveTCPAddr(network, c.cfg.LocalAddr)
 		if err != nil {
-			return nil, fmt.Errorf("resolve local udp addr failed: %w", err)
+			return nil, fmt.Errorf("resolve local tcp addr failed: %w", err)
 		}
 	}
 
@@ -53,11 +165,5 @@ func (c Client) Dial() (boot.Conn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("dial tcp failed: %w", err)
 	}
-
-	c.log.Debug("Dial conn: " + raw.RemoteAddr().String())
-
-	nc := conn.NewNETConn(c.ctx, raw, c.cfg, c.hook)
-	nc.Start(c.wg)
-
-	return nc, nil
+	return raw, nil
 }
@@ -6,8 +6,13 @@ import (
 	"github.com/yurazsb/uno/internal/boot"
 	"github.com/yurazsb/uno/internal/boot/conn"
 	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/handoff"
 	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/proxyproto"
+	"github.com/yurazsb/uno/internal/reactor"
 	"net"
+	"os"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,7 +21,15 @@ import (
 type Server struct {
 	address string
 	addr    net.Addr
-	ln      net.Listener
+
+	lm       sync.Mutex
+	lns      []net.Listener    // 启用 ReusePort 时有多个共享地址的监听器
+	provided net.Listener      // 外部传入的监听器，不再自行监听
+	hl       *handoff.Listener // 监听套接字交接的控制套接字
+
+	proxy *proxyproto.Options // PROXY 协议配置（已补齐默认值），未启用时为 nil
+
+	reactor *reactor.Reactor // 驱动连接读取的事件循环，未启用时为 nil
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -26,11 +39,16 @@ type Server struct {
 	log  boot.Logger
 
 	hook hook.ServerHook
+	reg  *conn.Registry
 
 	running atomic.Bool
 
 	started atomic.Bool
 
+	served atomic.Bool
+	done   chan struct{} // 服务循环退出后关闭
+	err    error         // 服务循环的退出错误
+
 	stopOnce sync.Once
 	stopped  chan struct{}
 
@@ -39,7 +57,7 @@ type Server struct {
 
 func NewServer(parent context.Context, cfg conf.Config, hook hook.ServerHook, addr string) *Server {
 	ctx, cancel := context.WithCancel(parent)
-	return &Server{
+	s := &Server{
 		address: addr,
 		ctx:     ctx,
 		cancel:  cancel,
@@ -47,9 +65,25 @@ func NewServer(parent context.Context, cfg conf.Config, hook hook.ServerHook, ad
 		pool:    cfg.Pool,
 		log:     cfg.Logger,
 		hook:    hook,
+		reg:     conn.NewRegistry(),
 		wg:      &sync.WaitGroup{},
 		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
 	}
+	if cfg.ProxyProtocol != nil {
+		opts := *cfg.ProxyProtocol
+		opts.WithDefault()
+		s.proxy = &opts
+	}
+	return s
+}
+
+// NewListenerServer 基于已有的监听器创建服务（如 systemd 套接字激活、测试桩或包装过的监听器），
+// 复用同一 accept 循环；服务停止时关闭该监听器
+func NewListenerServer(parent context.Context, cfg conf.Config, hook hook.ServerHook, ln net.Listener) *Server {
+	s := NewServer(parent, cfg, hook, ln.Addr().String())
+	s.provided = ln
+	return s
 }
 
 func (s *Server) Addr() net.Addr { return s.addr }
@@ -58,47 +92,279 @@ func (s *Server) Context() context.Context { return s.ctx }
 
 func (s *Server) IsRunning() bool { return s.running.Load() }
 
+func (s *Server) Conns() boot.ConnManager { return s.reg }
+
 func (s *Server) Stop() {
 	s.stopOnce.Do(func() {
 		s.cancel()
+		// 服务循环未运行（未 Bind 或 Bind 后未 Serve）时由 Stop 释放资源，否则等待服务循环退出
+		if s.served.CompareAndSwap(false, true) {
+			s.clear()
+			close(s.done)
+			return
+		}
 		<-s.stopped
 	})
 }
 
+// Shutdown 优雅关闭：关闭监听器，并发排空现有连接后停止服务
+func (s *Server) Shutdown(ctx context.Context) (boot.ShutdownReport, error) {
+	s.lm.Lock()
+	for _, ln := range s.lns {
+		_ = ln.Close()
+	}
+	s.lm.Unlock()
+
+	report := s.reg.Drain(ctx)
+	s.Stop()
+	if report.Killed > 0 {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+// Listen 监听并阻塞运行服务循环，直到 Stop() 调用或出错
 func (s *Server) Listen() error {
-	if s.started.Load() {
+	if err := s.Bind(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Bind 监听地址并触发 OnStart，返回后 Addr() 即可用；随后需调用 Serve 运行服务循环
+func (s *Server) Bind() error {
+	if !s.started.CompareAndSwap(false, true) {
 		return errors.New("already started")
 	}
 
-	s.running.Store(true)
-	ln, err := net.Listen(s.cfg.Network, s.address)
+	if s.cfg.Reactor > 0 {
+		r, err := reactor.New(s.cfg.Reactor, s.cfg.ReadBufferSize)
+		if err != nil {
+			return err
+		}
+		s.reactor = r
+	}
+
+	lns, err := s.listen()
 	if err != nil {
+		s.closeReactor()
 		return err
 	}
 
-	s.ln = ln
-	s.addr = ln.Addr()
-	s.log.Debug("listening on %s://%s", s.cfg.Network, s.addr.String())
+	s.running.Store(true)
+	s.lm.Lock()
+	s.lns = lns
+	s.lm.Unlock()
+	s.addr = lns[0].Addr()
+	s.log.Debug("listening on %s://%s (%d listeners)", s.cfg.Network, s.addr.String(), len(lns))
+
+	if err = s.listenHandoff(); err != nil {
+		s.lm.Lock()
+		for _, ln := range s.lns {
+			_ = ln.Close()
+		}
+		s.lns = nil
+		s.lm.Unlock()
+		s.running.Store(false)
+		s.closeReactor()
+		return err
+	}
 
 	task := func() { s.hook.OnStart(s) }
 	if !s.pool.Submit(task) {
 		s.log.Error("fail to submit task: %v", task)
 	}
+	return nil
+}
+
+// Serve 运行服务循环，阻塞直到 Stop() 调用或出错，需在 Bind 成功后调用
+func (s *Server) Serve() error {
+	if s.addr == nil {
+		return errors.New("not bound")
+	}
+	if !s.served.CompareAndSwap(false, true) {
+		if s.ctx.Err() != nil {
+			return net.ErrClosed
+		}
+		return errors.New("already serving")
+	}
+
+	s.err = s.serve()
+	close(s.done)
+	return s.err
+}
+
+// Wait 阻塞直到服务循环退出，返回其退出错误
+func (s *Server) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Err 服务循环的退出错误，运行中或正常停止时为 nil
+func (s *Server) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// listen 按网络类型创建监听器："tcp"、"tcp4"、"tcp6"、"unix"、"unixpacket"、"ws" 或 "wss"；
+// 启用 ReusePort 的 TCP / WebSocket 服务创建多个共享地址的监听器
+func (s *Server) listen() ([]net.Listener, error) {
+	if s.provided != nil {
+		return []net.Listener{s.provided}, nil
+	}
+
+	network := s.cfg.Network
+	if conn.IsWSNetwork(network) {
+		if network == "wss" && s.cfg.TLSConfig == nil {
+			return nil, errors.New("wss requires TLSConfig")
+		}
+		network = "tcp"
+	}
+	if !conn.IsUnixNetwork(network) && s.cfg.ReusePort > 1 {
+		if s.cfg.Handoff != nil {
+			return nil, errors.New("handoff does not support ReusePort")
+		}
+		if !conn.ReusePortSupported {
+			s.log.Warn("SO_REUSEPORT is not supported on %s, using a single listener", runtime.GOOS)
+		}
+		return conn.ListenReusePort(s.ctx, network, s.address, s.cfg.ReusePort)
+	}
+
+	ln, err := s.inherit()
+	if err != nil {
+		return nil, err
+	}
+	if ln != nil {
+		return []net.Listener{ln}, nil
+	}
+	if !conn.IsUnixNetwork(network) {
+		ln, err = net.Listen(network, s.address)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{ln}, nil
+	}
+
+	if err = conn.RemoveStaleSocket(s.address); err != nil {
+		return nil, err
+	}
+	ln, err = net.Listen(network, s.address)
+	if err != nil {
+		return nil, err
+	}
+	// 监听器关闭时会自动删除套接字文件
+	if err = conn.ChmodSocket(s.address, s.cfg.UnixSocketMode); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return []net.Listener{ln}, nil
+}
+
+// inherit 启用交接时向旧进程请求接管监听套接字，无旧进程时返回 nil
+func (s *Server) inherit() (net.Listener, error) {
+	if s.cfg.Handoff == nil {
+		return nil, nil
+	}
+	f, err := handoff.Request(s.cfg.Handoff.Socket, handoff.Key(s.cfg.Network, s.address))
+	if err != nil || f == nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	return s.serve()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("inherited listener %s://%s", s.cfg.Network, ln.Addr())
+	return ln, nil
+}
+
+// listenHandoff 监听控制套接字，等待新进程接管
+func (s *Server) listenHandoff() error {
+	if s.cfg.Handoff == nil {
+		return nil
+	}
+	hl, err := handoff.Listen(s.cfg.Handoff.Socket)
+	if err != nil {
+		return err
+	}
+	s.hl = hl
+	go s.handoff(hl)
+	return nil
+}
+
+// handoff 交出监听套接字后停止接受新连接，并在后台排空现有连接
+func (s *Server) handoff(hl *handoff.Listener) {
+	if err := hl.Accept(handoff.Key(s.cfg.Network, s.address), s.file); err != nil {
+		return
+	}
+	s.log.Info("listener %s://%s handed off, draining", s.cfg.Network, s.addr)
+
+	opts := *s.cfg.Handoff
+	opts.WithDefault()
+	ctx, cancel := context.WithTimeout(context.Background(), opts.DrainTimeout)
+	defer cancel()
+	report, err := s.Shutdown(ctx)
+	s.log.Info("drained %d, killed %d conns after handoff: %v", report.Drained, report.Killed, err)
+}
+
+// file 复制监听套接字的文件描述符
+func (s *Server) file() (*os.File, error) {
+	s.lm.Lock()
+	defer s.lm.Unlock()
+	if len(s.lns) == 0 {
+		return nil, net.ErrClosed
+	}
+	switch ln := s.lns[0].(type) {
+	case *net.TCPListener:
+		return ln.File()
+	case *net.UnixListener:
+		ln.SetUnlinkOnClose(false) // 套接字文件由新进程继续使用
+		return ln.File()
+	default:
+		return nil, net.ErrClosed
+	}
 }
 
 func (s *Server) serve() error {
 	defer s.clear()
 
-	listener, _ := s.ln.(*net.TCPListener)
+	// 每个监听器一个 accept 循环，任一循环出错时停止服务
+	errs := make(chan error, len(s.lns))
+	for _, ln := range s.lns {
+		go func() { errs <- s.accept(ln) }()
+	}
+	var first error
+	for range s.lns {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			s.cancel()
+		}
+	}
+	return first
+}
+
+// accept 监听器的 accept 循环，Stop() 调用或 Context 取消后返回 nil
+func (s *Server) accept(ln net.Listener) error {
+	listener, _ := ln.(interface{ SetDeadline(time.Time) error })
+	if listener == nil {
+		// 不支持超时的监听器（如外部包装）无法轮询退出，停止时直接关闭以唤醒 Accept
+		stop := context.AfterFunc(s.ctx, func() { _ = ln.Close() })
+		defer stop()
+	}
 	for {
 		select {
 		case <-s.ctx.Done():
 			return nil
 		default:
-			_ = listener.SetDeadline(time.Now().Add(conn.AcceptTimeout))
-			raw, err := s.ln.Accept()
+			if listener != nil {
+				_ = listener.SetDeadline(time.Now().Add(conn.AcceptTimeout))
+			}
+			raw, err := ln.Accept()
 			if err != nil {
 				// 处理 err（优先级顺序: 本端主动关闭 > 超时 / 临时 > 其他）
 				if errors.Is(err, net.ErrClosed) {
@@ -125,23 +391,90 @@ func (s *Server) serve() error {
 				return err
 			}
 
-			nc := conn.NewNETConn(s.ctx, raw, s.cfg, s.hook)
-			nc.Start(s.wg)
+			if s.proxy != nil || s.cfg.TLSConfig != nil || conn.IsWSNetwork(s.cfg.Network) {
+				s.wg.Add(1)
+				go s.upgrade(raw)
+				continue
+			}
+
+			s.start(s.newConn(raw))
+		}
+	}
+}
+
+// upgrade 解析 PROXY 协议头部、完成 TLS / WebSocket 握手后创建连接，避免慢握手阻塞 accept 循环
+func (s *Server) upgrade(raw net.Conn) {
+	defer s.wg.Done()
+
+	if s.proxy != nil {
+		pc, err := proxyproto.Server(raw, s.proxy)
+		if err != nil {
+			s.log.Warn("%s: %s", raw.RemoteAddr(), err)
+			return
 		}
+		raw = pc
+	}
+
+	if s.cfg.TLSConfig != nil {
+		tc, err := conn.TLSServer(s.ctx, raw, s.cfg)
+		if err != nil {
+			s.log.Warn("%s: %s", raw.RemoteAddr(), err)
+			return
+		}
+		raw = tc
+	}
+
+	if !conn.IsWSNetwork(s.cfg.Network) {
+		s.start(s.newConn(raw))
+		return
+	}
+
+	br, req, err := conn.WSAccept(raw, s.cfg)
+	if err != nil {
+		s.log.Warn("%s: %s", raw.RemoteAddr(), err)
+		_ = raw.Close()
+		return
+	}
+
+	nc := conn.NewWSConn(s.ctx, raw, br, s.cfg, s.hook, false)
+	nc.Attributes.Set(conn.AttrWSRequest, req)
+	s.start(nc)
+}
+
+// newConn 创建流式连接，启用事件循环时由其驱动读取（TLS / PROXY 包装的连接退化为读协程）
+func (s *Server) newConn(raw net.Conn) *conn.Conn {
+	if s.reactor != nil {
+		return conn.NewEpollConn(s.ctx, raw, s.cfg, s.hook, s.reactor)
 	}
+	return conn.NewNETConn(s.ctx, raw, s.cfg, s.hook)
+}
+
+// start 登记并启动连接；排空开始后到达的连接由登记表拒绝，避免遗漏在排空快照之外
+func (s *Server) start(nc *conn.Conn) {
+	nc.Registry = s.reg
+	nc.Start(s.wg)
 }
 
 func (s *Server) clear() {
-	if !s.running.Load() || s.ln == nil {
+	if !s.running.Load() || s.lns == nil {
+		close(s.stopped)
 		return
 	}
 
 	s.running.Store(false)
 
-	_ = s.ln.Close()
-	s.ln = nil
+	s.lm.Lock()
+	for _, ln := range s.lns {
+		_ = ln.Close()
+	}
+	s.lns = nil
+	s.lm.Unlock()
+	if s.hl != nil {
+		_ = s.hl.Close()
+	}
 
 	s.wg.Wait()
+	s.closeReactor() // 连接均已关闭
 
 	task := func() { s.hook.OnStop(s) }
 	if !s.pool.Submit(task) {
@@ -150,3 +483,9 @@ func (s *Server) clear() {
 
 	close(s.stopped)
 }
+
+func (s *Server) closeReactor() {
+	if s.reactor != nil {
+		s.reactor.Close()
+	}
+}
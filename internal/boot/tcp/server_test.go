@@ -0,0 +1,98 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+)
+
+type stopHook struct {
+	hook.ServerEvent
+	stopped chan struct{}
+}
+
+func (h *stopHook) OnStop(s boot.Server) { close(h.stopped) }
+
+func newServer(h hook.ServerHook) *Server {
+	cfg := conf.Config{}
+	cfg.WithDefault()
+	return NewServer(context.Background(), cfg, h, "127.0.0.1:0")
+}
+
+// stopWithin 调用 Stop 并确认其按时返回，Wait 随即返回
+func stopWithin(t *testing.T, s *Server) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop blocked")
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestStopWithoutBind(t *testing.T) {
+	h := &stopHook{stopped: make(chan struct{})}
+	s := newServer(h)
+	stopWithin(t, s)
+	s.Stop() // 可重复调用
+	select {
+	case <-h.stopped:
+		t.Fatal("OnStop called for a server that never started")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestStopWithoutServe Bind 后未 Serve 时 Stop 关闭监听器并触发 OnStop
+func TestStopWithoutServe(t *testing.T) {
+	h := &stopHook{stopped: make(chan struct{})}
+	s := newServer(h)
+	if err := s.Bind(); err != nil {
+		t.Fatal(err)
+	}
+	addr := s.Addr().String()
+	stopWithin(t, s)
+
+	select {
+	case <-h.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStop not called")
+	}
+	if s.IsRunning() {
+		t.Fatal("server still running after Stop")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("listener not released: %v", err)
+	}
+	_ = ln.Close()
+	if err := s.Serve(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Serve after Stop = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestStopWhileServing(t *testing.T) {
+	s := newServer(&hook.ServerEvent{})
+	if err := s.Bind(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve() }()
+	time.Sleep(20 * time.Millisecond)
+	stopWithin(t, s)
+	if err := <-served; err != nil {
+		t.Fatalf("Serve = %v", err)
+	}
+}
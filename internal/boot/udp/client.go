@@ -12,6 +12,7 @@ import (
 )
 
 type Client struct {
+	raw     net.Conn // 外部传入的已连接数据报套接字，不再自行拨号
 	address string
 	ctx     context.Context
 	cfg     *conf.Config
@@ -31,8 +32,61 @@ func NewClient(ctx context.Context, cfg conf.Config, hook hook.ConnHook, addr st
 	}
 }
 
+// NewConnClient 基于已连接的数据报套接字创建客户端，连接只能使用一次
+func NewConnClient(ctx context.Context, cfg conf.Config, hook hook.ConnHook, raw net.Conn) *Client {
+	c := NewClient(ctx, cfg, hook, raw.RemoteAddr().String())
+	c.raw = raw
+	return c
+}
+
 func (c *Client) Dial() (boot.Conn, error) {
+	raw, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	c.log.Debug("Dial conn: " + raw.RemoteAddr().String())
+
+	nc := conn.NewNETConn(c.ctx, raw, c.cfg, c.hook)
+	nc.Client = true
+	nc.Start(c.wg)
+	if err = nc.Handshake(); err != nil { // 配置 Handshaker 时等待握手成功
+		nc.Close()
+		return nil, err
+	}
+
+	return nc, nil
+}
+
+// dial 按网络类型建立底层连接
+func (c *Client) dial() (net.Conn, error) {
+	if c.raw != nil {
+		return c.raw, nil
+	}
+
 	network := c.cfg.Network
+	if network == "unixgram" {
+		rAddr, err := net.ResolveUnixAddr(network, c.address)
+		if err != nil {
+			return nil, fmt.Errorf("resolve addr failed: %w", err)
+		}
+
+		// unixgram 客户端需绑定本地路径才能收到服务端回包
+		var lAddr *net.UnixAddr
+		if c.cfg.LocalAddr != "" {
+			if err = conn.RemoveStaleSocket(c.cfg.LocalAddr); err != nil {
+				return nil, err
+			}
+			lAddr, _ = net.ResolveUnixAddr(network, c.cfg.LocalAddr)
+		}
+
+		raw, err := net.DialUnix(network, lAddr, rAddr)
+		if err != nil {
+			return nil, fmt.Errorf("dial unixgram failed: %w", err)
+		}
+		return raw, nil
+	}
+
 	rAddr, err := net.ResolveUDPAddr(network, c.address)
 	if err != nil {
 		return nil, fmt.Errorf("resolve addr failed: %w", err)
@@ -47,11 +101,5 @@ func (c *Client) Dial() (boot.Conn, error) {
 	if err != nil {
 		return nil, fmt.Errorf("dial udp failed: %w", err)
 	}
-
-	c.log.Debug("Dial conn: " + raw.RemoteAddr().String())
-
-	nc := conn.NewNETConn(c.ctx, raw, c.cfg, c.hook)
-	nc.Start(c.wg)
-
-	return nc, nil
+	return raw, nil
 }
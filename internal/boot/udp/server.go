@@ -6,22 +6,39 @@ import (
 	"github.com/yurazsb/uno/internal/boot"
 	"github.com/yurazsb/uno/internal/boot/conn"
 	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/handoff"
 	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/mmsg"
+	"github.com/yurazsb/uno/internal/proxyproto"
 	"net"
+	"os"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
 // Server 是 UDP 的“伪连接”服务端。
-// 单个 UDP socket 上，按 remote(IP:port) 多路复用出多个逻辑连接（SConn）
+// 单个 UDP（或 unixgram）socket 上，按 remote(IP:port / 路径) 多路复用出多个逻辑连接（SConn）
 // 每个逻辑连接都包装为 *conn.Conn，具备完整的编解码、拆帧、Hook、队列化写等能力。
-type Server struct {
+// shard 一个数据报套接字及其伪连接表，启用 ReusePort 时每个套接字由独立的读循环处理
+type shard struct {
+	uc net.PacketConn
 	us *conn.UDPSession
+}
+
+type Server struct {
+	shards []*shard
+	reg    *conn.Registry // 各分片的伪连接汇总登记于此
 
-	address string
-	addr    net.Addr
-	uc      *net.UDPConn
+	address  string
+	addr     net.Addr
+	provided net.PacketConn // 外部传入的数据报套接字，不再自行监听
+
+	hl        *handoff.Listener // 监听套接字交接的控制套接字
+	handedOff atomic.Bool       // 套接字已交给新进程，停止时不删除套接字文件
+
+	proxy *proxyproto.Options // PROXY 协议配置（已补齐默认值），未启用时为 nil
 
 	// 生命周期
 	ctx    context.Context
@@ -37,6 +54,10 @@ type Server struct {
 
 	started atomic.Bool
 
+	served atomic.Bool
+	done   chan struct{} // 服务循环退出后关闭
+	err    error         // 服务循环的退出错误
+
 	stopOnce sync.Once
 	stopped  chan struct{}
 
@@ -49,7 +70,7 @@ type Server struct {
 // NewServer 创建 UDP 服务器实例（未监听）。
 func NewServer(ctx context.Context, cfg conf.Config, hook hook.ServerHook, addr string) *Server {
 	c, cancel := context.WithCancel(ctx)
-	return &Server{
+	s := &Server{
 		address:  addr,
 		ctx:      c,
 		cancel:   cancel,
@@ -57,88 +78,310 @@ func NewServer(ctx context.Context, cfg conf.Config, hook hook.ServerHook, addr
 		pool:     cfg.Pool,
 		log:      cfg.Logger,
 		hook:     hook,
+		reg:      conn.NewRegistry(),
 		wg:       &sync.WaitGroup{},
 		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
 		reapStop: make(chan struct{}),
 	}
+	if cfg.ProxyProtocol != nil {
+		opts := *cfg.ProxyProtocol
+		opts.WithDefault()
+		s.proxy = &opts
+	}
+	return s
+}
+
+// NewPacketConnServer 基于已有的数据报套接字创建服务，复用同一伪连接机制；服务停止时关闭该套接字
+func NewPacketConnServer(ctx context.Context, cfg conf.Config, hook hook.ServerHook, pc net.PacketConn) *Server {
+	s := NewServer(ctx, cfg, hook, pc.LocalAddr().String())
+	s.provided = pc
+	return s
 }
 
 func (s *Server) Addr() net.Addr           { return s.addr }
 func (s *Server) Context() context.Context { return s.ctx }
 func (s *Server) IsRunning() bool          { return s.running.Load() }
+func (s *Server) Conns() boot.ConnManager  { return s.reg }
 
 func (s *Server) Stop() {
 	s.stopOnce.Do(func() {
 		s.cancel()
+		// 服务循环未运行（未 Bind 或 Bind 后未 Serve）时由 Stop 释放资源，否则等待服务循环退出
+		if s.served.CompareAndSwap(false, true) {
+			s.clear()
+			close(s.done)
+			return
+		}
 		<-s.stopped
 	})
 }
 
+// Shutdown 优雅关闭：不再创建新的伪连接，并发排空现有连接后停止服务
+func (s *Server) Shutdown(ctx context.Context) (boot.ShutdownReport, error) {
+	for _, sh := range s.shards {
+		sh.us.Drain()
+	}
+
+	report := s.reg.Drain(ctx)
+	s.Stop()
+	if report.Killed > 0 {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
+
+// Listen 监听并阻塞运行服务循环，直到 Stop() 调用或出错
 func (s *Server) Listen() error {
-	if s.started.Load() {
+	if err := s.Bind(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Bind 监听地址并触发 OnStart，返回后 Addr() 即可用；随后需调用 Serve 运行服务循环
+func (s *Server) Bind() error {
+	if !s.started.CompareAndSwap(false, true) {
 		return errors.New("already started")
 	}
 
-	s.started.Store(true)
-	network := s.cfg.Network // "udp", "udp4", "udp6"
-	udpAddr, err := net.ResolveUDPAddr(network, s.address)
+	pcs, err := s.listen()
 	if err != nil {
 		return err
 	}
 
-	s.uc, err = net.ListenUDP(network, udpAddr)
-	if err != nil {
-		return err
+	s.running.Store(true)
+	for _, pc := range pcs {
+		s.shards = append(s.shards, &shard{uc: pc, us: conn.NewUDPSession(pc, s.cfg, s.hook, s.reg)})
 	}
+	s.addr = pcs[0].LocalAddr()
+	s.log.Debug("listening on %s://%s (%d sockets)", s.cfg.Network, s.addr.String(), len(pcs))
 
-	s.us = conn.NewUDPSession(s.uc, s.cfg, s.hook)
-	s.addr = s.uc.LocalAddr()
-	s.log.Debug("listening on %s://%s", s.cfg.Network, s.addr.String())
+	if err = s.listenHandoff(); err != nil {
+		for _, pc := range pcs {
+			_ = pc.Close()
+		}
+		s.shards = nil
+		s.running.Store(false)
+		return err
+	}
 
 	task := func() { s.hook.OnStart(s) }
 	if !s.pool.Submit(task) {
 		s.log.Error("fail to submit task: %v", task)
 	}
+	return nil
+}
 
-	// 空闲连接清理（可选）
+// Serve 运行服务循环，阻塞直到 Stop() 调用或出错，需在 Bind 成功后调用
+func (s *Server) Serve() error {
+	if s.addr == nil {
+		return errors.New("not bound")
+	}
+	if !s.served.CompareAndSwap(false, true) {
+		if s.ctx.Err() != nil {
+			return net.ErrClosed
+		}
+		return errors.New("already serving")
+	}
+
+	// 空闲连接清理（未配置时默认 5 分钟）
 	idleTimeout := s.cfg.IdleTimeout
-	if idleTimeout > 0 {
+	if idleTimeout <= 0 {
 		idleTimeout = 5 * time.Minute
 	}
+	s.wg.Add(1)
 	go s.reaper(idleTimeout)
 
 	// 主循环
-	return s.serve()
+	s.err = s.serve()
+	close(s.done)
+	return s.err
+}
+
+// Wait 阻塞直到服务循环退出，返回其退出错误
+func (s *Server) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Err 服务循环的退出错误，运行中或正常停止时为 nil
+func (s *Server) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// listen 按网络类型创建数据报套接字："udp"、"udp4"、"udp6" 或 "unixgram"；
+// 启用 ReusePort 的 UDP 服务创建多个共享地址的套接字
+func (s *Server) listen() ([]net.PacketConn, error) {
+	if s.provided != nil {
+		return []net.PacketConn{s.provided}, nil
+	}
+
+	network := s.cfg.Network
+	if network != "unixgram" && s.cfg.ReusePort > 1 {
+		if s.cfg.Handoff != nil {
+			return nil, errors.New("handoff does not support ReusePort")
+		}
+		if !conn.ReusePortSupported {
+			s.log.Warn("SO_REUSEPORT is not supported on %s, using a single socket", runtime.GOOS)
+		}
+		return conn.ListenPacketReusePort(s.ctx, network, s.address, s.cfg.ReusePort)
+	}
+
+	uc, err := s.inherit()
+	if err != nil {
+		return nil, err
+	}
+	if uc != nil {
+		return []net.PacketConn{uc}, nil
+	}
+	if network == "unixgram" {
+		if err := conn.RemoveStaleSocket(s.address); err != nil {
+			return nil, err
+		}
+		uAddr, err := net.ResolveUnixAddr(network, s.address)
+		if err != nil {
+			return nil, err
+		}
+		uc, err = net.ListenUnixgram(network, uAddr)
+		if err != nil {
+			return nil, err
+		}
+		if err = conn.ChmodSocket(s.address, s.cfg.UnixSocketMode); err != nil {
+			_ = uc.Close()
+			_ = conn.RemoveStaleSocket(s.address)
+			return nil, err
+		}
+		return []net.PacketConn{uc}, nil
+	}
+
+	udpAddr, err := net.ResolveUDPAddr(network, s.address)
+	if err != nil {
+		return nil, err
+	}
+	uc, err = net.ListenUDP(network, udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	return []net.PacketConn{uc}, nil
+}
+
+// inherit 启用交接时向旧进程请求接管套接字，无旧进程时返回 nil
+func (s *Server) inherit() (net.PacketConn, error) {
+	if s.cfg.Handoff == nil {
+		return nil, nil
+	}
+	f, err := handoff.Request(s.cfg.Handoff.Socket, handoff.Key(s.cfg.Network, s.address))
+	if err != nil || f == nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	uc, err := net.FilePacketConn(f)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("inherited socket %s://%s", s.cfg.Network, uc.LocalAddr())
+	return uc, nil
+}
+
+// listenHandoff 监听控制套接字，等待新进程接管
+func (s *Server) listenHandoff() error {
+	if s.cfg.Handoff == nil {
+		return nil
+	}
+	hl, err := handoff.Listen(s.cfg.Handoff.Socket)
+	if err != nil {
+		return err
+	}
+	s.hl = hl
+	go s.handoff(hl, s.shards[0].uc)
+	return nil
+}
+
+// handoff 交出套接字后不再创建新的伪连接，并在后台排空现有连接。
+// 新旧进程共享同一套接字，排空期间已有对端的数据报可能被新进程读取。
+func (s *Server) handoff(hl *handoff.Listener, uc net.PacketConn) {
+	file := func() (*os.File, error) {
+		f, ok := uc.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, net.ErrClosed
+		}
+		return f.File()
+	}
+	if err := hl.Accept(handoff.Key(s.cfg.Network, s.address), file); err != nil {
+		return
+	}
+	s.handedOff.Store(true)
+	s.log.Info("socket %s://%s handed off, draining", s.cfg.Network, s.addr)
+
+	opts := *s.cfg.Handoff
+	opts.WithDefault()
+	ctx, cancel := context.WithTimeout(context.Background(), opts.DrainTimeout)
+	defer cancel()
+	report, err := s.Shutdown(ctx)
+	s.log.Info("drained %d, killed %d conns after handoff: %v", report.Drained, report.Killed, err)
 }
 
 func (s *Server) serve() error {
 	defer s.clear()
 
+	// 每个分片一个读循环，均在 Stop() 或 Context 取消后退出
+	var g sync.WaitGroup
+	for _, sh := range s.shards {
+		g.Add(1)
+		go func() {
+			defer g.Done()
+			if s.cfg.UDPBatchSize > 1 {
+				s.readBatch(sh)
+			} else {
+				s.read(sh)
+			}
+		}()
+	}
+	g.Wait()
+	return nil
+}
+
+// read 分片读循环：读取数据报并投递给该分片的伪连接
+func (s *Server) read(sh *shard) {
 	// 主读缓冲可复用，但每次要 Clone 给下游，避免数据竞争
 	buf := make([]byte, s.cfg.ReadBufferSize)
 
+	// 停止时立即唤醒阻塞中的读，无需等待读超时
+	uc := sh.uc
+	stop := context.AfterFunc(s.ctx, func() { _ = uc.SetReadDeadline(time.Now()) })
+	defer stop()
+
 	for {
 		select {
 		case <-s.ctx.Done():
-			return nil
+			return
 		default:
 		}
 
 		// 可选读超时（避免永久阻塞，便于响应 Stop）此处实际用于接收连接
-		_ = s.uc.SetReadDeadline(time.Now().Add(conn.AcceptTimeout))
-		nr, raddr, err := s.uc.ReadFromUDP(buf)
+		_ = uc.SetReadDeadline(time.Now().Add(conn.AcceptTimeout))
+		nr, raddr, err := uc.ReadFrom(buf)
 
 		// 先处理有效数据（即便 err != nil，也要先处理 nc>0 的数据）
 		if nr > 0 {
-			chunk := make([]byte, nr)
-			copy(chunk, buf[:nr])
-			s.us.Delivery(s.ctx, s.wg, raddr, chunk)
+			if hdr, payload, ok := s.stripProxy(raddr, buf[:nr]); ok {
+				chunk := make([]byte, len(payload))
+				copy(chunk, payload)
+				sh.us.Delivery(s.ctx, s.wg, raddr, hdr, chunk)
+			}
 		}
 
 		if err != nil {
 			// 被 Stop() 关闭或 Context 取消
 			if s.ctx.Err() != nil {
-				return nil
+				return
 			}
 
 			// 其他错误：记录并继续
@@ -147,9 +390,73 @@ func (s *Server) serve() error {
 	}
 }
 
+// readBatch 分片批量读循环：每次系统调用读取多个数据报（非 Linux 平台逐个读取）
+func (s *Server) readBatch(sh *shard) {
+	uc := sh.uc
+	bc := mmsg.New(uc)
+	ms := make([]mmsg.Message, s.cfg.UDPBatchSize)
+	for i := range ms {
+		ms[i].Buf = make([]byte, s.cfg.ReadBufferSize)
+	}
+
+	// 停止时立即唤醒阻塞中的读，无需等待读超时
+	stop := context.AfterFunc(s.ctx, func() { _ = uc.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+
+		_ = uc.SetReadDeadline(time.Now().Add(conn.AcceptTimeout))
+		n, err := bc.ReadBatch(ms)
+
+		for i := range ms[:n] {
+			m := &ms[i]
+			if m.N == 0 {
+				continue
+			}
+			if hdr, payload, ok := s.stripProxy(m.Addr, m.Buf[:m.N]); ok {
+				chunk := make([]byte, len(payload))
+				copy(chunk, payload)
+				sh.us.Delivery(s.ctx, s.wg, m.Addr, hdr, chunk)
+			}
+		}
+
+		if err != nil && s.ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// stripProxy 剥离可信来源数据报开头的 PROXY 协议 v2 头部，数据报应被丢弃时返回 false
+func (s *Server) stripProxy(raddr net.Addr, b []byte) (*proxyproto.Header, []byte, bool) {
+	if s.proxy == nil || raddr == nil || !s.proxy.Trusts(raddr) {
+		return nil, b, true
+	}
+
+	hdr, n, err := proxyproto.ParseV2(b)
+	if err != nil {
+		if errors.Is(err, proxyproto.ErrNoHeader) && !s.proxy.Required {
+			return nil, b, true
+		}
+		s.log.Debug("%s: %s", raddr, err)
+		return nil, nil, false
+	}
+	// 仅含头部的数据报（如负载均衡的健康检查）没有负载
+	if len(b) == n {
+		return nil, nil, false
+	}
+	if hdr.Command == proxyproto.CmdLocal {
+		hdr = nil
+	}
+	return hdr, b[n:], true
+}
+
 // 清理空闲连接：定时扫描 connMap，超过 idle 超时的连接关闭并移除
 func (s *Server) reaper(idle time.Duration) {
-	s.wg.Add(1)
 	defer s.wg.Done()
 
 	tk := time.NewTicker(idle / 2)
@@ -162,13 +469,16 @@ func (s *Server) reaper(idle time.Duration) {
 		case <-s.ctx.Done():
 			return
 		case <-tk.C:
-			s.us.Reaper(idle)
+			for _, sh := range s.shards {
+				sh.us.Reaper(idle)
+			}
 		}
 	}
 }
 
 func (s *Server) clear() {
-	if !s.running.Load() || s.uc == nil {
+	if !s.running.Load() {
+		close(s.stopped)
 		return
 	}
 
@@ -177,8 +487,16 @@ func (s *Server) clear() {
 
 	s.wg.Wait()
 
-	_ = s.uc.Close()
-	s.uc = nil
+	if s.hl != nil {
+		_ = s.hl.Close()
+	}
+	for _, sh := range s.shards {
+		sh.us.Close()
+		_ = sh.uc.Close()
+	}
+	if s.cfg.Network == "unixgram" && !s.handedOff.Load() {
+		_ = conn.RemoveStaleSocket(s.address)
+	}
 	close(s.stopped)
 
 	// 触发 Hook
@@ -0,0 +1,138 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/boot/conn"
+	"github.com/yurazsb/uno/internal/conf"
+	"github.com/yurazsb/uno/internal/hook"
+)
+
+type stopHook struct {
+	hook.ServerEvent
+	stopped chan struct{}
+}
+
+func (h *stopHook) OnStop(s boot.Server) { close(h.stopped) }
+
+func newServer(h hook.ServerHook) *Server {
+	cfg := conf.Config{Network: "udp"}
+	cfg.WithDefault()
+	return NewServer(context.Background(), cfg, h, "127.0.0.1:0")
+}
+
+// stopWithin 调用 Stop 并确认其按时返回，Wait 随即返回
+func stopWithin(t *testing.T, s *Server) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop blocked")
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestStopWithoutBind(t *testing.T) {
+	h := &stopHook{stopped: make(chan struct{})}
+	s := newServer(h)
+	stopWithin(t, s)
+	s.Stop() // 可重复调用
+	select {
+	case <-h.stopped:
+		t.Fatal("OnStop called for a server that never started")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestStopWithoutServe Bind 后未 Serve 时 Stop 关闭套接字并触发 OnStop
+func TestStopWithoutServe(t *testing.T) {
+	h := &stopHook{stopped: make(chan struct{})}
+	s := newServer(h)
+	if err := s.Bind(); err != nil {
+		t.Fatal(err)
+	}
+	addr := s.Addr().String()
+	stopWithin(t, s)
+
+	select {
+	case <-h.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnStop not called")
+	}
+	if s.IsRunning() {
+		t.Fatal("server still running after Stop")
+	}
+	ln, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Fatalf("socket not released: %v", err)
+	}
+	_ = ln.Close()
+	if err := s.Serve(); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Serve after Stop = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestStopWhileServing(t *testing.T) {
+	s := newServer(&hook.ServerEvent{})
+	if err := s.Bind(); err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve() }()
+	time.Sleep(20 * time.Millisecond)
+	stopWithin(t, s)
+	if err := <-served; err != nil {
+		t.Fatalf("Serve = %v", err)
+	}
+}
+
+// TestReusePortConns 各套接字拥有独立的伪连接表，Conns() 汇总所有分片
+func TestReusePortConns(t *testing.T) {
+	cfg := conf.Config{Network: "udp", ReusePort: 4}
+	cfg.WithDefault()
+	s := NewServer(context.Background(), cfg, &hook.ServerEvent{}, "127.0.0.1:0")
+	if err := s.Bind(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = s.Serve() }()
+	defer stopWithin(t, s)
+
+	want := 4
+	if !conn.ReusePortSupported {
+		want = 1
+	}
+	if len(s.shards) != want {
+		t.Fatalf("opened %d sockets, want %d", len(s.shards), want)
+	}
+
+	const peers = 32
+	for i := 0; i < peers; i++ {
+		c, err := net.Dial("udp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if _, err := c.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Conns().Count() != peers {
+		if time.Now().After(deadline) {
+			t.Fatalf("Conns().Count() = %d, want %d", s.Conns().Count(), peers)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
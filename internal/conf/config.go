@@ -1,18 +1,40 @@
 package conf
 
 import (
+	"crypto/tls"
+	"github.com/yurazsb/uno/internal/arq"
 	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/boot/reconnect"
 	"github.com/yurazsb/uno/internal/decoder"
 	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/fragment"
 	"github.com/yurazsb/uno/internal/framer"
 	"github.com/yurazsb/uno/internal/handler"
+	"github.com/yurazsb/uno/internal/handoff"
+	"github.com/yurazsb/uno/internal/handshake"
+	"github.com/yurazsb/uno/internal/heartbeat"
+	"github.com/yurazsb/uno/internal/mux"
+	"github.com/yurazsb/uno/internal/proxyproto"
 	"github.com/yurazsb/uno/pkg/logger"
 	"github.com/yurazsb/uno/pkg/pool"
+	"github.com/yurazsb/uno/pkg/state"
 	"github.com/yurazsb/uno/pkg/uuid"
+	"net/http"
+	"os"
 	"runtime"
 	"time"
 )
 
+// QueuePolicy 发送队列已满时的处理策略
+type QueuePolicy int
+
+const (
+	QueueFailFast   QueuePolicy = iota // 立即返回队列已满错误
+	QueueBlock                         // 阻塞等待，直到队列有空位、连接关闭或发送上下文结束
+	QueueDropOldest                    // 丢弃队列中最旧的消息
+	QueueClose                         // 关闭连接
+)
+
 // Config 定义了通信框架的运行时配置。
 // 建议通过 WithXXX 方法链式构建，再调用 WithDefault() 补齐未设置项。
 type Config struct {
@@ -40,7 +62,7 @@ type Config struct {
 	// 按照顺序执行。
 	Handlers []handler.Handler
 
-	// Network 网络类型（"tcp"、"udp"）。
+	// Network 网络类型（"tcp"、"udp"、"unix"、"unixpacket"、"unixgram"、"ws"、"wss"）。
 	// 如果为空，默认使用 "tcp"。
 	Network string
 
@@ -68,6 +90,10 @@ type Config struct {
 	// 如果为 0，使用框架可能设有兜底值 30s。
 	WriteTimeout time.Duration
 
+	// CloseTimeout 关闭连接时等待发送队列写出与工作协程退出的时间，超时后强制中止传输层。
+	// 如果为 0，默认 5s。
+	CloseTimeout time.Duration
+
 	// ReadBufferSize 读缓冲区大小（单位：字节）。
 	// 如果为 0，默认 4096。
 	ReadBufferSize int
@@ -83,6 +109,129 @@ type Config struct {
 	// TickInterval 内部定时任务的周期（如 Idle 检测）。
 	// 如果为 0，表示不启用周期任务。
 	TickInterval time.Duration
+
+	// TLSConfig TLS 配置，设置后 TCP 服务端与客户端启用 TLS。
+	// 服务端需配置 Certificates，双向认证通过 ClientAuth / ClientCAs 开启；
+	// 客户端未设置 ServerName 时默认使用拨号地址中的主机名。
+	// 如果为 nil，表示不启用 TLS。
+	TLSConfig *tls.Config
+
+	// TLSHandshakeTimeout TLS 握手超时时间。
+	// 如果为 0，默认 10 秒。
+	TLSHandshakeTimeout time.Duration
+
+	// UnixSocketMode Unix 域套接字文件权限，仅 Unix 服务端有效。
+	// 如果为 0，保持系统默认（受 umask 影响）。
+	UnixSocketMode os.FileMode
+
+	// WSPath WebSocket 服务端允许升级的请求路径，例如 "/ws"。
+	// 如果为空，接受任意路径。
+	WSPath string
+
+	// WSCheckOrigin WebSocket 服务端 Origin 校验函数，返回 false 时拒绝握手。
+	// 如果为 nil，不校验 Origin。
+	WSCheckOrigin func(r *http.Request) bool
+
+	// WSText 是否以文本帧发送消息（默认二进制帧）。
+	WSText bool
+
+	// WSMaxMessageSize 单条 WebSocket 消息（含分片重组）的最大字节数。
+	// 如果为 0，默认 4MB。
+	WSMaxMessageSize int
+
+	// ARQ 可靠有序传输配置（类 KCP），仅 UDP / unixgram 有效，服务端与客户端需同时开启。
+	// 如果为 nil，表示不启用。
+	ARQ *arq.Options
+
+	// Fragment UDP 分片重组配置，超过 MTU 的消息拆分为多个数据报发送，服务端与客户端需同时开启。
+	// 启用 ARQ 时由 ARQ 负责分段，该配置不生效。
+	// 如果为 nil，表示不启用。
+	Fragment *fragment.Options
+
+	// Ordered 是否按连接串行派发事件。开启后同一连接的回调与消息处理严格按
+	// OnConnect → OnMessage... → OnClose 的先后顺序执行，不同连接之间仍由协程池并发处理。
+	// 默认 false，所有任务直接提交协程池，执行顺序不保证。
+	Ordered bool
+
+	// RPC 是否启用请求/应答模式。开启后所有消息以信封帧（类型 + 消息 ID + 长度）收发，
+	// 支持 Conn.Call 与 Context.Reply，Framer 不再生效，服务端与客户端需同时开启。
+	RPC bool
+
+	// SendQueueSize 单连接发送队列最多容纳的消息数。
+	// 如果为 0，默认 4096。
+	SendQueueSize int
+
+	// SendQueueBytes 单连接发送队列最多容纳的字节数（编码后）。
+	// 如果为 0，表示不限制。
+	SendQueueBytes int
+
+	// SendQueuePolicy 发送队列已满时的处理策略。
+	// 默认 QueueFailFast：Send 不阻塞调用方，队列已满时返回 ErrSendQueueFull；需要阻塞式背压时设置为 QueueBlock。
+	SendQueuePolicy QueuePolicy
+
+	// SendQueueHighWatermark 发送队列字节数升至该值时触发高水位通知，生产方可据此暂停发送。
+	// 如果为 0，表示不启用水位通知。
+	SendQueueHighWatermark int
+
+	// SendQueueLowWatermark 越过高水位后，队列字节数回落至该值时触发低水位通知。
+	// 如果为 0 或不小于高水位，默认为高水位的一半。
+	SendQueueLowWatermark int
+
+	// Mux 多路复用配置，在单个连接上承载多个独立的逻辑流，服务端与客户端需同时开启。
+	// 连接自身的消息经流 0 收发；数据报网络需同时启用 ARQ。
+	// 如果为 nil，表示不启用。
+	Mux *mux.Options
+
+	// Handoff 监听套接字交接配置（零停机重启，仅 Unix 平台）。
+	// 服务启动时先经控制套接字向旧进程请求接管监听套接字，旧进程交出后停止接受新连接并在后台排空。
+	// 如果为 nil，表示不启用。
+	Handoff *handoff.Options
+
+	// ReusePort 服务端通过 SO_REUSEPORT 在同一地址上打开的套接字数量（仅 Linux / BSD / macOS，
+	// 不适用于 Unix 域套接字与外部传入的监听器），由内核在其间分发新连接与数据报。
+	// TCP 服务端每个监听器拥有独立的 accept 循环；UDP 服务端每个套接字拥有独立的读循环与伪连接表。
+	// 如果小于等于 1，使用单个套接字；其他平台同样使用单个套接字并记录警告。不能与 Handoff 同时使用。
+	ReusePort int
+
+	// UDPBatchSize UDP 服务端单次批量收发的最大数据报数。Linux 下通过 recvmmsg / sendmmsg 在一次系统调用中
+	// 读取多个数据报、发送各伪连接并发写入的数据报，其他平台逐个收发。
+	// 如果小于等于 1，不启用。
+	UDPBatchSize int
+
+	// Handshaker 连接握手处理：连接建立后先进入握手阶段，期间拆出的帧交由 Handshaker 处理而非处理器链，
+	// 可校验版本、协商编解码与认证，接受后派发 OnReady 并开始处理普通消息。客户端 Dial 在握手成功后返回。
+	// 如果为 nil，表示不启用。
+	Handshaker handshake.Handshaker
+
+	// HandshakeTimeout 握手超时，超时后以 ErrHandshakeTimeout 关闭连接。
+	// 如果为 0，默认 10s。
+	HandshakeTimeout time.Duration
+
+	// States 为每个连接的生命周期状态机注册应用自定义状态、迁移与钩子，连接创建后、启动前调用。
+	// 状态生效之后的钩子经连接的协程池执行。
+	// 如果为 nil，连接仅维护生命周期状态。
+	States func(c boot.Conn, m *state.Machine)
+
+	// Heartbeat 应用层心跳配置：按周期发送 ping 帧，对端自动回复 pong 帧，
+	// 连续多次在超时内未收到 pong 或任何入站数据时以 ErrHeartbeatTimeout 关闭连接，并测量往返时延。
+	// 如果为 nil，表示不启用。
+	Heartbeat *heartbeat.Options
+
+	// Reactor TCP / Unix 流式服务端的 epoll 事件循环数量（仅 Linux）。少量事件循环监听全部连接，
+	// 套接字可读时才在循环协程中读取，数据交给按需启动的协程处理，连接不再占用常驻读协程、读缓冲与写协程，适合海量空闲长连接；
+	// Framer、Decoder 与处理器不受影响。TLS、WebSocket 与 PROXY 协议连接仍使用读协程。
+	// 如果小于等于 0，不启用。
+	Reactor int
+
+	// ProxyProtocol HAProxy PROXY 协议配置，仅服务端有效。
+	// TCP 服务端在 Framer 之前解析可信来源连接开头的 v1 / v2 头部，UDP 服务端解析每个数据报开头的 v2 头部，
+	// 连接的 RemoteAddr 随后报告客户端的真实地址。
+	// 如果为 nil，表示不启用。
+	ProxyProtocol *proxyproto.Options
+
+	// Reconnect 自动重连配置，仅客户端有效。设置后 Dial 返回的连接在底层断开时按退避策略重新拨号。
+	// 如果为 nil，表示不启用。
+	Reconnect *reconnect.Options
 }
 
 func (c *Config) WithDefault() {
@@ -123,10 +272,28 @@ func (c *Config) WithDefault() {
 	if c.WriteTimeout <= 0 {
 		c.WriteTimeout = 30 * time.Second
 	}
+	if c.CloseTimeout <= 0 {
+		c.CloseTimeout = 5 * time.Second
+	}
 	if c.ReadBufferSize <= 0 {
 		c.ReadBufferSize = 4096
 	}
 	if c.MTU <= 0 {
 		c.MTU = 1472
 	}
+	if c.HandshakeTimeout <= 0 {
+		c.HandshakeTimeout = 10 * time.Second
+	}
+	if c.TLSHandshakeTimeout <= 0 {
+		c.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if c.WSMaxMessageSize <= 0 {
+		c.WSMaxMessageSize = 4 << 20
+	}
+	if c.SendQueueSize <= 0 {
+		c.SendQueueSize = 4096
+	}
+	if c.SendQueueLowWatermark <= 0 || c.SendQueueLowWatermark >= c.SendQueueHighWatermark {
+		c.SendQueueLowWatermark = c.SendQueueHighWatermark / 2
+	}
 }
@@ -1,6 +1,7 @@
 package encoder
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"github.com/yurazsb/uno/internal/boot"
@@ -47,3 +48,26 @@ var GenericEncoder = func() Encoder {
 		}
 	}
 }
+
+// VarintLengthEncoder 返回一个添加 varint 长度前缀的编码器 (Encoder)。
+//
+// 功能说明：
+//   - 先由 e 编码消息（为 nil 时使用 GenericEncoder），再在前面添加无符号 LEB128 varint 长度前缀
+//   - 输出格式与 framer.VarintLengthFramer 对应，前缀与 encoding/binary.AppendUvarint 一致
+//
+// 使用场景：
+//   - protobuf 流、使用 varint 长度前缀的日志或消息队列协议
+var VarintLengthEncoder = func(e Encoder) Encoder {
+	if e == nil {
+		e = GenericEncoder()
+	}
+	return func(c boot.Conn, msg any) (buf []byte, err error) {
+		payload, err := e(c, msg)
+		if err != nil {
+			return nil, err
+		}
+		buf = make([]byte, 0, binary.MaxVarintLen64+len(payload))
+		buf = binary.AppendUvarint(buf, uint64(len(payload)))
+		return append(buf, payload...), nil
+	}
+}
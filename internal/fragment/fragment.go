@@ -0,0 +1,364 @@
+package fragment
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"github.com/yurazsb/uno/internal/boot"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Name 处理层名称
+const Name = "fragment"
+
+// 数据报格式：
+//
+//	完整消息: [0x00][payload]
+//	分片消息: [0x01][msgID 4][index 2][count 2][payload]
+const (
+	kindWhole byte = 0
+	kindFrag  byte = 1
+
+	wholeHeader = 1
+	fragHeader  = 9
+)
+
+// 重组中的消息除负载外的内存开销：每个分片槽位一个切片头，另计记录本身、链表节点与 map 项
+const (
+	slotCost  = int(unsafe.Sizeof([]byte(nil)))
+	entryCost = int(unsafe.Sizeof(pending{})+unsafe.Sizeof(list.Element{})) + 64
+)
+
+var (
+	ErrTooLarge  = errors.New("fragment: message too large")
+	ErrMalformed = errors.New("fragment: malformed datagram")
+	ErrMTUTooLow = errors.New("fragment: mtu too small")
+)
+
+// Options 分片重组参数，服务端与客户端需同时开启。
+type Options struct {
+	// Timeout 不完整消息的重组超时，到期后由定时器丢弃并计入 Stats.Expired。
+	// 如果为 0，默认 5 秒。
+	Timeout time.Duration
+
+	// MaxPending 单个对端重组中的消息所占内存上限（字节，含分片槽位与记录开销），超出时丢弃最旧的不完整消息。
+	// 如果为 0，默认 4MB。
+	MaxPending int
+
+	// MaxPendingMessages 单个对端同时重组中的消息数上限，超出时丢弃最旧的不完整消息。
+	// 如果为 0，默认 256。
+	MaxPendingMessages int
+
+	// MaxMessageSize 单条消息的最大字节数。
+	// 如果为 0，默认 1MB。
+	MaxMessageSize int
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	if o.MaxPending <= 0 {
+		o.MaxPending = 4 << 20
+	}
+	if o.MaxPendingMessages <= 0 {
+		o.MaxPendingMessages = 256
+	}
+	if o.MaxMessageSize <= 0 {
+		o.MaxMessageSize = 1 << 20
+	}
+}
+
+// Stats 分片重组统计
+type Stats struct {
+	Fragmented  uint64 // 被拆分发送的消息数
+	Reassembled uint64 // 重组成功的消息数
+	Expired     uint64 // 重组超时丢弃的不完整消息数
+	Evicted     uint64 // 超出内存上限丢弃的不完整消息数
+	Malformed   uint64 // 格式错误的数据报
+	Pending     int    // 当前重组中的消息数
+	PendingSize int    // 当前重组中的消息所占内存（字节，含分片槽位与记录开销）
+}
+
+// StatsOf 获取连接的分片重组统计，未启用时返回 false
+func StatsOf(c boot.Conn) (Stats, bool) {
+	lc, ok := c.(interface {
+		Layer(name string) (boot.Layer, bool)
+	})
+	if !ok {
+		return Stats{}, false
+	}
+	l, ok := lc.Layer(Name)
+	if !ok {
+		return Stats{}, false
+	}
+	return l.(*Layer).Stats(), true
+}
+
+type pending struct {
+	id       uint32
+	parts    [][]byte
+	received int
+	size     int // 已收到的负载字节数
+	cost     int // 计入内存上限的字节数
+	deadline time.Time
+	elem     *list.Element
+}
+
+// Layer 单连接的分片重组层：超过 MTU 的消息按 MTU 拆分发送，接收端按消息 ID 重组。
+type Layer struct {
+	opts  Options
+	chunk int // 单个分片的最大负载
+	mtu   int
+	io    boot.LayerIO
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]*pending
+	order   *list.List // 按到达顺序（即截止时间顺序）排列的重组中消息，用于超时与淘汰
+	size    int
+	stats   Stats
+	timer   *time.Timer // 最早的截止时间到达时清理超时消息，无需等待下一个分片到达
+	armed   time.Time   // 定时器对应的截止时间
+	closed  bool
+}
+
+// New 创建分片重组层，mtu 为下层单个数据报的最大字节数
+func New(opts Options, mtu int) *Layer {
+	opts.WithDefault()
+	return &Layer{
+		opts:    opts,
+		mtu:     mtu,
+		chunk:   mtu - fragHeader,
+		pending: make(map[uint32]*pending),
+		order:   list.New(),
+	}
+}
+
+func (l *Layer) Name() string { return Name }
+
+func (l *Layer) Open(c boot.Conn, io boot.LayerIO) { l.io = io }
+
+func (l *Layer) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.pending = make(map[uint32]*pending)
+	l.order.Init()
+	l.size = 0
+}
+
+// Write 未超过 MTU 的消息整包发送，否则拆分为多个分片
+func (l *Layer) Write(buf []byte) error {
+	if len(buf) > l.opts.MaxMessageSize {
+		return ErrTooLarge
+	}
+
+	if len(buf)+wholeHeader <= l.mtu {
+		pkt := make([]byte, 0, len(buf)+wholeHeader)
+		pkt = append(pkt, kindWhole)
+		pkt = append(pkt, buf...)
+		return l.io.Down(pkt)
+	}
+
+	if l.chunk <= 0 {
+		return ErrMTUTooLow
+	}
+	count := (len(buf) + l.chunk - 1) / l.chunk
+	if count > 0xFFFF {
+		return ErrTooLarge
+	}
+
+	l.mu.Lock()
+	id := l.nextID
+	l.nextID++
+	l.stats.Fragmented++
+	l.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		part := buf[i*l.chunk : min((i+1)*l.chunk, len(buf))]
+		pkt := make([]byte, 0, fragHeader+len(part))
+		pkt = append(pkt, kindFrag)
+		pkt = binary.BigEndian.AppendUint32(pkt, id)
+		pkt = binary.BigEndian.AppendUint16(pkt, uint16(i))
+		pkt = binary.BigEndian.AppendUint16(pkt, uint16(count))
+		pkt = append(pkt, part...)
+		if err := l.io.Down(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read 整包直接投递，分片在集齐后投递
+func (l *Layer) Read(chunk []byte) error {
+	if len(chunk) < wholeHeader {
+		return l.malformed()
+	}
+
+	switch chunk[0] {
+	case kindWhole:
+		l.io.Up(chunk[wholeHeader:])
+		return nil
+	case kindFrag:
+	default:
+		return l.malformed()
+	}
+
+	if len(chunk) < fragHeader {
+		return l.malformed()
+	}
+	id := binary.BigEndian.Uint32(chunk[1:])
+	index := int(binary.BigEndian.Uint16(chunk[5:]))
+	count := int(binary.BigEndian.Uint16(chunk[7:]))
+	data := chunk[fragHeader:]
+	if count == 0 || index >= count || count*l.chunk > l.opts.MaxMessageSize+l.chunk {
+		return l.malformed()
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	l.expire(now)
+
+	p, ok := l.pending[id]
+	if !ok {
+		p = &pending{id: id, parts: make([][]byte, count), deadline: now.Add(l.opts.Timeout)}
+		p.cost = entryCost + count*slotCost
+		p.elem = l.order.PushBack(p)
+		l.pending[id] = p
+		l.size += p.cost
+	}
+	if len(p.parts) != count {
+		l.schedule(now)
+		l.mu.Unlock()
+		return l.malformed()
+	}
+	if p.parts[index] != nil {
+		l.schedule(now)
+		l.mu.Unlock()
+		return nil // 重复分片
+	}
+
+	p.parts[index] = append([]byte(nil), data...)
+	p.received++
+	p.size += len(data)
+	p.cost += len(data)
+	l.size += len(data)
+
+	var msg []byte
+	if p.received == count {
+		msg = make([]byte, 0, p.size)
+		for _, part := range p.parts {
+			msg = append(msg, part...)
+		}
+		l.remove(p)
+		l.stats.Reassembled++
+	} else {
+		l.evict(p)
+	}
+	l.schedule(now)
+	l.mu.Unlock()
+
+	if msg != nil {
+		l.io.Up(msg)
+	}
+	return nil
+}
+
+// Stats 返回统计快照
+func (l *Layer) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.stats
+	st.Pending = len(l.pending)
+	st.PendingSize = l.size
+	return st
+}
+
+func (l *Layer) malformed() error {
+	l.mu.Lock()
+	l.stats.Malformed++
+	l.mu.Unlock()
+	return ErrMalformed
+}
+
+// expire 丢弃已超时的不完整消息
+func (l *Layer) expire(now time.Time) {
+	for e := l.order.Front(); e != nil; e = l.order.Front() {
+		p := e.Value.(*pending)
+		if now.Before(p.deadline) {
+			return
+		}
+		l.remove(p)
+		l.stats.Expired++
+	}
+}
+
+// schedule 按最早的截止时间重设清理定时器，截止时间未变时不重设
+func (l *Layer) schedule(now time.Time) {
+	if l.closed {
+		return
+	}
+	e := l.order.Front()
+	if e == nil {
+		if l.timer != nil {
+			l.timer.Stop()
+			l.armed = time.Time{}
+		}
+		return
+	}
+	deadline := e.Value.(*pending).deadline
+	if deadline.Equal(l.armed) {
+		return
+	}
+	l.armed = deadline
+	if l.timer == nil {
+		l.timer = time.AfterFunc(deadline.Sub(now), l.tick)
+		return
+	}
+	l.timer.Reset(deadline.Sub(now))
+}
+
+func (l *Layer) tick() {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.armed = time.Time{}
+	l.expire(now)
+	l.schedule(now)
+}
+
+// evict 超出内存或消息数上限时从最旧的消息开始丢弃，keep 为当前正在重组的消息
+func (l *Layer) evict(keep *pending) {
+	for e := l.order.Front(); e != nil && l.over(); {
+		p := e.Value.(*pending)
+		e = e.Next()
+		if p == keep {
+			continue
+		}
+		l.remove(p)
+		l.stats.Evicted++
+	}
+
+	// 单条消息已超出上限
+	if l.size > l.opts.MaxPending {
+		l.remove(keep)
+		l.stats.Evicted++
+	}
+}
+
+func (l *Layer) over() bool {
+	return l.size > l.opts.MaxPending || len(l.pending) > l.opts.MaxPendingMessages
+}
+
+func (l *Layer) remove(p *pending) {
+	l.size -= p.cost
+	delete(l.pending, p.id)
+	l.order.Remove(p.elem)
+}
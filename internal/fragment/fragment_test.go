@@ -0,0 +1,136 @@
+package fragment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno/internal/boot"
+)
+
+func fragPacket(id uint32, index, count int, data []byte) []byte {
+	pkt := []byte{kindFrag}
+	pkt = binary.BigEndian.AppendUint32(pkt, id)
+	pkt = binary.BigEndian.AppendUint16(pkt, uint16(index))
+	pkt = binary.BigEndian.AppendUint16(pkt, uint16(count))
+	return append(pkt, data...)
+}
+
+func TestReassemble(t *testing.T) {
+	var wire [][]byte
+	var got [][]byte
+	tx := New(Options{}, 100)
+	tx.Open(nil, boot.LayerIO{Down: func(p []byte) error { wire = append(wire, p); return nil }})
+	rx := New(Options{}, 100)
+	rx.Open(nil, boot.LayerIO{Up: func(m []byte) { got = append(got, m) }})
+
+	msg := bytes.Repeat([]byte("0123456789"), 100)
+	if err := tx.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(wire) < 2 {
+		t.Fatalf("message sent in %d datagrams, want fragments", len(wire))
+	}
+	// 乱序到达
+	for i := len(wire) - 1; i >= 0; i-- {
+		if err := rx.Read(wire[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(got) != 1 || !bytes.Equal(got[0], msg) {
+		t.Fatalf("reassembled %d messages", len(got))
+	}
+	if st := rx.Stats(); st.Pending != 0 || st.PendingSize != 0 || st.Reassembled != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestPendingAccountsForSlots(t *testing.T) {
+	const maxPending = 64 << 10
+	l := New(Options{MaxPending: maxPending, MaxMessageSize: 1 << 20}, 1400)
+	l.Open(nil, boot.LayerIO{Up: func([]byte) {}})
+
+	// 每条消息只发 1 字节分片却声明数百个分片：按槽位计费后受 MaxPending 约束
+	count := (1<<20)/l.chunk - 1
+	for id := uint32(0); id < 10000; id++ {
+		if err := l.Read(fragPacket(id, 0, count, []byte{1})); err != nil {
+			t.Fatal(err)
+		}
+		if st := l.Stats(); st.PendingSize > maxPending {
+			t.Fatalf("PendingSize = %d exceeds MaxPending", st.PendingSize)
+		}
+	}
+	st := l.Stats()
+	if perMsg := entryCost + count*slotCost + 1; st.PendingSize != st.Pending*perMsg {
+		t.Fatalf("PendingSize = %d for %d messages, want %d each", st.PendingSize, st.Pending, perMsg)
+	}
+	if st.Evicted == 0 {
+		t.Fatal("no messages evicted")
+	}
+}
+
+func TestMaxPendingMessages(t *testing.T) {
+	l := New(Options{MaxPendingMessages: 8}, 1400)
+	l.Open(nil, boot.LayerIO{Up: func([]byte) {}})
+
+	for id := uint32(0); id < 100; id++ {
+		if err := l.Read(fragPacket(id, 0, 2, []byte{1})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	st := l.Stats()
+	if st.Pending != 8 || st.Evicted != 92 {
+		t.Fatalf("Pending = %d, Evicted = %d, want 8, 92", st.Pending, st.Evicted)
+	}
+	// 最新的消息仍可完成重组
+	var got []byte
+	l.io.Up = func(m []byte) { got = m }
+	if err := l.Read(fragPacket(99, 1, 2, []byte{2})); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, []byte{1, 2}) {
+		t.Fatalf("reassembled %v", got)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	l := New(Options{Timeout: 20 * time.Millisecond}, 1400)
+	l.Open(nil, boot.LayerIO{Up: func([]byte) {}})
+
+	for id := uint32(0); id < 1000; id++ {
+		_ = l.Read(fragPacket(id, 0, 2, []byte{1}))
+	}
+	time.Sleep(30 * time.Millisecond)
+	_ = l.Read(fragPacket(5000, 0, 2, []byte{1}))
+	st := l.Stats()
+	if st.Pending != 1 || st.Expired != 256 || st.Evicted != 1000-256 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+// TestExpireWithoutTraffic 没有新的分片到达时，不完整消息同样按时清理并计入 Expired
+func TestExpireWithoutTraffic(t *testing.T) {
+	l := New(Options{Timeout: 20 * time.Millisecond}, 1400)
+	l.Open(nil, boot.LayerIO{Up: func([]byte) {}})
+	defer l.Close()
+
+	_ = l.Read(fragPacket(1, 0, 2, []byte{1}))
+	time.Sleep(10 * time.Millisecond)
+	_ = l.Read(fragPacket(2, 0, 2, []byte{1}))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st := l.Stats()
+		if st.Expired == 2 {
+			if st.Pending != 0 || st.PendingSize != 0 {
+				t.Fatalf("stats = %+v", st)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stats = %+v, want 2 expired", st)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
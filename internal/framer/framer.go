@@ -3,10 +3,46 @@ package framer
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/yurazsb/uno/internal/boot"
 )
 
+var (
+	ErrMalformedVarint = errors.New("malformed varint length")
+	ErrFrameTooLarge   = errors.New("frame too large")
+)
+
+// DefaultMaxVarintFrameSize VarintLengthFramer 默认的单帧上限
+const DefaultMaxVarintFrameSize = 4 << 20
+
+// VarintError varint 长度前缀不合法，可通过 errors.Is(err, ErrMalformedVarint) 判断
+type VarintError struct {
+	Prefix   []byte // 不合法的前缀字节
+	Overflow bool   // 取值超出 uint64，否则为超过允许的最大字节数
+}
+
+func (e *VarintError) Error() string {
+	if e.Overflow {
+		return fmt.Sprintf("malformed varint length: % x overflows uint64", e.Prefix)
+	}
+	return fmt.Sprintf("malformed varint length: % x exceeds %d bytes", e.Prefix, len(e.Prefix))
+}
+
+func (e *VarintError) Is(target error) bool { return target == ErrMalformedVarint }
+
+// FrameSizeError 帧长度超过上限，可通过 errors.Is(err, ErrFrameTooLarge) 判断
+type FrameSizeError struct {
+	Size uint64 // 长度前缀声明的帧长度
+	Max  int    // 允许的最大帧长度
+}
+
+func (e *FrameSizeError) Error() string {
+	return fmt.Sprintf("frame too large: %d bytes exceeds %d", e.Size, e.Max)
+}
+
+func (e *FrameSizeError) Is(target error) bool { return target == ErrFrameTooLarge }
+
 // Framer 拆帧 解码器接口
 type Framer func(c boot.Conn, buf []byte) (frames [][]byte, remaining []byte, err error)
 
@@ -238,3 +274,76 @@ var LengthFieldFramer = func(lengthFieldOffset, lengthFieldSize, lengthAdjustmen
 		return
 	}
 }
+
+// VarintLengthFramer 返回一个基于“varint 长度前缀”的帧解码器 (Framer)。
+//
+// 该解码器假设消息包格式为：
+//
+//	[varint length | payload]
+//
+// 长度前缀为无符号 LEB128 varint（与 encoding/binary.Uvarint / protobuf 的 length-delimited 格式一致），
+// 每字节低 7 位为数据、最高位表示后续仍有字节。返回的帧不含长度前缀。
+// 发送端可使用 encoder.VarintLengthEncoder 或 binary.AppendUvarint 添加前缀。
+//
+// 参数说明：
+//   - maxVarintLen:  长度前缀的最大字节数（1 ~ binary.MaxVarintLen64），小于等于 0 或超出时取 binary.MaxVarintLen64。
+//     例如：设置为 4 时帧长度最大为 2^28-1。
+//   - maxFrameSize:  单帧 payload 的最大字节数，为 0 时取 DefaultMaxVarintFrameSize（4MB），小于 0 表示不限制。
+//     在 payload 到达之前即按长度前缀检查，避免对端声明超大帧占用读缓冲。
+//
+// 返回值：
+//   - frames:     已解析出的完整帧（不含长度前缀）。
+//   - remaining:  未能组成完整帧的剩余字节（含不完整的长度前缀）；出错时为从不合法的长度前缀开始的全部字节。
+//   - err:        长度前缀超过 maxVarintLen 字节或超出 uint64 时返回 *VarintError（ErrMalformedVarint），
+//     帧长度超过 maxFrameSize 时返回 *FrameSizeError（ErrFrameTooLarge）。出错后字节流无法再对齐，
+//     应在 OnRead 收到错误时关闭连接。
+//
+// 使用场景：
+//   - protobuf 流（writeDelimitedTo / parseDelimitedFrom）。
+//   - 使用 varint 长度前缀的日志、消息队列协议。
+//
+// 协议示例：
+//
+//	格式: [varint 长度][payload]
+//	配置: VarintLengthFramer(0, 0)
+//	示例: 05  48 65 6C 6C 6F  AC 02  <300 字节>
+//	     len=5 H  e  l  l  o  len=300
+//	解码结果: ["Hello", <300 字节>]
+var VarintLengthFramer = func(maxVarintLen, maxFrameSize int) Framer {
+	if maxVarintLen <= 0 || maxVarintLen > binary.MaxVarintLen64 {
+		maxVarintLen = binary.MaxVarintLen64
+	}
+	if maxFrameSize == 0 {
+		maxFrameSize = DefaultMaxVarintFrameSize
+	}
+	return func(c boot.Conn, buf []byte) (frames [][]byte, remaining []byte, err error) {
+		for len(buf) > 0 {
+			prefix := buf[:min(len(buf), maxVarintLen)]
+			size, n := binary.Uvarint(prefix)
+			if n < 0 {
+				err = &VarintError{Prefix: bytes.Clone(buf[:-n]), Overflow: true}
+				break
+			}
+			if n == 0 {
+				// 长度前缀未完整
+				if len(prefix) == maxVarintLen {
+					err = &VarintError{Prefix: bytes.Clone(prefix)}
+				}
+				break
+			}
+			if maxFrameSize > 0 && size > uint64(maxFrameSize) {
+				err = &FrameSizeError{Size: size, Max: maxFrameSize}
+				break
+			}
+			if uint64(len(buf)-n) < size {
+				break
+			}
+
+			frameEnd := n + int(size)
+			frames = append(frames, buf[n:frameEnd])
+			buf = buf[frameEnd:]
+		}
+		remaining = buf
+		return
+	}
+}
@@ -0,0 +1,193 @@
+package framer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// frame 以 varint 长度前缀编码 payload
+func frame(payload []byte) []byte {
+	return append(binary.AppendUvarint(nil, uint64(len(payload))), payload...)
+}
+
+func repeat(b byte, n int) []byte { return bytes.Repeat([]byte{b}, n) }
+
+func concat(bs ...[]byte) []byte { return bytes.Join(bs, nil) }
+
+func TestVarintLengthFramer(t *testing.T) {
+	hello := []byte("hello")
+	big := repeat('x', 300) // 两字节长度前缀
+
+	tests := []struct {
+		name         string
+		maxVarintLen int
+		maxFrameSize int
+		buf          []byte
+		frames       [][]byte
+		remaining    []byte
+		err          error
+	}{
+		{name: "empty", buf: nil},
+		{name: "empty frame", buf: []byte{0}, frames: [][]byte{{}}},
+		{name: "single", buf: frame(hello), frames: [][]byte{hello}},
+		{name: "multiple", buf: concat(frame(hello), frame(big), frame(nil)), frames: [][]byte{hello, big, {}}},
+		{name: "partial payload", buf: concat(frame(hello), frame(big)[:100]), frames: [][]byte{hello}, remaining: frame(big)[:100]},
+		{name: "partial prefix", buf: concat(frame(hello), frame(big)[:1]), frames: [][]byte{hello}, remaining: frame(big)[:1]},
+		{name: "non-minimal prefix", buf: []byte{0x85, 0x00, 'h', 'e', 'l', 'l', 'o'}, frames: [][]byte{hello}},
+
+		// 9 字节 0xff 后跟 0x02：超出 uint64
+		{name: "overflow", buf: concat(repeat(0xff, 9), []byte{0x02}, hello), remaining: concat(repeat(0xff, 9), []byte{0x02}, hello),
+			err: &VarintError{Prefix: concat(repeat(0xff, 9), []byte{0x02}), Overflow: true}},
+		{name: "overflow after frame", buf: concat(frame(hello), repeat(0xff, 9), []byte{0x02}), frames: [][]byte{hello},
+			remaining: concat(repeat(0xff, 9), []byte{0x02}), err: &VarintError{Prefix: concat(repeat(0xff, 9), []byte{0x02}), Overflow: true}},
+
+		// 超过 10 字节的前缀：10 个延续字节之后仍未结束
+		{name: "over-long", buf: concat(repeat(0x80, 10), []byte{0x01}), remaining: concat(repeat(0x80, 10), []byte{0x01}),
+			err: &VarintError{Prefix: repeat(0x80, 10)}},
+		{name: "over-long incomplete", buf: repeat(0x80, 9), remaining: repeat(0x80, 9)},
+		{name: "over max varint len", maxVarintLen: 3, buf: []byte{0x80, 0x80, 0x80, 0x01}, remaining: []byte{0x80, 0x80, 0x80, 0x01},
+			err: &VarintError{Prefix: []byte{0x80, 0x80, 0x80}}},
+		{name: "within max varint len", maxVarintLen: 3, buf: frame(big), frames: [][]byte{big}},
+		{name: "max varint len incomplete", maxVarintLen: 3, buf: []byte{0x80, 0x80}, remaining: []byte{0x80, 0x80}},
+
+		// 帧长度在 payload 到达之前即按前缀检查
+		{name: "too large", maxFrameSize: 4, buf: frame(hello)[:1], remaining: frame(hello)[:1],
+			err: &FrameSizeError{Size: 5, Max: 4}},
+		{name: "too large after frame", maxFrameSize: 5, buf: concat(frame(hello), frame(big)), frames: [][]byte{hello},
+			remaining: frame(big), err: &FrameSizeError{Size: 300, Max: 5}},
+		{name: "exactly max frame size", maxFrameSize: 5, buf: frame(hello), frames: [][]byte{hello}},
+		{name: "default max frame size", buf: binary.AppendUvarint(nil, DefaultMaxVarintFrameSize+1),
+			remaining: binary.AppendUvarint(nil, DefaultMaxVarintFrameSize+1),
+			err:       &FrameSizeError{Size: DefaultMaxVarintFrameSize + 1, Max: DefaultMaxVarintFrameSize}},
+		{name: "unlimited frame size", maxFrameSize: -1, buf: binary.AppendUvarint(nil, 1<<40),
+			remaining: binary.AppendUvarint(nil, 1<<40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, remaining, err := VarintLengthFramer(tt.maxVarintLen, tt.maxFrameSize)(nil, tt.buf)
+			if len(frames) != len(tt.frames) {
+				t.Fatalf("frames = %q, want %q", frames, tt.frames)
+			}
+			for i := range frames {
+				if !bytes.Equal(frames[i], tt.frames[i]) {
+					t.Fatalf("frame %d = %q, want %q", i, frames[i], tt.frames[i])
+				}
+			}
+			if !bytes.Equal(remaining, tt.remaining) {
+				t.Fatalf("remaining = % x, want % x", remaining, tt.remaining)
+			}
+			checkErr(t, err, tt.err)
+		})
+	}
+}
+
+func checkErr(t *testing.T, err, want error) {
+	t.Helper()
+	switch want := want.(type) {
+	case nil:
+		if err != nil {
+			t.Fatalf("err = %v, want nil", err)
+		}
+	case *VarintError:
+		var got *VarintError
+		if !errors.As(err, &got) || !errors.Is(err, ErrMalformedVarint) {
+			t.Fatalf("err = %v, want *VarintError", err)
+		}
+		if got.Overflow != want.Overflow || !bytes.Equal(got.Prefix, want.Prefix) {
+			t.Fatalf("err = %v, want %v", got, want)
+		}
+	case *FrameSizeError:
+		var got *FrameSizeError
+		if !errors.As(err, &got) || !errors.Is(err, ErrFrameTooLarge) {
+			t.Fatalf("err = %v, want *FrameSizeError", err)
+		}
+		if *got != *want {
+			t.Fatalf("err = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestVarintLengthFramerTruncated 完整编码的每个截断前缀都不产生帧与错误，原样留在 remaining 中
+func TestVarintLengthFramerTruncated(t *testing.T) {
+	for _, size := range []int{0, 1, 127, 128, 300, 16383, 16384, 1 << 20} {
+		enc := frame(repeat('x', size))
+		f := VarintLengthFramer(0, 0)
+		for i := 0; i < len(enc); i++ {
+			frames, remaining, err := f(nil, enc[:i])
+			if len(frames) != 0 || err != nil || !bytes.Equal(remaining, enc[:i]) {
+				t.Fatalf("size %d, %d bytes: frames %d, remaining %d bytes, err %v", size, i, len(frames), len(remaining), err)
+			}
+			if i > 16 && i < len(enc)-16 {
+				i = len(enc) - 16 // payload 中间的截断与其前后等价
+			}
+		}
+		frames, remaining, err := f(nil, enc)
+		if len(frames) != 1 || len(frames[0]) != size || len(remaining) != 0 || err != nil {
+			t.Fatalf("size %d: frames %d, remaining %d bytes, err %v", size, len(frames), len(remaining), err)
+		}
+	}
+}
+
+// run 以 split 处为界分两次输入，模拟读缓冲的拼接，返回全部帧与最后的 remaining、err
+func run(f Framer, data []byte, split int) (frames [][]byte, remaining []byte, err error) {
+	frames, remaining, err = f(nil, data[:split])
+	if err != nil {
+		return
+	}
+	var more [][]byte
+	more, remaining, err = f(nil, concat(remaining, data[split:]))
+	return append(frames, more...), remaining, err
+}
+
+func FuzzVarintLengthFramer(f *testing.F) {
+	f.Add(concat(frame([]byte("hello")), frame(repeat('x', 300))), 3, 0, 0)
+	f.Add(concat(repeat(0xff, 9), []byte{0x02}), 5, 0, 0)
+	f.Add(concat(repeat(0x80, 10), []byte{0x01}), 4, 0, 0)
+	f.Add([]byte{0x80, 0x80, 0x80, 0x01}, 1, 3, 0)
+	f.Add(frame([]byte("hello")), 1, 0, 4)
+
+	f.Fuzz(func(t *testing.T, data []byte, split, maxVarintLen, maxFrameSize int) {
+		fr := VarintLengthFramer(maxVarintLen%16, maxFrameSize%1024)
+		frames, remaining, err := fr(nil, data)
+
+		// remaining 是输入的后缀，帧与前缀恰好覆盖其余字节
+		if !bytes.HasSuffix(data, remaining) {
+			t.Fatalf("remaining % x is not a suffix of % x", remaining, data)
+		}
+		consumed := len(data) - len(remaining)
+		for _, fm := range frames {
+			consumed -= len(fm)
+		}
+		if consumed < len(frames) || consumed > len(frames)*binary.MaxVarintLen64 {
+			t.Fatalf("%d frames consumed %d prefix bytes", len(frames), consumed)
+		}
+		if err != nil {
+			if len(remaining) == 0 {
+				t.Fatalf("err %v with empty remaining", err)
+			}
+			if !errors.Is(err, ErrMalformedVarint) && !errors.Is(err, ErrFrameTooLarge) {
+				t.Fatalf("unexpected err %v", err)
+			}
+		}
+
+		// 分两次输入的结果与一次输入一致
+		split = int(uint(split) % uint(len(data)+1))
+		frames2, remaining2, err2 := run(fr, data, split)
+		if (err == nil) != (err2 == nil) {
+			t.Fatalf("split at %d: err %v, want %v", split, err2, err)
+		}
+		if err == nil && !bytes.Equal(remaining2, remaining) {
+			t.Fatalf("split at %d: remaining % x, want % x", split, remaining2, remaining)
+		}
+		if len(frames2) != len(frames) {
+			t.Fatalf("split at %d: %d frames, want %d", split, len(frames2), len(frames))
+		}
+		for i := range frames {
+			if !bytes.Equal(frames2[i], frames[i]) {
+				t.Fatalf("split at %d: frame %d = % x, want % x", split, i, frames2[i], frames[i])
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"github.com/yurazsb/uno/internal/boot"
 	"github.com/yurazsb/uno/pkg/attrs"
 	"sync/atomic"
@@ -9,6 +10,11 @@ import (
 
 type Handler func(ctx Context, next func())
 
+var (
+	ErrNotRequest = errors.New("handler: message is not a request")
+	ErrReplied    = errors.New("handler: request already replied")
+)
+
 type Context interface {
 	Context() context.Context
 	Cancel()
@@ -18,6 +24,7 @@ type Context interface {
 	Conn() boot.Conn
 	Payload() any
 	SetPayload(payload any)
+	Reply(msg any) <-chan error // 应答当前请求（仅启用 RPC 且消息来自 Call 时有效）
 }
 
 type Chain struct {
@@ -63,6 +70,8 @@ type hContext struct {
 	cancel  context.CancelFunc
 	payload atomic.Value
 	attrs   boot.Attrs
+	reply   func(msg any) <-chan error
+	replied atomic.Bool
 }
 
 func NewContext(conn boot.Conn, payload any) Context {
@@ -77,6 +86,13 @@ func NewContext(conn boot.Conn, payload any) Context {
 	return c
 }
 
+// NewRequestContext 创建 RPC 请求的处理上下文，reply 用于将应答发回调用方
+func NewRequestContext(conn boot.Conn, payload any, reply func(msg any) <-chan error) Context {
+	c := NewContext(conn, payload).(*hContext)
+	c.reply = reply
+	return c
+}
+
 func (c *hContext) Context() context.Context { return c.ctx }
 func (c *hContext) Cancel()                  { c.cancel() }
 func (c *hContext) Done() <-chan struct{}    { return c.ctx.Done() }
@@ -85,3 +101,20 @@ func (c *hContext) Attrs() boot.Attrs        { return c.attrs }
 func (c *hContext) Conn() boot.Conn          { return c.conn }
 func (c *hContext) Payload() any             { return c.payload.Load() }
 func (c *hContext) SetPayload(p any)         { c.payload.Store(p) }
+
+func (c *hContext) Reply(msg any) <-chan error {
+	var err error
+	switch {
+	case c.reply == nil:
+		err = ErrNotRequest
+	case !c.replied.CompareAndSwap(false, true):
+		err = ErrReplied
+	default:
+		return c.reply(msg)
+	}
+
+	done := make(chan error, 1)
+	done <- err
+	close(done)
+	return done
+}
@@ -0,0 +1,51 @@
+package handoff
+
+import (
+	"errors"
+	"time"
+)
+
+// 监听套接字交接（零停机重启）：
+//
+// 旧进程在控制套接字（Unix 域）上等待接管请求；新进程启动时连接控制套接字，
+// 发送监听键（network://address），旧进程通过 SCM_RIGHTS 交出监听套接字的文件描述符，
+// 随后关闭控制套接字并在后台排空现有连接。新进程收到描述符后直接在其上服务，
+// 并接管控制套接字，等待下一次重启。
+
+var (
+	ErrUnsupported = errors.New("handoff: not supported on this platform")
+	ErrMismatch    = errors.New("handoff: listener mismatch")
+	ErrRejected    = errors.New("handoff: rejected by peer")
+)
+
+// Options 监听套接字交接参数
+type Options struct {
+	// Socket 控制套接字路径，新旧进程需一致；同一进程内的多个服务需使用不同路径。
+	Socket string
+
+	// DrainTimeout 交出监听套接字后排空现有连接的最长时间。
+	// 如果为 0，默认 30s。
+	DrainTimeout time.Duration
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.DrainTimeout <= 0 {
+		o.DrainTimeout = 30 * time.Second
+	}
+}
+
+// Key 监听键，用于确认新旧进程交接的是同一个监听地址
+func Key(network, address string) string {
+	return network + "://" + address
+}
+
+// 应答状态
+const (
+	statusOK       byte = 0
+	statusMismatch byte = 1
+	statusError    byte = 2
+)
+
+// maxKey 请求中监听键的最大长度
+const maxKey = 1024
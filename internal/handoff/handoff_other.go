@@ -0,0 +1,26 @@
+//go:build !unix
+
+package handoff
+
+import "os"
+
+// Request 非 Unix 平台不支持 SCM_RIGHTS
+func Request(path, key string) (*os.File, error) {
+	return nil, ErrUnsupported
+}
+
+// Listener 控制套接字
+type Listener struct{}
+
+// Listen 非 Unix 平台不支持 SCM_RIGHTS
+func Listen(path string) (*Listener, error) {
+	return nil, ErrUnsupported
+}
+
+func (l *Listener) Accept(key string, file func() (*os.File, error)) error {
+	return ErrUnsupported
+}
+
+func (l *Listener) Close() error {
+	return nil
+}
@@ -0,0 +1,157 @@
+//go:build unix
+
+package handoff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// timeout 单次交接的读写超时
+const timeout = 5 * time.Second
+
+// Request 连接控制套接字请求接管监听套接字。
+// 控制套接字不存在或无进程监听时返回 (nil, nil)，由调用方自行监听；
+// 成功时等待旧进程释放控制套接字后返回监听套接字的文件。
+func Request(path, key string) (*os.File, error) {
+	c, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	uc := c.(*net.UnixConn)
+	defer uc.Close()
+	_ = uc.SetDeadline(time.Now().Add(timeout))
+
+	if _, err = uc.Write([]byte(key + "\n")); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1+maxKey)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := uc.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch buf[0] {
+	case statusOK:
+	case statusMismatch:
+		return nil, fmt.Errorf("%w: %s", ErrMismatch, buf[1:n])
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrRejected, buf[1:n])
+	}
+
+	fd, err := parseRights(oob[:oobn])
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), key)
+
+	// 旧进程关闭控制套接字后断开连接，此后可安全接管控制套接字路径
+	if _, err = io.Copy(io.Discard, uc); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Listener 控制套接字，等待新进程的接管请求
+type Listener struct {
+	ln *net.UnixListener
+}
+
+// Listen 监听控制套接字，路径上残留的无人监听的套接字文件会被删除
+func Listen(path string) (*Listener, error) {
+	addr := &net.UnixAddr{Name: path, Net: "unix"}
+	ln, err := net.ListenUnix("unix", addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		if c, derr := net.DialTimeout("unix", path, timeout); derr == nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("handoff: control socket %s is in use", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+		ln, err = net.ListenUnix("unix", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{ln: ln}, nil
+}
+
+// Accept 等待一次成功的交接：校验监听键后通过 SCM_RIGHTS 发送 file 返回的描述符，
+// 随后关闭控制套接字并返回 nil。控制套接字被关闭时返回 net.ErrClosed。
+func (l *Listener) Accept(key string, file func() (*os.File, error)) error {
+	for {
+		uc, err := l.ln.AcceptUnix()
+		if err != nil {
+			return err
+		}
+		if l.handle(uc, key, file) {
+			_ = l.ln.Close() // 先释放控制套接字路径，再断开连接通知新进程
+			_ = uc.Close()
+			return nil
+		}
+		_ = uc.Close()
+	}
+}
+
+// Close 关闭控制套接字
+func (l *Listener) Close() error {
+	return l.ln.Close()
+}
+
+func (l *Listener) handle(uc *net.UnixConn, key string, file func() (*os.File, error)) bool {
+	_ = uc.SetDeadline(time.Now().Add(timeout))
+
+	line, err := bufio.NewReaderSize(io.LimitReader(uc, maxKey+1), maxKey+1).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	if got := strings.TrimSuffix(line, "\n"); got != key {
+		_, _ = uc.Write(append([]byte{statusMismatch}, fmt.Sprintf("want %s, got %s", key, got)...))
+		return false
+	}
+
+	f, err := file()
+	if err != nil {
+		_, _ = uc.Write(append([]byte{statusError}, err.Error()...))
+		return false
+	}
+	defer f.Close()
+
+	_, _, err = uc.WriteMsgUnix([]byte{statusOK}, syscall.UnixRights(int(f.Fd())), nil)
+	return err == nil
+}
+
+// parseRights 解析 SCM_RIGHTS 控制消息中的描述符
+func parseRights(oob []byte) (int, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return -1, err
+	}
+	for _, m := range msgs {
+		fds, err := syscall.ParseUnixRights(&m)
+		if err != nil || len(fds) == 0 {
+			continue
+		}
+		for _, extra := range fds[1:] {
+			_ = syscall.Close(extra)
+		}
+		return fds[0], nil
+	}
+	return -1, errors.New("handoff: no file descriptor received")
+}
@@ -0,0 +1,63 @@
+package handshake
+
+import (
+	"errors"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/decoder"
+	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/framer"
+)
+
+// 连接握手：
+//
+// 配置 Handshaker 后，连接建立时先进入握手阶段（OnConnect 之后），期间拆出的帧交由 Handshaker 处理，
+// 不进入 Decoder 与处理器链；Handshaker 可完成版本检查、编解码协商与认证，调用 Accept 后连接就绪，
+// 派发 OnReady 并开始处理普通消息，调用 Reject 或超时则关闭连接。
+
+var (
+	ErrTimeout  = errors.New("handshake timeout")
+	ErrRejected = errors.New("handshake rejected")
+)
+
+// Handshaker 握手处理，服务端与客户端各自配置；Begin 与 Handle 在协程池中按顺序串行调用
+type Handshaker interface {
+	// Begin 进入握手阶段时调用，可在此发送首个握手消息（如客户端的版本与凭据）
+	Begin(h Handshake)
+
+	// Handle 处理握手阶段收到的帧（拆帧后、解码前），调用 Accept / Reject 结束握手
+	Handle(h Handshake, frame []byte)
+}
+
+// Handshake 握手上下文
+type Handshake interface {
+	Conn() boot.Conn
+
+	// Send 经当前 Encoder 发送消息
+	Send(msg any) <-chan error
+
+	// Decode 经当前 Decoder 解码帧
+	Decode(frame []byte) (any, error)
+
+	// SetFramer 替换 Framer，对尚未拆帧的数据生效（启用 RPC 时由信封帧拆分，不可替换）
+	SetFramer(f framer.Framer)
+
+	// SetDecoder 替换 Decoder
+	SetDecoder(d decoder.Decoder)
+
+	// SetEncoder 替换 Encoder，对之后发送的消息生效
+	SetEncoder(e encoder.Encoder)
+
+	// Accept 握手成功，连接就绪；握手阶段已到达的后续数据随即按普通消息处理
+	Accept()
+
+	// Reject 握手失败，以 ErrRejected 包装 reason 触发 OnError 并关闭连接；
+	// 此前经 Send 发送的消息（如拒绝原因）会在关闭前写出
+	Reject(reason error)
+}
+
+// Func 将函数适配为只处理收到的帧的 Handshaker（如仅由服务端校验客户端的首帧）
+type Func func(h Handshake, frame []byte)
+
+func (f Func) Begin(h Handshake) {}
+
+func (f Func) Handle(h Handshake, frame []byte) { f(h, frame) }
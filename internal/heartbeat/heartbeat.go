@@ -0,0 +1,76 @@
+package heartbeat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 应用层心跳：
+//
+// 连接每隔 Interval 发送一次 ping 帧，对端收到后自动回复 pong 帧；发送 ping 后 Timeout 内未收到 pong
+// 或任何入站数据记一次未应答，连续 MaxMisses 次未应答判定对端失效并关闭连接。
+// ping / pong 帧在拆帧之后、解码之前被识别，不会进入 Decoder 与处理器；收到 pong 时测量往返时延。
+
+var ErrTimeout = errors.New("heartbeat timeout")
+
+// TimeoutError 心跳超时错误，可通过 errors.Is(err, ErrTimeout) 判断
+type TimeoutError struct {
+	Misses   int       // 连续未应答次数
+	LastRecv time.Time // 最后一次收到数据的时间，从未收到时为零值
+}
+
+func (e *TimeoutError) Error() string {
+	if e.LastRecv.IsZero() {
+		return fmt.Sprintf("heartbeat timeout: %d pings missed, nothing received", e.Misses)
+	}
+	return fmt.Sprintf("heartbeat timeout: %d pings missed, last received %s ago", e.Misses, time.Since(e.LastRecv).Round(time.Millisecond))
+}
+
+func (e *TimeoutError) Is(target error) bool { return target == ErrTimeout }
+
+// Options 心跳参数，两端的 Ping / Pong 需一致。
+type Options struct {
+	// Interval 发送 ping 的周期。
+	// 如果为 0，默认 30s；小于 0 表示不主动发送，仅应答对端的 ping（如由服务端负责探测的客户端）。
+	Interval time.Duration
+
+	// Timeout 发送 ping 后等待 pong 或任意入站数据的时长，超过即记一次未应答。
+	// 如果为 0，默认 10s；大于 Interval 时按 Interval 处理。
+	Timeout time.Duration
+
+	// MaxMisses 连续未应答次数达到该值时以 ErrTimeout 关闭连接。
+	// 如果为 0，默认 3。
+	MaxMisses int
+
+	// Ping 心跳请求帧，为完整的线上字节：不经过 Encoder 直接写出（启用 RPC 时封装为信封），
+	// 须能被对端 Framer 拆出恰好一帧，且不与业务消息相同。
+	// 如果为空，默认 "ping"（适用于 RawFramer）。
+	Ping []byte
+
+	// Pong 心跳应答帧，要求同 Ping。
+	// 如果为空，默认 "pong"。
+	Pong []byte
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.Interval == 0 {
+		o.Interval = 30 * time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.Interval > 0 && o.Timeout > o.Interval {
+		o.Timeout = o.Interval
+	}
+	if o.MaxMisses <= 0 {
+		o.MaxMisses = 3
+	}
+	if len(o.Ping) == 0 {
+		o.Ping = []byte("ping")
+	}
+	if len(o.Pong) == 0 {
+		o.Pong = []byte("pong")
+	}
+}
@@ -20,6 +20,43 @@ type ConnHook interface {
 	OnMessage(c boot.Conn, msg any)
 }
 
+// ReadyHook 连接就绪的可选回调，由 ConnHook 的实现按需实现
+type ReadyHook interface {
+	// OnReady 连接就绪，此后开始处理普通消息：配置 Handshaker 时在握手成功后调用，否则紧随 OnConnect
+	OnReady(c boot.Conn)
+}
+
+// ReconnectHook 自动重连客户端的可选回调，由 ConnHook 的实现按需实现
+type ReconnectHook interface {
+	// OnReconnecting 第 attempt 次重连拨号前调用，err 为断开或上次拨号失败的原因
+	OnReconnecting(c boot.Conn, attempt int, err error)
+	// OnReconnected 重连成功后调用，c 为新建立的底层连接。
+	// 在此经 c 发送的消息（如重新认证）先于断线期间缓冲的消息发出。
+	OnReconnected(c boot.Conn, attempt int)
+}
+
+// BackpressureHook 发送队列背压的可选回调，由 ConnHook 的实现按需实现
+type BackpressureHook interface {
+	// OnBackpressure 发送队列已满，按 SendQueuePolicy 处理 msg 前调用
+	OnBackpressure(c boot.Conn, msg any)
+	// OnHighWatermark 发送队列字节数升至高水位
+	OnHighWatermark(c boot.Conn)
+	// OnLowWatermark 发送队列字节数回落至低水位
+	OnLowWatermark(c boot.Conn)
+}
+
+// KickHook 重复登录的可选回调，由 ConnHook 的实现按需实现
+type KickHook interface {
+	// OnKick 同一用户在其他连接上绑定，c 即将被关闭；在此发送的消息会在关闭前发出
+	OnKick(c boot.Conn, uid string)
+}
+
+// ShutdownHook 服务端优雅关闭的可选回调，由 ServerHook 的实现按需实现
+type ShutdownHook interface {
+	// OnShutdown 连接开始排空时调用，此时不再处理新消息；在此发送的告别消息会在关闭前发出
+	OnShutdown(c boot.Conn)
+}
+
 type ServerEvent struct {
 	ConnEvent
 }
@@ -39,3 +76,16 @@ func (e *ConnEvent) OnSend(c boot.Conn, msg any)                {}
 func (e *ConnEvent) OnWrite(c boot.Conn, buf []byte, err error) {}
 func (e *ConnEvent) OnRead(c boot.Conn, buf []byte, err error)  {}
 func (e *ConnEvent) OnMessage(c boot.Conn, msg any)             {}
+
+func (e *ConnEvent) OnReady(c boot.Conn) {}
+
+func (e *ConnEvent) OnReconnecting(c boot.Conn, attempt int, err error) {}
+func (e *ConnEvent) OnReconnected(c boot.Conn, attempt int)             {}
+
+func (e *ConnEvent) OnBackpressure(c boot.Conn, msg any) {}
+func (e *ConnEvent) OnHighWatermark(c boot.Conn)         {}
+func (e *ConnEvent) OnLowWatermark(c boot.Conn)          {}
+
+func (e *ConnEvent) OnKick(c boot.Conn, uid string) {}
+
+func (e *ConnEvent) OnShutdown(c boot.Conn) {}
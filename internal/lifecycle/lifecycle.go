@@ -0,0 +1,22 @@
+package lifecycle
+
+import "github.com/yurazsb/uno/pkg/state"
+
+// 连接生命周期：
+//
+// 每个连接持有一个同步执行的 state.Machine，生命周期状态按 Connecting → Handshaking → Ready → Draining → Closing → Closed
+// 只前进不后退，由连接强制迁移（不经过迁移表、Guards 与 Before 钩子）。应用可在同一状态机上注册取值不小于 User 的
+// 自定义状态与迁移（如 Ready → 大厅 → 对局 → 观战），经 Change / Event 迁移；进入 Closing 后不再接受自定义迁移。
+// 状态生效之后的钩子（Apply、After、OnExit、OnEnter）经连接的协程池执行，不阻塞迁移。
+
+const (
+	Connecting  state.State = iota // 已创建，尚未启动
+	Handshaking                    // 握手中（配置 Handshaker 时）
+	Ready                          // 就绪，处理普通消息
+	Draining                       // 排空中，不再处理新消息
+	Closing                        // 关闭中
+	Closed                         // 已关闭
+)
+
+// User 自定义状态的起始值，应用状态取 User 及以上的值以免与生命周期状态冲突
+const User state.State = 100
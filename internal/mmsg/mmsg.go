@@ -0,0 +1,68 @@
+package mmsg
+
+import "net"
+
+// 批量收发数据报：
+//
+// Linux 下的 *net.UDPConn 通过 recvmmsg / sendmmsg 在一次系统调用中收发多个数据报，
+// 其他平台或其他类型的套接字逐个调用 ReadFrom / WriteTo，调用方无需区分。
+
+// Message 一个数据报
+type Message struct {
+	Buf  []byte   // 读：接收缓冲；写：待发送的数据
+	N    int      // 读：实际接收的字节数
+	Addr net.Addr // 读：发送方地址；写：目标地址
+}
+
+// Conn 批量收发接口。ReadBatch 与 WriteBatch 可并发调用，但各自不可并发。
+type Conn interface {
+	// ReadBatch 阻塞直到至少收到一个数据报，返回填充的消息数（n > 0 时即使 err 非 nil 也应先处理数据）；
+	// 遵循套接字的读超时
+	ReadBatch(ms []Message) (int, error)
+
+	// WriteBatch 依次发送消息，返回成功发送的消息数；n < len(ms) 时 err 为 ms[n] 的发送错误
+	WriteBatch(ms []Message) (int, error)
+
+	// Native 是否使用批量系统调用
+	Native() bool
+}
+
+// New 为数据报套接字创建批量收发接口，不支持批量系统调用时退化为逐个收发
+func New(pc net.PacketConn) Conn {
+	if c := newNative(pc); c != nil {
+		return c
+	}
+	return Fallback(pc)
+}
+
+// Fallback 创建逐个收发的实现，用于对比或禁用批量系统调用
+func Fallback(pc net.PacketConn) Conn {
+	return fallback{pc: pc}
+}
+
+type fallback struct {
+	pc net.PacketConn
+}
+
+func (f fallback) ReadBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	n, addr, err := f.pc.ReadFrom(ms[0].Buf)
+	if n > 0 || err == nil {
+		ms[0].N, ms[0].Addr = n, addr
+		return 1, err
+	}
+	return 0, err
+}
+
+func (f fallback) WriteBatch(ms []Message) (int, error) {
+	for i := range ms {
+		if _, err := f.pc.WriteTo(ms[i].Buf, ms[i].Addr); err != nil {
+			return i, err
+		}
+	}
+	return len(ms), nil
+}
+
+func (f fallback) Native() bool { return false }
@@ -0,0 +1,227 @@
+//go:build linux
+
+package mmsg
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// mmsghdr 对应 struct mmsghdr
+type mmsghdr struct {
+	hdr syscall.Msghdr
+	n   uint32
+}
+
+// batch 一组可复用的系统调用参数
+type batch struct {
+	hs   []mmsghdr
+	iovs []syscall.Iovec
+	sas  []syscall.RawSockaddrInet6 // 足以容纳 IPv4 / IPv6 地址
+}
+
+func (b *batch) grow(n int) {
+	if len(b.hs) < n {
+		b.hs = make([]mmsghdr, n)
+		b.iovs = make([]syscall.Iovec, n)
+		b.sas = make([]syscall.RawSockaddrInet6, n)
+	}
+}
+
+type native struct {
+	rc syscall.RawConn
+	v6 bool // 套接字协议族为 AF_INET6（含双栈），IPv4 目标需编码为映射地址
+	r  batch
+	w  batch
+}
+
+func newNative(pc net.PacketConn) Conn {
+	uc, ok := pc.(*net.UDPConn)
+	if !ok {
+		return nil
+	}
+	rc, err := uc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	domain := -1
+	if err = rc.Control(func(fd uintptr) {
+		domain, _ = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_DOMAIN)
+	}); err != nil || (domain != syscall.AF_INET && domain != syscall.AF_INET6) {
+		return nil
+	}
+	return &native{rc: rc, v6: domain == syscall.AF_INET6}
+}
+
+func (c *native) Native() bool { return true }
+
+func (c *native) ReadBatch(ms []Message) (int, error) {
+	if len(ms) == 0 {
+		return 0, nil
+	}
+	b := &c.r
+	b.grow(len(ms))
+	for i := range ms {
+		b.iovs[i] = syscall.Iovec{}
+		if len(ms[i].Buf) > 0 {
+			b.iovs[i].Base = &ms[i].Buf[0]
+			b.iovs[i].SetLen(len(ms[i].Buf))
+		}
+		b.hs[i] = mmsghdr{hdr: syscall.Msghdr{
+			Name:    (*byte)(unsafe.Pointer(&b.sas[i])),
+			Namelen: syscall.SizeofSockaddrInet6,
+			Iov:     &b.iovs[i],
+			Iovlen:  1,
+		}}
+	}
+
+	var got int
+	var errno syscall.Errno
+	err := c.rc.Read(func(fd uintptr) bool {
+		for {
+			r, _, e := syscall.Syscall6(syscall.SYS_RECVMMSG, fd, uintptr(unsafe.Pointer(&b.hs[0])), uintptr(len(ms)), syscall.MSG_DONTWAIT, 0, 0)
+			switch e {
+			case syscall.EINTR:
+				continue
+			case syscall.EAGAIN:
+				return false // 等待可读
+			}
+			got, errno = int(r), e
+			return true
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errno != 0 {
+		return 0, os.NewSyscallError("recvmmsg", errno)
+	}
+
+	for i := 0; i < got; i++ {
+		ms[i].N = int(b.hs[i].n)
+		ms[i].Addr = parseAddr(&b.sas[i])
+	}
+	return got, nil
+}
+
+func (c *native) WriteBatch(ms []Message) (int, error) {
+	b := &c.w
+	b.grow(len(ms))
+
+	// 编码目标地址，无法编码的消息截断批次并返回其错误
+	n := len(ms)
+	var encErr error
+	for i := range ms {
+		namelen, err := c.encodeAddr(&b.sas[i], ms[i].Addr)
+		if err != nil {
+			n, encErr = i, err
+			break
+		}
+		b.iovs[i] = syscall.Iovec{}
+		if len(ms[i].Buf) > 0 {
+			b.iovs[i].Base = &ms[i].Buf[0]
+			b.iovs[i].SetLen(len(ms[i].Buf))
+		}
+		b.hs[i] = mmsghdr{hdr: syscall.Msghdr{
+			Name:    (*byte)(unsafe.Pointer(&b.sas[i])),
+			Namelen: namelen,
+			Iov:     &b.iovs[i],
+			Iovlen:  1,
+		}}
+	}
+
+	sent := 0
+	for sent < n {
+		var k int
+		var errno syscall.Errno
+		err := c.rc.Write(func(fd uintptr) bool {
+			for {
+				r, _, e := syscall.Syscall6(sysSENDMMSG, fd, uintptr(unsafe.Pointer(&b.hs[sent])), uintptr(n-sent), syscall.MSG_DONTWAIT, 0, 0)
+				switch e {
+				case syscall.EINTR:
+					continue
+				case syscall.EAGAIN:
+					return false // 等待可写
+				}
+				k, errno = int(r), e
+				return true
+			}
+		})
+		if err != nil {
+			return sent, err
+		}
+		if errno != 0 {
+			// 首条消息即失败，后续消息由调用方重试
+			return sent, os.NewSyscallError("sendmmsg", errno)
+		}
+		sent += k
+	}
+	if encErr != nil {
+		return n, encErr
+	}
+	return n, nil
+}
+
+// encodeAddr 按套接字协议族编码目标地址，返回地址长度
+func (c *native) encodeAddr(sa *syscall.RawSockaddrInet6, addr net.Addr) (uint32, error) {
+	ua, ok := addr.(*net.UDPAddr)
+	if !ok || ua == nil {
+		return 0, &net.AddrError{Err: "not a UDP address", Addr: addrString(addr)}
+	}
+	ip, ok := netip.AddrFromSlice(ua.IP)
+	if !ok {
+		return 0, &net.AddrError{Err: "invalid IP", Addr: ua.String()}
+	}
+
+	if !c.v6 {
+		if !ip.Unmap().Is4() {
+			return 0, &net.AddrError{Err: "non-IPv4 address on IPv4 socket", Addr: ua.String()}
+		}
+		sa4 := (*syscall.RawSockaddrInet4)(unsafe.Pointer(sa))
+		*sa4 = syscall.RawSockaddrInet4{Family: syscall.AF_INET, Addr: ip.Unmap().As4()}
+		port := (*[2]byte)(unsafe.Pointer(&sa4.Port))
+		port[0], port[1] = byte(ua.Port>>8), byte(ua.Port)
+		return syscall.SizeofSockaddrInet4, nil
+	}
+
+	*sa = syscall.RawSockaddrInet6{Family: syscall.AF_INET6, Addr: ip.As16()}
+	if ua.Zone != "" {
+		if ifi, err := net.InterfaceByName(ua.Zone); err == nil {
+			sa.Scope_id = uint32(ifi.Index)
+		} else if id, err := strconv.Atoi(ua.Zone); err == nil {
+			sa.Scope_id = uint32(id)
+		}
+	}
+	port := (*[2]byte)(unsafe.Pointer(&sa.Port))
+	port[0], port[1] = byte(ua.Port>>8), byte(ua.Port)
+	return syscall.SizeofSockaddrInet6, nil
+}
+
+// parseAddr 解析内核填充的发送方地址
+func parseAddr(sa *syscall.RawSockaddrInet6) net.Addr {
+	switch sa.Family {
+	case syscall.AF_INET:
+		sa4 := (*syscall.RawSockaddrInet4)(unsafe.Pointer(sa))
+		p := (*[2]byte)(unsafe.Pointer(&sa4.Port))
+		return &net.UDPAddr{IP: net.IPv4(sa4.Addr[0], sa4.Addr[1], sa4.Addr[2], sa4.Addr[3]), Port: int(p[0])<<8 | int(p[1])}
+	case syscall.AF_INET6:
+		p := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		ua := &net.UDPAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: int(p[0])<<8 | int(p[1])}
+		if sa.Scope_id != 0 {
+			ua.Zone = strconv.Itoa(int(sa.Scope_id))
+		}
+		return ua
+	}
+	return nil
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return "<nil>"
+	}
+	return addr.String()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package mmsg
+
+import "net"
+
+// newNative 非 Linux 平台不支持 recvmmsg / sendmmsg
+func newNative(pc net.PacketConn) Conn {
+	return nil
+}
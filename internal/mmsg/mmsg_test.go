@@ -0,0 +1,154 @@
+package mmsg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	benchBatch = 64
+	benchSize  = 64
+)
+
+// impls 逐个收发与批量系统调用两条路径
+var impls = []struct {
+	name   string
+	native bool
+	open   func(net.PacketConn) Conn
+}{
+	{"Fallback", false, Fallback},
+	{"Native", true, New},
+}
+
+func listen(tb testing.TB) net.PacketConn {
+	tb.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = pc.Close() })
+	return pc
+}
+
+// open 按 impl 创建批量收发接口，当前平台不支持批量系统调用时跳过
+func open(tb testing.TB, pc net.PacketConn, native bool, fn func(net.PacketConn) Conn) Conn {
+	tb.Helper()
+	c := fn(pc)
+	if c.Native() != native {
+		tb.Skip("recvmmsg / sendmmsg not supported on this platform")
+	}
+	return c
+}
+
+func messages(n, size int, addr net.Addr) []Message {
+	ms := make([]Message, n)
+	for i := range ms {
+		ms[i] = Message{Buf: make([]byte, size), Addr: addr}
+	}
+	return ms
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, impl := range impls {
+		t.Run(impl.name, func(t *testing.T) {
+			rx, tx := listen(t), listen(t)
+			r, w := open(t, rx, impl.native, impl.open), open(t, tx, impl.native, impl.open)
+
+			out := make([]Message, 8)
+			for i := range out {
+				out[i] = Message{Buf: []byte(fmt.Sprintf("datagram-%d", i)), Addr: rx.LocalAddr()}
+			}
+			if n, err := w.WriteBatch(out); n != len(out) || err != nil {
+				t.Fatalf("WriteBatch = %d, %v", n, err)
+			}
+
+			in := messages(len(out), 2048, nil)
+			_ = rx.SetReadDeadline(time.Now().Add(5 * time.Second))
+			for got := 0; got < len(out); {
+				n, err := r.ReadBatch(in[:len(out)-got])
+				for i := 0; i < n; i++ {
+					m := in[i]
+					if want := out[got+i].Buf; !bytes.Equal(m.Buf[:m.N], want) {
+						t.Fatalf("datagram %d = %q, want %q", got+i, m.Buf[:m.N], want)
+					}
+					if m.Addr.String() != tx.LocalAddr().String() {
+						t.Fatalf("datagram %d from %v, want %v", got+i, m.Addr, tx.LocalAddr())
+					}
+				}
+				got += n
+				if err != nil {
+					t.Fatalf("ReadBatch after %d datagrams: %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSendBatch 每次操作发送一个数据报，接收方不读取
+func BenchmarkSendBatch(b *testing.B) {
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			sink := listen(b)
+			c := open(b, listen(b), impl.native, impl.open)
+			ms := messages(benchBatch, benchSize, sink.LocalAddr())
+
+			b.SetBytes(benchSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for sent := 0; sent < b.N; {
+				batch := ms[:min(len(ms), b.N-sent)]
+				n, err := c.WriteBatch(batch)
+				if err != nil {
+					n++ // 跳过发送失败的数据报（接收缓冲已满）
+				}
+				sent += n
+			}
+		})
+	}
+}
+
+// BenchmarkRecvBatch 每次操作接收一个数据报，发送方以批量方式持续灌入
+func BenchmarkRecvBatch(b *testing.B) {
+	for _, impl := range impls {
+		b.Run(impl.name, func(b *testing.B) {
+			pc := listen(b)
+			c := open(b, pc, impl.native, impl.open)
+			ms := messages(benchBatch, 2048, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			for i := 0; i < 2; i++ {
+				tx := New(listen(b))
+				out := messages(benchBatch, benchSize, pc.LocalAddr())
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for ctx.Err() == nil {
+						_, _ = tx.WriteBatch(out)
+					}
+				}()
+			}
+			defer func() {
+				cancel()
+				wg.Wait()
+			}()
+
+			b.SetBytes(benchSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			_ = pc.SetReadDeadline(time.Now().Add(time.Minute))
+			for recv := 0; recv < b.N; {
+				n, err := c.ReadBatch(ms[:min(len(ms), b.N-recv)])
+				if err != nil && n == 0 {
+					b.Fatal(err)
+				}
+				recv += n
+			}
+		})
+	}
+}
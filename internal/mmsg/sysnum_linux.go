@@ -0,0 +1,7 @@
+//go:build linux && !amd64 && !386
+
+package mmsg
+
+import "syscall"
+
+const sysSENDMMSG = syscall.SYS_SENDMMSG
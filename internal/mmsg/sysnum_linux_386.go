@@ -0,0 +1,4 @@
+package mmsg
+
+// sysSENDMMSG syscall 包在该平台上未导出 SYS_SENDMMSG
+const sysSENDMMSG = 345
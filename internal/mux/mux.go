@@ -0,0 +1,385 @@
+package mux
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/decoder"
+	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/handler"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/pkg/pool"
+	"net"
+	"sync"
+)
+
+// Name 处理层名称
+const Name = "mux"
+
+// 帧格式（类 yamux）：
+//
+//	[version 1][type 1][flags 2][streamID 4][length 4][payload]
+//
+// 数据帧 length 为负载长度；窗口更新帧无负载，length 为窗口增量。
+// 流 0 承载连接自身的消息，不受流控限制。
+const (
+	version    byte = 0
+	headerSize      = 12
+
+	typeData   byte = 0
+	typeWindow byte = 1
+
+	flagSYN uint16 = 1 << 0 // 打开流，数据帧负载为流标签
+	flagACK uint16 = 1 << 1 // 确认打开
+	flagFIN uint16 = 1 << 2 // 关闭流
+	flagRST uint16 = 1 << 3 // 重置流
+
+	initialWindow = 256 << 10
+	maxPayload    = 16 << 20
+)
+
+var (
+	ErrRefused       = errors.New("mux: stream refused")
+	ErrReset         = errors.New("mux: stream reset")
+	ErrTooManyStream = errors.New("mux: too many streams")
+	ErrProtocol      = errors.New("mux: protocol error")
+	ErrUnsupported   = errors.New("mux: not supported on stream")
+)
+
+// Options 多路复用参数，服务端与客户端需同时开启
+type Options struct {
+	// Window 单个流的接收窗口（字节），消息处理完成后归还。
+	// 如果小于 256KB，默认 256KB。
+	Window int
+
+	// MaxFrameSize 单个数据帧的最大负载，大消息会被拆分，避免阻塞其他流。
+	// 如果为 0，默认 16KB。
+	MaxFrameSize int
+
+	// MaxStreams 单连接最大并发流数。
+	// 如果为 0，默认 1024。
+	MaxStreams int
+
+	// Accept 对端打开流时调用，返回该流的配置；返回错误时拒绝。
+	// 如果为 nil，接受所有流并沿用连接的配置。
+	Accept func(c boot.Conn, label string) (StreamConfig, error)
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.Window < initialWindow {
+		o.Window = initialWindow
+	}
+	if o.MaxFrameSize <= 0 {
+		o.MaxFrameSize = 16 << 10
+	}
+	if o.MaxStreams <= 0 {
+		o.MaxStreams = 1024
+	}
+}
+
+// StreamConfig 流的处理配置，未设置项沿用连接的配置
+type StreamConfig struct {
+	Framer   framer.Framer
+	Decoder  decoder.Decoder
+	Encoder  encoder.Encoder
+	Handlers []handler.Handler
+	Hook     hook.ConnHook
+}
+
+// Defaults 连接的处理配置，作为流的默认值
+type Defaults struct {
+	StreamConfig
+	Pool    boot.Pool
+	Ordered bool
+	Logger  boot.Logger
+}
+
+// SessionOf 获取连接的多路复用会话，未启用时返回 false
+func SessionOf(c boot.Conn) (*Session, bool) {
+	lc, ok := c.(interface {
+		Layer(name string) (boot.Layer, bool)
+	})
+	if !ok {
+		return nil, false
+	}
+	l, ok := lc.Layer(Name)
+	if !ok {
+		return nil, false
+	}
+	return l.(*Session), true
+}
+
+// Session 单连接的多路复用层
+type Session struct {
+	opts Options
+	def  Defaults
+
+	conn   boot.Conn
+	io     boot.LayerIO
+	client bool // 本端使用奇数流 ID
+
+	mu      sync.Mutex
+	streams map[uint32]*Stream
+	nextID  uint32
+	closed  bool
+
+	rbuf   []byte
+	broken bool // 出现协议错误，不再解析
+}
+
+// New 创建多路复用层
+func New(opts Options, def Defaults) *Session {
+	opts.WithDefault()
+	return &Session{
+		opts:    opts,
+		def:     def,
+		streams: make(map[uint32]*Stream),
+	}
+}
+
+func (s *Session) Name() string { return Name }
+
+// Open 客户端使用奇数流 ID，服务端使用偶数流 ID，避免双方同时打开时冲突
+func (s *Session) Open(c boot.Conn, io boot.LayerIO) {
+	s.conn = c
+	s.io = io
+	s.nextID = 2
+	if cc, ok := c.(interface{ IsClient() bool }); ok && cc.IsClient() {
+		s.client = true
+		s.nextID = 1
+	}
+}
+
+// Close 连接关闭时终止所有流
+func (s *Session) Close() {
+	s.mu.Lock()
+	s.closed = true
+	streams := make([]*Stream, 0, len(s.streams))
+	for _, st := range s.streams {
+		streams = append(streams, st)
+	}
+	s.mu.Unlock()
+
+	for _, st := range streams {
+		st.terminate(net.ErrClosed)
+	}
+}
+
+// Conn 返回底层连接
+func (s *Session) Conn() boot.Conn { return s.conn }
+
+// NumStreams 当前流数
+func (s *Session) NumStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// OpenStream 打开一个流并等待对端确认，label 供对端选择流配置
+func (s *Session) OpenStream(ctx context.Context, label string, cfg StreamConfig) (*Stream, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	if len(s.streams) >= s.opts.MaxStreams {
+		s.mu.Unlock()
+		return nil, ErrTooManyStream
+	}
+	id := s.nextID
+	s.nextID += 2
+	st := newStream(s, id, label, cfg)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	if err := s.writeFrame(ctx, typeData, flagSYN, id, []byte(label)); err != nil {
+		s.remove(id)
+		return nil, err
+	}
+
+	select {
+	case <-st.ready:
+		if st.err != nil {
+			return nil, st.err
+		}
+		return st, nil
+	case <-ctx.Done():
+		st.Reset()
+		return nil, ctx.Err()
+	}
+}
+
+// Write 连接自身的消息经流 0 发送，由连接的写协程调用
+func (s *Session) Write(buf []byte) error {
+	for len(buf) > 0 {
+		n := min(len(buf), s.opts.MaxFrameSize)
+		if err := s.io.Down(frame(typeData, 0, 0, n, buf[:n])); err != nil {
+			return err
+		}
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// Read 解析帧并分发到各流
+func (s *Session) Read(chunk []byte) error {
+	if s.broken {
+		return nil
+	}
+	s.rbuf = append(s.rbuf, chunk...)
+
+	buf := s.rbuf
+parse:
+	for len(buf) >= headerSize {
+		typ := buf[1]
+		flags := binary.BigEndian.Uint16(buf[2:])
+		id := binary.BigEndian.Uint32(buf[4:])
+		length := int(binary.BigEndian.Uint32(buf[8:]))
+		if buf[0] != version || typ > typeWindow || (typ == typeData && length > maxPayload) {
+			s.broken = true
+			s.io.Fail(fmt.Errorf("%w: bad frame header", ErrProtocol))
+			return nil
+		}
+
+		// 负载在流内处理时同步拷贝，可直接引用 rbuf
+		var payload []byte
+		if typ == typeData {
+			if len(buf) < headerSize+length {
+				break parse
+			}
+			payload = buf[headerSize : headerSize+length]
+			buf = buf[headerSize+length:]
+		} else {
+			buf = buf[headerSize:]
+		}
+
+		if err := s.handle(typ, flags, id, length, payload); err != nil {
+			s.broken = true
+			s.io.Fail(err)
+			return nil
+		}
+	}
+
+	s.rbuf = append(s.rbuf[:0], buf...)
+	return nil
+}
+
+// handle 处理单个帧，返回的错误不可恢复
+func (s *Session) handle(typ byte, flags uint16, id uint32, length int, payload []byte) error {
+	if id == 0 {
+		if typ == typeData && len(payload) > 0 {
+			s.io.Up(payload)
+		}
+		return nil
+	}
+
+	if flags&flagSYN != 0 {
+		// 对端只能使用与本端奇偶性相反的流 ID
+		if (id%2 == 1) == s.client {
+			return fmt.Errorf("%w: stream %d opened with local parity", ErrProtocol, id)
+		}
+		s.accept(id, string(payload))
+		return nil
+	}
+
+	s.mu.Lock()
+	st, ok := s.streams[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil // 已关闭的流
+	}
+
+	switch {
+	case flags&flagRST != 0:
+		if st.IsActive() {
+			st.dispatchError(ErrReset)
+		}
+		st.terminate(ErrReset)
+	case typ == typeWindow:
+		if flags&flagACK != 0 {
+			st.establish()
+		}
+		st.grant(length)
+	default:
+		if len(payload) > 0 {
+			st.recv(payload)
+		}
+		if flags&flagFIN != 0 {
+			st.finish()
+		}
+	}
+	return nil
+}
+
+// accept 处理对端打开流的请求
+func (s *Session) accept(id uint32, label string) {
+	cfg := StreamConfig{}
+	if s.opts.Accept != nil {
+		var err error
+		if cfg, err = s.opts.Accept(s.conn, label); err != nil {
+			s.def.Logger.Debug("conn %s refuse stream %d (%s): %v", s.conn.ID(), id, label, err)
+			s.postFrame(typeWindow, flagRST, id, 0)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	_, dup := s.streams[id]
+	if s.closed || dup || len(s.streams) >= s.opts.MaxStreams {
+		s.mu.Unlock()
+		s.postFrame(typeWindow, flagRST, id, 0)
+		return
+	}
+	st := newStream(s, id, label, cfg)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	s.postFrame(typeWindow, flagACK, id, s.opts.Window-initialWindow)
+	st.establish()
+}
+
+func (s *Session) remove(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+// frame 组装帧，窗口更新帧的 length 为窗口增量
+func frame(typ byte, flags uint16, id uint32, length int, payload []byte) []byte {
+	b := make([]byte, headerSize, headerSize+len(payload))
+	b[0] = version
+	b[1] = typ
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint32(b[4:], id)
+	binary.BigEndian.PutUint32(b[8:], uint32(length))
+	return append(b, payload...)
+}
+
+// writeFrame 经连接的发送队列写出帧，阻塞至写出完成。流的帧与连接自身的消息共享队列容量与背压
+func (s *Session) writeFrame(ctx context.Context, typ byte, flags uint16, id uint32, payload []byte) error {
+	done := make(chan error, 1)
+	if err := s.io.Queue(ctx, frame(typ, flags, id, len(payload), payload), done); err != nil {
+		return err
+	}
+	return <-done
+}
+
+// postFrame 经连接的发送队列写出控制帧，不等待写出完成。用于读协程与消息处理任务，
+// 写出失败时连接随之关闭，无需单独处理
+func (s *Session) postFrame(typ byte, flags uint16, id uint32, length int) {
+	_ = s.io.Queue(s.conn.Context(), frame(typ, flags, id, length, nil), nil)
+}
+
+// executor 为流创建执行器，有序模式下每个流独立串行
+func (s *Session) executor() boot.Pool {
+	if !s.def.Ordered {
+		return s.def.Pool
+	}
+	return pool.NewSerialExecutor(s.def.Pool, func(r any) {
+		s.def.Logger.Error("conn %s stream task panic: %v", s.conn.ID(), r)
+	})
+}
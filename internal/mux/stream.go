@@ -0,0 +1,443 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/decoder"
+	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/framer"
+	"github.com/yurazsb/uno/internal/handler"
+	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/pkg/attrs"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// sendQueueSize 单个流的发送队列容量
+const sendQueueSize = 1024
+
+// sendItem 待发送的消息
+type sendItem struct {
+	buf  []byte
+	done chan error
+}
+
+// Stream 连接上的逻辑流，拥有独立的 Framer / Decoder / Handler 链与流控窗口，可作为 boot.Conn 使用。
+// 回调中的连接参数为流本身；流不派发 OnRead / OnWrite / OnTick / OnIdle。
+type Stream struct {
+	s          *Session
+	id         uint32
+	label      string
+	attributes boot.Attrs
+
+	framer  framer.Framer
+	decoder decoder.Decoder
+	encoder encoder.Encoder
+	chain   *handler.Chain
+	hook    hook.ConnHook
+	pool    boot.Pool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	ready     chan struct{} // 建立完成或被拒绝
+	readyOnce sync.Once
+	err       error // 建立失败原因
+	active    atomic.Bool
+
+	sm      sync.RWMutex
+	stopped bool
+	sendCh  chan *sendItem
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	termOnce  sync.Once
+
+	wm       sync.Mutex
+	window   int           // 发送窗口
+	windowCh chan struct{} // 发送窗口增加
+
+	rm      sync.Mutex
+	readBuf []byte
+
+	cm         sync.Mutex
+	recvWindow int // 对端剩余可发送的字节数
+	credit     int // 待归还给对端的窗口
+}
+
+func newStream(s *Session, id uint32, label string, cfg StreamConfig) *Stream {
+	def := s.def
+	st := &Stream{
+		s:          s,
+		id:         id,
+		label:      label,
+		attributes: attrs.New[any, any](true),
+		framer:     cfg.Framer,
+		decoder:    cfg.Decoder,
+		encoder:    cfg.Encoder,
+		hook:       cfg.Hook,
+		pool:       s.executor(),
+		ready:      make(chan struct{}),
+		sendCh:     make(chan *sendItem, sendQueueSize),
+		closing:    make(chan struct{}),
+		window:     initialWindow,
+		windowCh:   make(chan struct{}, 1),
+		recvWindow: s.opts.Window,
+	}
+	st.ctx, st.cancel = context.WithCancel(s.conn.Context())
+
+	if st.framer == nil {
+		st.framer = def.Framer
+	}
+	if st.decoder == nil {
+		st.decoder = def.Decoder
+	}
+	if st.encoder == nil {
+		st.encoder = def.Encoder
+	}
+	if st.hook == nil {
+		st.hook = def.Hook
+	}
+
+	handlers := cfg.Handlers
+	if handlers == nil {
+		handlers = def.Handlers
+	}
+	st.chain = handler.NewChain(handlers...)
+	st.chain.Use(func(ctx handler.Context, next func()) {
+		st.hook.OnMessage(ctx.Conn(), ctx.Payload())
+	})
+	return st
+}
+
+// ---- boot.Conn ----
+
+func (st *Stream) ID() string               { return st.s.conn.ID() + "/" + strconv.FormatUint(uint64(st.id), 10) }
+func (st *Stream) Context() context.Context { return st.ctx }
+func (st *Stream) LocalAddr() net.Addr      { return st.s.conn.LocalAddr() }
+func (st *Stream) RemoteAddr() net.Addr     { return st.s.conn.RemoteAddr() }
+func (st *Stream) Attrs() boot.Attrs        { return st.attributes }
+func (st *Stream) IsActive() bool           { return st.active.Load() }
+
+// StreamID 流 ID
+func (st *Stream) StreamID() uint32 { return st.id }
+
+// Label 打开流时指定的标签
+func (st *Stream) Label() string { return st.label }
+
+// Parent 返回承载该流的连接
+func (st *Stream) Parent() boot.Conn { return st.s.conn }
+
+func (st *Stream) Send(msg any) <-chan error {
+	return st.SendContext(st.ctx, msg)
+}
+
+// SendContext 发送消息，发送队列已满时最多阻塞至 ctx 结束；大消息按 MaxFrameSize 分帧并受对端窗口约束
+func (st *Stream) SendContext(ctx context.Context, msg any) <-chan error {
+	done := make(chan error, 1)
+	fail := func(err error) <-chan error {
+		done <- err
+		close(done)
+		return done
+	}
+
+	if !st.IsActive() {
+		return fail(net.ErrClosed)
+	}
+	buf, err := st.encoder(st, msg)
+	if err != nil {
+		return fail(fmt.Errorf("encoder error: %w", err))
+	}
+
+	st.sm.RLock()
+	defer st.sm.RUnlock()
+	if st.stopped {
+		return fail(net.ErrClosed)
+	}
+	select {
+	case st.sendCh <- &sendItem{buf: buf, done: done}:
+	case <-ctx.Done():
+		return fail(ctx.Err())
+	case <-st.ctx.Done():
+		return fail(net.ErrClosed)
+	}
+
+	st.submit(func() { st.hook.OnSend(st, msg) }, nil)
+	return done
+}
+
+// Call 流不支持 RPC
+func (st *Stream) Call(ctx context.Context, msg any) (any, error) {
+	return nil, ErrUnsupported
+}
+
+// Close 发出已入队的消息后关闭流，阻塞直到流结束
+func (st *Stream) Close() {
+	st.closeOnce.Do(func() { close(st.closing) })
+	if !st.IsActive() {
+		st.terminate(nil)
+	}
+	<-st.ctx.Done()
+}
+
+// Reset 立即关闭流并通知对端，未发出的消息返回 ErrReset
+func (st *Stream) Reset() {
+	st.s.postFrame(typeWindow, flagRST, st.id, 0)
+	st.terminate(ErrReset)
+}
+
+// ---- 会话内部调用 ----
+
+// establish 流建立完成，开始收发
+func (st *Stream) establish() {
+	st.readyOnce.Do(func() {
+		st.active.Store(true)
+		close(st.ready)
+		go st.writeLoop()
+		st.submit(func() { st.hook.OnConnect(st) }, nil)
+	})
+}
+
+// grant 对端归还发送窗口
+func (st *Stream) grant(n int) {
+	if n <= 0 {
+		return
+	}
+	st.wm.Lock()
+	st.window += n
+	st.wm.Unlock()
+	select {
+	case st.windowCh <- struct{}{}:
+	default:
+	}
+}
+
+// recv 接收数据并拆帧派发。处于半包中的字节立即归还窗口（避免单帧超过窗口时死锁），
+// 其余字节在对应消息处理完成后归还，使处理缓慢的流对发送方形成背压。
+func (st *Stream) recv(payload []byte) {
+	n := len(payload)
+	st.cm.Lock()
+	over := n > st.recvWindow
+	st.recvWindow -= n
+	st.cm.Unlock()
+	if over {
+		st.dispatchError(fmt.Errorf("%w: stream %d window exceeded", ErrProtocol, st.id))
+		st.Reset()
+		return
+	}
+
+	st.rm.Lock()
+	defer st.rm.Unlock()
+
+	st.readBuf = append(st.readBuf, payload...)
+	frames, rest, err := st.framer(st, st.readBuf)
+	if err != nil {
+		st.dispatchError(fmt.Errorf("framer error: %w", err))
+		st.Reset()
+		return
+	}
+
+	immediate := min(len(rest), n)
+	if len(frames) == 0 {
+		immediate = n
+	}
+	deferred := n - immediate
+
+	for i, frame := range frames {
+		frames[i] = bytes.Clone(frame)
+	}
+	st.readBuf = append(st.readBuf[:0], rest...)
+
+	st.release(immediate)
+	if len(frames) == 0 {
+		return
+	}
+
+	var left atomic.Int32
+	left.Store(int32(len(frames)))
+	done := func() {
+		if left.Add(-1) == 0 {
+			st.release(deferred)
+		}
+	}
+	for _, frame := range frames {
+		fr := frame
+		st.submit(func() {
+			defer done()
+			st.process(fr)
+		}, done)
+	}
+}
+
+// finish 对端关闭流
+func (st *Stream) finish() {
+	st.terminate(nil)
+}
+
+// terminate 结束流：取消上下文、失败未发送的消息并派发 OnClose
+func (st *Stream) terminate(err error) {
+	st.termOnce.Do(func() {
+		established := st.active.Swap(false)
+		st.readyOnce.Do(func() {
+			st.err = err
+			if err == ErrReset {
+				st.err = ErrRefused
+			}
+			close(st.ready)
+		})
+
+		st.cancel()
+		st.s.remove(st.id)
+
+		st.sm.Lock()
+		st.stopped = true
+		st.sm.Unlock()
+		if err == nil {
+			err = net.ErrClosed
+		}
+		st.drain(err)
+
+		if established {
+			st.submit(func() { st.hook.OnClose(st) }, nil)
+		}
+	})
+}
+
+// ---- 内部实现 ----
+
+func (st *Stream) writeLoop() {
+	for {
+		select {
+		case it := <-st.sendCh:
+			st.write(it)
+		case <-st.closing:
+			for flushed := false; !flushed; {
+				select {
+				case it := <-st.sendCh:
+					st.write(it)
+				default:
+					flushed = true
+				}
+			}
+			st.s.postFrame(typeData, flagFIN, st.id, 0)
+			st.terminate(nil)
+			return
+		case <-st.ctx.Done():
+			return
+		}
+	}
+}
+
+// write 按窗口与最大帧长分帧送入连接的发送队列，不等待写出；末帧的写出结果即消息的发送结果
+func (st *Stream) write(it *sendItem) {
+	buf := it.buf
+	var err error
+	for len(buf) > 0 && err == nil {
+		var n int
+		if n, err = st.acquire(min(len(buf), st.s.opts.MaxFrameSize)); err != nil {
+			break
+		}
+		if n == len(buf) {
+			if err = st.s.io.Queue(st.ctx, frame(typeData, 0, st.id, n, buf), it.done); err == nil {
+				return // 由连接的写协程通知发送方
+			}
+			break
+		}
+		err = st.s.io.Queue(st.ctx, frame(typeData, 0, st.id, n, buf[:n]), nil)
+		buf = buf[n:]
+	}
+	if err == nil && st.ctx.Err() != nil {
+		err = net.ErrClosed
+	}
+	it.done <- err
+	close(it.done)
+}
+
+// acquire 等待发送窗口，返回本次可发送的字节数
+func (st *Stream) acquire(want int) (int, error) {
+	for {
+		if st.ctx.Err() != nil {
+			return 0, net.ErrClosed
+		}
+		st.wm.Lock()
+		if st.window > 0 {
+			n := min(want, st.window)
+			st.window -= n
+			st.wm.Unlock()
+			return n, nil
+		}
+		st.wm.Unlock()
+
+		select {
+		case <-st.windowCh:
+		case <-st.ctx.Done():
+		}
+	}
+}
+
+// release 归还接收窗口，累计超过一半窗口时通知对端
+func (st *Stream) release(n int) {
+	if n <= 0 {
+		return
+	}
+	st.cm.Lock()
+	st.recvWindow += n
+	st.credit += n
+	delta := 0
+	if st.credit >= st.s.opts.Window/2 {
+		delta, st.credit = st.credit, 0
+	}
+	st.cm.Unlock()
+
+	if delta > 0 && st.ctx.Err() == nil {
+		st.s.postFrame(typeWindow, 0, st.id, delta)
+	}
+}
+
+// drain 失败所有未发送的消息
+func (st *Stream) drain(err error) {
+	for {
+		select {
+		case it := <-st.sendCh:
+			it.done <- err
+			close(it.done)
+		default:
+			return
+		}
+	}
+}
+
+// process 解码并执行处理器链
+func (st *Stream) process(frame []byte) {
+	msg, err := st.decoder(st, frame)
+	if err != nil {
+		st.dispatchError(fmt.Errorf("decoder error: %w", err))
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			st.dispatchError(fmt.Errorf("handler process panic: %v", r))
+		}
+	}()
+	st.chain.Handler(handler.NewContext(st, msg))
+}
+
+// submit 提交任务，协程池拒绝时调用 rejected
+func (st *Stream) submit(task func(), rejected func()) {
+	if st.pool.Submit(task) {
+		return
+	}
+	st.s.def.Logger.Error("stream %s fail to submit task", st.ID())
+	if rejected != nil {
+		rejected()
+	}
+}
+
+func (st *Stream) dispatchError(err error) {
+	st.submit(func() { st.hook.OnError(st, err) }, nil)
+}
@@ -0,0 +1,69 @@
+package proxyproto
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"time"
+)
+
+// Conn 剥离头部后的连接，RemoteAddr / LocalAddr 报告头部中的客户端与代理地址
+type Conn struct {
+	net.Conn
+	r      *bufio.Reader // 解析头部时预读的数据，读尽后直接读取底层连接
+	header *Header
+}
+
+// Server 解析可信来源连接开头的头部。
+// 非可信来源、或未要求头部且连接不以签名开头（含签名到达前超时、结束）时返回的连接不改变地址；
+// 解析失败时关闭连接。
+func Server(raw net.Conn, opts *Options) (net.Conn, error) {
+	if !opts.Trusts(raw.RemoteAddr()) {
+		return raw, nil
+	}
+
+	_ = raw.SetReadDeadline(time.Now().Add(opts.HeaderTimeout))
+	br := bufio.NewReader(raw)
+	h, err := Read(br)
+	_ = raw.SetReadDeadline(time.Time{})
+
+	if err != nil && (opts.Required || !errors.Is(err, ErrNoHeader)) {
+		_ = raw.Close()
+		return nil, err
+	}
+	return &Conn{Conn: raw, r: br, header: h}, nil
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	if c.r != nil {
+		if c.r.Buffered() > 0 {
+			return c.r.Read(b)
+		}
+		c.r = nil
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.header != nil && c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	if c.header != nil && c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// Header 返回解析到的头部，连接未携带头部时为 nil
+func (c *Conn) Header() *Header {
+	return c.header
+}
+
+// NetConn 返回底层连接
+func (c *Conn) NetConn() net.Conn {
+	return c.Conn
+}
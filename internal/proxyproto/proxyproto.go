@@ -0,0 +1,333 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HAProxy PROXY 协议（v1 文本 / v2 二进制）：
+//
+// 四层负载均衡在转发的连接开头（UDP 为每个数据报开头）写入一个头部，携带客户端的真实地址。
+// 服务端在 Framer 处理任何字节之前解析并剥离头部，此后连接的 RemoteAddr 报告客户端地址。
+// 仅信任来自白名单地址的头部，防止客户端伪造来源。
+
+var (
+	ErrNoHeader = errors.New("proxyproto: no header")
+	ErrInvalid  = errors.New("proxyproto: invalid header")
+)
+
+var (
+	sigV1 = []byte("PROXY ")
+	sigV2 = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// maxV1 v1 头部最大长度（含 CRLF）
+const maxV1 = 107
+
+// Command v2 头部命令
+type Command byte
+
+const (
+	CmdLocal Command = 0 // 负载均衡自身发起的连接（如健康检查），保留连接的真实地址
+	CmdProxy Command = 1 // 代理连接，地址取自头部
+)
+
+// v2 TLV 类型
+const (
+	TypeALPN      byte = 0x01
+	TypeAuthority byte = 0x02
+	TypeCRC32C    byte = 0x03
+	TypeNoop      byte = 0x04
+	TypeUniqueID  byte = 0x05
+	TypeSSL       byte = 0x20
+	TypeNetNS     byte = 0x30
+)
+
+// TLV v2 头部携带的扩展字段
+type TLV struct {
+	Type  byte
+	Value []byte
+}
+
+// Header 解析后的 PROXY 协议头部
+type Header struct {
+	Version     int // 1 或 2
+	Command     Command
+	Source      net.Addr // 客户端地址，LOCAL 命令或未知协议族时为 nil
+	Destination net.Addr // 代理接收连接的地址，LOCAL 命令或未知协议族时为 nil
+	TLVs        []TLV    // 仅 v2
+}
+
+// TLV 查找指定类型的扩展字段
+func (h *Header) TLV(typ byte) ([]byte, bool) {
+	for _, t := range h.TLVs {
+		if t.Type == typ {
+			return t.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Options PROXY 协议参数
+type Options struct {
+	// Trusted 允许发送头部的来源地址段（负载均衡地址），其他来源的连接不解析头部、按原样处理。
+	// 如果为空，信任所有来源，仅适用于端口只对负载均衡开放的部署。
+	Trusted []netip.Prefix
+
+	// Required 可信来源的连接必须携带头部，否则关闭连接（UDP 丢弃数据报）。
+	// 为 false 时缺少头部的连接按原样处理。
+	Required bool
+
+	// HeaderTimeout 等待 TCP 连接头部的最长时间；未要求头部时，超时前未收到完整签名的连接按原样处理。
+	// 如果为 0，默认 5s。
+	HeaderTimeout time.Duration
+}
+
+// WithDefault 补齐未设置项
+func (o *Options) WithDefault() {
+	if o.HeaderTimeout <= 0 {
+		o.HeaderTimeout = 5 * time.Second
+	}
+}
+
+// Trusts 判断来源地址是否允许发送头部
+func (o *Options) Trusts(addr net.Addr) bool {
+	if len(o.Trusted) == 0 {
+		return true
+	}
+	var ip netip.Addr
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip, _ = netip.AddrFromSlice(a.IP)
+	case *net.UDPAddr:
+		ip, _ = netip.AddrFromSlice(a.IP)
+	default:
+		return false
+	}
+	ip = ip.Unmap()
+	for _, p := range o.Trusted {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read 从流中读取并剥离头部。流开头不是 PROXY 签名、或完整签名到达之前读超时或流结束时
+// 返回 ErrNoHeader（后者同时包装读取错误），且不消耗任何字节。
+func Read(br *bufio.Reader) (*Header, error) {
+	first, err := br.Peek(1)
+	if err != nil {
+		return nil, noHeader(err)
+	}
+	switch first[0] {
+	case sigV1[0]:
+		if err = peekSig(br, sigV1); err != nil {
+			return nil, err
+		}
+		return readV1(br)
+	case sigV2[0]:
+		if err = peekSig(br, sigV2); err != nil {
+			return nil, err
+		}
+		return readV2(br)
+	default:
+		return nil, ErrNoHeader
+	}
+}
+
+// peekSig 逐字节比对签名，避免在不足签名长度的普通消息上阻塞
+func peekSig(br *bufio.Reader, sig []byte) error {
+	for i := 2; i <= len(sig); i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return noHeader(err)
+		}
+		if b[i-1] != sig[i-1] {
+			return ErrNoHeader
+		}
+	}
+	return nil
+}
+
+// noHeader 签名不完整时的读超时与流结束视为没有头部（如首条消息短于签名），其他错误原样返回
+func noHeader(err error) error {
+	var ne net.Error
+	if errors.Is(err, io.EOF) || (errors.As(err, &ne) && ne.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrNoHeader, err)
+	}
+	return err
+}
+
+func readV1(br *bufio.Reader) (*Header, error) {
+	var line []byte
+	for len(line) < maxV1 {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: v1 line too long or missing CRLF", ErrInvalid)
+	}
+	return parseV1(string(line[:len(line)-2]))
+}
+
+// parseV1 解析 "PROXY TCP4 src dst sport dport" 或 "PROXY UNKNOWN ..."
+func parseV1(line string) (*Header, error) {
+	f := strings.Split(line, " ")
+	h := &Header{Version: 1, Command: CmdProxy}
+	if len(f) >= 2 && f[1] == "UNKNOWN" {
+		h.Command = CmdLocal
+		return h, nil
+	}
+	if len(f) != 6 || (f[1] != "TCP4" && f[1] != "TCP6") {
+		return nil, fmt.Errorf("%w: v1 %q", ErrInvalid, line)
+	}
+	src, err := v1Addr(f[1], f[2], f[4])
+	if err != nil {
+		return nil, err
+	}
+	dst, err := v1Addr(f[1], f[3], f[5])
+	if err != nil {
+		return nil, err
+	}
+	h.Source, h.Destination = src, dst
+	return h, nil
+}
+
+func v1Addr(family, host, port string) (net.Addr, error) {
+	ip, err := netip.ParseAddr(host)
+	if err != nil || ip.Is4() != (family == "TCP4") {
+		return nil, fmt.Errorf("%w: v1 address %q", ErrInvalid, host)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: v1 port %q", ErrInvalid, port)
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(p))), nil
+}
+
+func readV2(br *bufio.Reader) (*Header, error) {
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(buf[14:16]))
+	buf = append(buf, make([]byte, n)...)
+	if _, err := io.ReadFull(br, buf[16:]); err != nil {
+		return nil, err
+	}
+	h, _, err := ParseV2(buf)
+	return h, err
+}
+
+// ParseV2 解析数据报开头的 v2 头部，返回头部与其长度。
+// 数据不以 v2 签名开头时返回 ErrNoHeader。
+func ParseV2(b []byte) (*Header, int, error) {
+	if !bytes.HasPrefix(b, sigV2) {
+		return nil, 0, ErrNoHeader
+	}
+	if len(b) < 16 {
+		return nil, 0, fmt.Errorf("%w: v2 truncated", ErrInvalid)
+	}
+	if b[12]>>4 != 2 {
+		return nil, 0, fmt.Errorf("%w: v2 version %d", ErrInvalid, b[12]>>4)
+	}
+	cmd := Command(b[12] & 0x0f)
+	if cmd != CmdLocal && cmd != CmdProxy {
+		return nil, 0, fmt.Errorf("%w: v2 command %d", ErrInvalid, cmd)
+	}
+	total := 16 + int(binary.BigEndian.Uint16(b[14:16]))
+	if len(b) < total {
+		return nil, 0, fmt.Errorf("%w: v2 truncated", ErrInvalid)
+	}
+
+	h := &Header{Version: 2, Command: cmd}
+	family, proto := b[13]>>4, b[13]&0x0f
+	body := b[16:total]
+
+	var alen int
+	switch family {
+	case 0x1: // AF_INET
+		alen = 12
+	case 0x2: // AF_INET6
+		alen = 36
+	case 0x3: // AF_UNIX
+		alen = 216
+	}
+	if len(body) < alen {
+		return nil, 0, fmt.Errorf("%w: v2 address block too short", ErrInvalid)
+	}
+	if cmd == CmdProxy {
+		h.Source, h.Destination = v2Addrs(family, proto, body[:alen])
+	}
+
+	tlvs, err := parseTLVs(body[alen:])
+	if err != nil {
+		return nil, 0, err
+	}
+	h.TLVs = tlvs
+	return h, total, nil
+}
+
+func v2Addrs(family, proto byte, b []byte) (src, dst net.Addr) {
+	ipAddr := func(ip []byte, port uint16) net.Addr {
+		a, _ := netip.AddrFromSlice(ip)
+		ap := netip.AddrPortFrom(a, port)
+		if proto == 0x2 { // DGRAM
+			return net.UDPAddrFromAddrPort(ap)
+		}
+		return net.TCPAddrFromAddrPort(ap)
+	}
+	switch family {
+	case 0x1:
+		return ipAddr(b[0:4], binary.BigEndian.Uint16(b[8:10])), ipAddr(b[4:8], binary.BigEndian.Uint16(b[10:12]))
+	case 0x2:
+		return ipAddr(b[0:16], binary.BigEndian.Uint16(b[32:34])), ipAddr(b[16:32], binary.BigEndian.Uint16(b[34:36]))
+	case 0x3:
+		network := "unix"
+		if proto == 0x2 {
+			network = "unixgram"
+		}
+		path := func(p []byte) string {
+			if i := bytes.IndexByte(p, 0); i >= 0 {
+				p = p[:i]
+			}
+			return string(p)
+		}
+		return &net.UnixAddr{Name: path(b[:108]), Net: network}, &net.UnixAddr{Name: path(b[108:216]), Net: network}
+	}
+	return nil, nil
+}
+
+func parseTLVs(b []byte) ([]TLV, error) {
+	var tlvs []TLV
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, fmt.Errorf("%w: v2 TLV truncated", ErrInvalid)
+		}
+		n := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+n {
+			return nil, fmt.Errorf("%w: v2 TLV truncated", ErrInvalid)
+		}
+		if b[0] != TypeNoop {
+			tlvs = append(tlvs, TLV{Type: b[0], Value: append([]byte(nil), b[3:3+n]...)})
+		}
+		b = b[3+n:]
+	}
+	return tlvs, nil
+}
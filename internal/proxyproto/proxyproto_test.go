@@ -0,0 +1,302 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v2 组装 v2 头部：ver_cmd、fam_proto、地址块与 TLV
+func v2(cmd Command, famProto byte, addrs []byte, tlvs ...TLV) []byte {
+	body := append([]byte(nil), addrs...)
+	for _, t := range tlvs {
+		body = append(body, t.Type)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(t.Value)))
+		body = append(body, t.Value...)
+	}
+	b := append([]byte(nil), sigV2...)
+	b = append(b, 0x20|byte(cmd), famProto)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(body)))
+	return append(b, body...)
+}
+
+// inet 组装 IPv4 / IPv6 地址块
+func inet(src, dst string, sport, dport uint16) []byte {
+	b := append(netip.MustParseAddr(src).AsSlice(), netip.MustParseAddr(dst).AsSlice()...)
+	b = binary.BigEndian.AppendUint16(b, sport)
+	return binary.BigEndian.AppendUint16(b, dport)
+}
+
+func unixAddrs(src, dst string) []byte {
+	b := make([]byte, 216)
+	copy(b, src)
+	copy(b[108:], dst)
+	return b
+}
+
+func addrString(a net.Addr) string {
+	if a == nil {
+		return ""
+	}
+	return a.Network() + ":" + a.String()
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		version int
+		cmd     Command
+		src     string
+		dst     string
+		tlvs    []TLV
+		err     error
+	}{
+		{name: "v1 tcp4", in: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n", version: 1, cmd: CmdProxy,
+			src: "tcp:192.0.2.1:56324", dst: "tcp:198.51.100.1:443"},
+		{name: "v1 tcp6", in: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n", version: 1, cmd: CmdProxy,
+			src: "tcp:[2001:db8::1]:56324", dst: "tcp:[2001:db8::2]:443"},
+		{name: "v1 unknown", in: "PROXY UNKNOWN\r\n", version: 1, cmd: CmdLocal},
+		{name: "v1 unknown with addresses", in: "PROXY UNKNOWN ffff:: ffff:: 1 2\r\n", version: 1, cmd: CmdLocal},
+		{name: "v1 family mismatch", in: "PROXY TCP4 2001:db8::1 192.0.2.1 1 2\r\n", err: ErrInvalid},
+		{name: "v1 bad port", in: "PROXY TCP4 192.0.2.1 192.0.2.2 65536 2\r\n", err: ErrInvalid},
+		{name: "v1 bad protocol", in: "PROXY UDP4 192.0.2.1 192.0.2.2 1 2\r\n", err: ErrInvalid},
+		{name: "v1 missing fields", in: "PROXY TCP4 192.0.2.1\r\n", err: ErrInvalid},
+		{name: "v1 missing CRLF", in: "PROXY TCP4 192.0.2.1 192.0.2.2 1 2\n", err: ErrInvalid},
+		{name: "v1 too long", in: "PROXY TCP4 " + strings.Repeat("1", maxV1) + "\r\n", err: ErrInvalid},
+		{name: "v1 truncated", in: "PROXY TCP4 192.0.2.1", err: io.EOF},
+
+		{name: "v2 tcp4", in: string(v2(CmdProxy, 0x11, inet("192.0.2.1", "198.51.100.1", 56324, 443))), version: 2, cmd: CmdProxy,
+			src: "tcp:192.0.2.1:56324", dst: "tcp:198.51.100.1:443"},
+		{name: "v2 udp6", in: string(v2(CmdProxy, 0x22, inet("2001:db8::1", "2001:db8::2", 1, 2))), version: 2, cmd: CmdProxy,
+			src: "udp:[2001:db8::1]:1", dst: "udp:[2001:db8::2]:2"},
+		{name: "v2 unix", in: string(v2(CmdProxy, 0x31, unixAddrs("/tmp/a.sock", "/tmp/b.sock"))), version: 2, cmd: CmdProxy,
+			src: "unix:/tmp/a.sock", dst: "unix:/tmp/b.sock"},
+		{name: "v2 local", in: string(v2(CmdLocal, 0x11, inet("192.0.2.1", "198.51.100.1", 1, 2))), version: 2, cmd: CmdLocal},
+		{name: "v2 local unspec", in: string(v2(CmdLocal, 0x00, nil)), version: 2, cmd: CmdLocal},
+		{name: "v2 tlvs", in: string(v2(CmdProxy, 0x11, inet("192.0.2.1", "198.51.100.1", 1, 2),
+			TLV{Type: TypeALPN, Value: []byte("h2")}, TLV{Type: TypeNoop, Value: []byte{0, 0}}, TLV{Type: TypeAuthority, Value: []byte("example.com")})),
+			version: 2, cmd: CmdProxy, src: "tcp:192.0.2.1:1", dst: "tcp:198.51.100.1:2",
+			tlvs: []TLV{{Type: TypeALPN, Value: []byte("h2")}, {Type: TypeAuthority, Value: []byte("example.com")}}},
+		{name: "v2 truncated body", in: string(v2(CmdProxy, 0x11, inet("192.0.2.1", "198.51.100.1", 1, 2))[:20]), err: io.ErrUnexpectedEOF},
+		{name: "v2 short address block", in: string(v2(CmdProxy, 0x21, inet("192.0.2.1", "198.51.100.1", 1, 2))), err: ErrInvalid},
+		{name: "v2 truncated tlv", in: string(v2(CmdProxy, 0x11, append(inet("192.0.2.1", "198.51.100.1", 1, 2), TypeALPN, 0, 5, 'h'))), err: ErrInvalid},
+		{name: "v2 bad version", in: string(func() []byte {
+			b := v2(CmdProxy, 0x11, inet("192.0.2.1", "198.51.100.1", 1, 2))
+			b[12] = 0x11
+			return b
+		}()), err: ErrInvalid},
+		{name: "v2 bad command", in: string(func() []byte {
+			b := v2(CmdProxy, 0x11, inet("192.0.2.1", "198.51.100.1", 1, 2))
+			b[12] = 0x22
+			return b
+		}()), err: ErrInvalid},
+
+		{name: "no header", in: "GET / HTTP/1.1\r\n", err: ErrNoHeader},
+		{name: "short message starting like v1", in: "PING\r\n", err: ErrNoHeader},
+		{name: "short message starting like v2", in: "\r\nX", err: ErrNoHeader},
+		{name: "eof inside signature", in: "PRO", err: ErrNoHeader},
+		{name: "empty", in: "", err: ErrNoHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const payload = "payload"
+			in := tt.in
+			if tt.err == nil {
+				in += payload
+			}
+			br := bufio.NewReader(strings.NewReader(in))
+			h, err := Read(br)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				if errors.Is(err, ErrNoHeader) {
+					// 未消耗任何字节
+					if rest, _ := io.ReadAll(br); string(rest) != tt.in {
+						t.Fatalf("stream after ErrNoHeader = %q, want %q", rest, tt.in)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Version != tt.version || h.Command != tt.cmd {
+				t.Fatalf("version %d command %d, want %d %d", h.Version, h.Command, tt.version, tt.cmd)
+			}
+			if got := addrString(h.Source); got != tt.src {
+				t.Errorf("source = %s, want %s", got, tt.src)
+			}
+			if got := addrString(h.Destination); got != tt.dst {
+				t.Errorf("destination = %s, want %s", got, tt.dst)
+			}
+			if len(h.TLVs) != len(tt.tlvs) {
+				t.Fatalf("TLVs = %v, want %v", h.TLVs, tt.tlvs)
+			}
+			for i := range tt.tlvs {
+				if h.TLVs[i].Type != tt.tlvs[i].Type || !bytes.Equal(h.TLVs[i].Value, tt.tlvs[i].Value) {
+					t.Fatalf("TLV %d = %v, want %v", i, h.TLVs[i], tt.tlvs[i])
+				}
+			}
+			if rest, _ := io.ReadAll(br); string(rest) != payload {
+				t.Fatalf("stream after header = %q, want %q", rest, payload)
+			}
+		})
+	}
+}
+
+func TestParseV2Datagram(t *testing.T) {
+	hdr := v2(CmdProxy, 0x12, inet("192.0.2.1", "198.51.100.1", 5000, 53), TLV{Type: TypeUniqueID, Value: []byte("id")})
+	h, n, err := ParseV2(append(hdr, "query"...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(hdr) {
+		t.Fatalf("header length = %d, want %d", n, len(hdr))
+	}
+	if got := addrString(h.Source); got != "udp:192.0.2.1:5000" {
+		t.Fatalf("source = %s", got)
+	}
+	if v, ok := h.TLV(TypeUniqueID); !ok || string(v) != "id" {
+		t.Fatalf("TLV(UniqueID) = %q, %v", v, ok)
+	}
+	if _, ok := h.TLV(TypeSSL); ok {
+		t.Fatal("TLV(SSL) found")
+	}
+
+	if _, _, err := ParseV2([]byte("query")); !errors.Is(err, ErrNoHeader) {
+		t.Fatalf("datagram without header: %v", err)
+	}
+	for _, n := range []int{13, 15, len(hdr) - 1} {
+		if _, _, err := ParseV2(hdr[:n]); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("truncated to %d bytes: %v", n, err)
+		}
+	}
+}
+
+func TestTrusts(t *testing.T) {
+	opts := &Options{Trusted: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	tests := []struct {
+		addr net.Addr
+		want bool
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1}, true},
+		{&net.TCPAddr{IP: net.ParseIP("::ffff:10.1.2.3"), Port: 1}, true},
+		{&net.UDPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1}, true},
+		{&net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1}, false},
+		{&net.UnixAddr{Name: "/tmp/x", Net: "unix"}, false},
+	}
+	for _, tt := range tests {
+		if got := opts.Trusts(tt.addr); got != tt.want {
+			t.Errorf("Trusts(%v) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if !(&Options{}).Trusts(&net.UnixAddr{Name: "/tmp/x", Net: "unix"}) {
+		t.Error("empty Trusted does not trust every source")
+	}
+}
+
+// serve 经 net.Pipe 将 send 写入服务端，返回 Server 的结果
+func serve(t *testing.T, opts Options, send string) (net.Conn, error) {
+	t.Helper()
+	opts.WithDefault()
+	server, client := net.Pipe()
+	t.Cleanup(func() { _ = client.Close() })
+	go func() { _, _ = client.Write([]byte(send)) }()
+	return Server(server, &opts)
+}
+
+func readN(t *testing.T, c net.Conn, n int) string {
+	t.Helper()
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+func TestServer(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		c, err := serve(t, Options{}, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nhello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.RemoteAddr().String(); got != "192.0.2.1:56324" {
+			t.Fatalf("RemoteAddr = %s", got)
+		}
+		if got := c.LocalAddr().String(); got != "198.51.100.1:443" {
+			t.Fatalf("LocalAddr = %s", got)
+		}
+		if got := readN(t, c, 5); got != "hello" {
+			t.Fatalf("payload = %q", got)
+		}
+		if c.(*Conn).Header() == nil {
+			t.Fatal("Header() = nil")
+		}
+	})
+
+	t.Run("optional without header", func(t *testing.T) {
+		c, err := serve(t, Options{}, "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.(*Conn).Header() != nil {
+			t.Fatal("Header() != nil")
+		}
+		if got := readN(t, c, 5); got != "hello" {
+			t.Fatalf("payload = %q", got)
+		}
+	})
+
+	// 首条消息短于签名：超时后按无头部处理，已读出的字节保留
+	t.Run("optional with short first message", func(t *testing.T) {
+		for _, msg := range []string{"P", "\r\n"} {
+			start := time.Now()
+			c, err := serve(t, Options{HeaderTimeout: 50 * time.Millisecond}, msg)
+			if err != nil {
+				t.Fatalf("%q: %v", msg, err)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Fatalf("%q: waited %v", msg, d)
+			}
+			if got := readN(t, c, len(msg)); got != msg {
+				t.Fatalf("payload = %q, want %q", got, msg)
+			}
+		}
+	})
+
+	t.Run("required without header", func(t *testing.T) {
+		if _, err := serve(t, Options{Required: true, HeaderTimeout: 50 * time.Millisecond}, "P"); !errors.Is(err, ErrNoHeader) {
+			t.Fatalf("err = %v, want ErrNoHeader", err)
+		}
+		if _, err := serve(t, Options{Required: true}, "hello"); !errors.Is(err, ErrNoHeader) {
+			t.Fatalf("err = %v, want ErrNoHeader", err)
+		}
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		if _, err := serve(t, Options{}, "PROXY TCP4 bogus\r\n"); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("err = %v, want ErrInvalid", err)
+		}
+	})
+
+	t.Run("untrusted source", func(t *testing.T) {
+		opts := Options{Trusted: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+		c, err := serve(t, opts, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.(*Conn); ok {
+			t.Fatal("untrusted connection was parsed")
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package reactor
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// 事件循环：
+//
+// 少量事件循环协程通过 epoll 等待大量套接字的可读事件，套接字可读时才在循环协程中调用其处理函数，
+// 读缓冲由循环共享，空闲连接不再占用读协程与读缓冲，也不会被读超时周期性唤醒。
+// 每次通知后注册即暂停（EPOLLONESHOT），处理函数可将数据交给其他协程处理，完成后 Resume 再接收下一次通知，
+// 同一套接字的通知不会并发，数据按序交付。
+// 仅支持 Linux，其他平台 New 返回 ErrUnsupported。
+
+var ErrUnsupported = errors.New("reactor is not supported on this platform")
+
+// Handler 套接字可读（含对端关闭、出错）时在事件循环协程中调用，
+// buf 为该循环共享的读缓冲，返回后即被复用。处理函数不得阻塞，否则会拖慢同一循环上的其他套接字；
+// 调用后注册暂停，须调用 Registration.Resume 才会再次通知
+type Handler func(buf []byte)
+
+// Reactor 一组事件循环，新注册的套接字轮流分配到各循环
+type Reactor struct {
+	loops []*loop
+	next  atomic.Uint32
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// New 创建 n 个事件循环，每个循环持有 bufSize 字节的共享读缓冲（首次可读时分配）
+func New(n, bufSize int) (*Reactor, error) {
+	if n <= 0 {
+		n = 1
+	}
+	r := &Reactor{loops: make([]*loop, 0, n)}
+	for i := 0; i < n; i++ {
+		l, err := newLoop(bufSize)
+		if err != nil {
+			for _, l := range r.loops {
+				l.close()
+			}
+			return nil, err
+		}
+		r.loops = append(r.loops, l)
+	}
+	for _, l := range r.loops {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			l.run()
+		}()
+	}
+	return r, nil
+}
+
+// Register 将套接字交由事件循环监听可读事件。返回的 Registration 须在关闭套接字之前 Close，
+// 否则文件描述符被复用后可能误删其他套接字的注册
+func (r *Reactor) Register(rc syscall.RawConn, h Handler) (*Registration, error) {
+	l := r.loops[int(r.next.Add(1)-1)%len(r.loops)]
+	return l.register(rc, h)
+}
+
+// Close 停止全部事件循环，已注册的套接字不再收到可读事件
+func (r *Reactor) Close() {
+	r.closeOnce.Do(func() {
+		for _, l := range r.loops {
+			l.wakeup()
+		}
+		r.wg.Wait()
+		for _, l := range r.loops {
+			l.close()
+		}
+	})
+}
+
+// Registration 一个套接字在事件循环中的注册
+type Registration struct {
+	l  *loop
+	fd int
+	h  Handler
+}
+
+// Resume 处理完本次通知后重新开启可读通知，取消注册后调用无效
+func (reg *Registration) Resume() {
+	reg.l.resume(reg)
+}
+
+// Close 取消注册，可重复调用
+func (reg *Registration) Close() {
+	reg.l.unregister(reg)
+}
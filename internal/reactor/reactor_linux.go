@@ -0,0 +1,167 @@
+//go:build linux
+
+package reactor
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// events 套接字关注的事件
+const events = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+
+// loop 一个 epoll 事件循环，水平触发且单次通知：处理函数每次只需读取一次，Resume 后未读完的数据继续通知
+type loop struct {
+	epfd int
+	wake [2]int // 用于唤醒 epoll_wait 的管道
+
+	size int
+	buf  []byte // 共享读缓冲，仅由循环协程访问
+
+	mu     sync.RWMutex
+	regs   map[int]*Registration
+	closed bool
+}
+
+func newLoop(size int) (*loop, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("epoll_create1", err)
+	}
+	l := &loop{epfd: epfd, size: size, regs: make(map[int]*Registration)}
+	if err = syscall.Pipe2(l.wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		_ = syscall.Close(epfd)
+		return nil, os.NewSyscallError("pipe2", err)
+	}
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(l.wake[0])}
+	if err = syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, l.wake[0], &ev); err != nil {
+		l.close()
+		return nil, os.NewSyscallError("epoll_ctl", err)
+	}
+	return l, nil
+}
+
+// Read 非阻塞地读取一次套接字：无数据可读时返回 0, nil，对端关闭时返回 io.EOF
+func Read(rc syscall.RawConn, buf []byte) (int, error) {
+	var n int
+	var err error
+	if cerr := rc.Read(func(fd uintptr) bool {
+		for {
+			n, err = syscall.Read(int(fd), buf)
+			if err != syscall.EINTR {
+				return true // 不等待 Go 运行时的网络轮询器
+			}
+		}
+	}); cerr != nil {
+		return 0, cerr
+	}
+	switch {
+	case err == syscall.EAGAIN:
+		return 0, nil
+	case err != nil:
+		return 0, os.NewSyscallError("read", err)
+	case n == 0:
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (l *loop) run() {
+	events := make([]syscall.EpollEvent, 128)
+	for {
+		n, err := syscall.EpollWait(l.epfd, events, -1)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return
+		}
+		for i := 0; i < n; i++ {
+			fd := int(events[i].Fd)
+			if fd == l.wake[0] {
+				return
+			}
+			l.mu.RLock()
+			reg := l.regs[fd]
+			l.mu.RUnlock()
+			if reg == nil {
+				continue // 同一批事件中已取消注册
+			}
+			if l.buf == nil {
+				l.buf = make([]byte, l.size)
+			}
+			reg.h(l.buf)
+		}
+	}
+}
+
+func (l *loop) register(rc syscall.RawConn, h Handler) (*Registration, error) {
+	reg := &Registration{l: l, h: h}
+	var err error
+	cerr := rc.Control(func(fd uintptr) {
+		reg.fd = int(fd)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.closed {
+			err = net.ErrClosed
+			return
+		}
+		// 先登记再加入 epoll，保证首个事件即可找到处理函数
+		l.regs[reg.fd] = reg
+		ev := syscall.EpollEvent{Events: events, Fd: int32(fd)}
+		if err = syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_ADD, reg.fd, &ev); err != nil {
+			delete(l.regs, reg.fd)
+			err = os.NewSyscallError("epoll_ctl", err)
+		}
+	})
+	if cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// resume 重新开启单次通知；文件描述符已被其他注册复用时忽略，避免误改其事件
+func (l *loop) resume(reg *Registration) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed || l.regs[reg.fd] != reg {
+		return
+	}
+	ev := syscall.EpollEvent{Events: events, Fd: int32(reg.fd)}
+	_ = syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_MOD, reg.fd, &ev)
+}
+
+func (l *loop) unregister(reg *Registration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.regs[reg.fd] != reg {
+		return
+	}
+	delete(l.regs, reg.fd)
+	if !l.closed {
+		_ = syscall.EpollCtl(l.epfd, syscall.EPOLL_CTL_DEL, reg.fd, &syscall.EpollEvent{})
+	}
+}
+
+func (l *loop) wakeup() {
+	_, _ = syscall.Write(l.wake[1], []byte{0})
+}
+
+func (l *loop) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	clear(l.regs)
+	_ = syscall.Close(l.wake[0])
+	_ = syscall.Close(l.wake[1])
+	_ = syscall.Close(l.epfd)
+}
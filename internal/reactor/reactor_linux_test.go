@@ -0,0 +1,46 @@
+//go:build linux
+
+package reactor
+
+import (
+	"syscall"
+	"testing"
+)
+
+// rawFD 直接以文件描述符实现 syscall.RawConn
+type rawFD int
+
+func (fd rawFD) Control(f func(uintptr)) error    { f(uintptr(fd)); return nil }
+func (fd rawFD) Read(f func(uintptr) bool) error  { f(uintptr(fd)); return nil }
+func (fd rawFD) Write(f func(uintptr) bool) error { f(uintptr(fd)); return nil }
+
+// TestFDReuse 旧注册在文件描述符被复用后 Close / Resume 不影响新注册
+func TestFDReuse(t *testing.T) {
+	r := newReactor(t, 1)
+	server, rc, _ := pair(t)
+	_, rc2, client := pair(t)
+	old, _ := reader(t, r, rc, true)
+	fd := fdOf(t, rc)
+	old.Close()
+	_ = server.Close()
+
+	// 将另一条连接的套接字复制到旧的文件描述符上，模拟复用
+	if err := syscall.Dup3(fdOf(t, rc2), fd, syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(fd)
+	_, ch := reader(t, r, rawFD(fd), false)
+
+	old.Close()
+	if _, err := client.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	expectRead(t, ch, "a", nil)
+
+	// 新注册处于暂停中，旧注册的 Resume 不得重新开启通知
+	old.Resume()
+	if _, err := client.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, ch)
+}
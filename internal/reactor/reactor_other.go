@@ -0,0 +1,31 @@
+//go:build !linux
+
+package reactor
+
+import "syscall"
+
+// loop 非 Linux 平台不支持 epoll
+type loop struct{}
+
+func newLoop(size int) (*loop, error) {
+	return nil, ErrUnsupported
+}
+
+func (l *loop) run() {}
+
+func (l *loop) register(rc syscall.RawConn, h Handler) (*Registration, error) {
+	return nil, ErrUnsupported
+}
+
+func (l *loop) resume(reg *Registration) {}
+
+func (l *loop) unregister(reg *Registration) {}
+
+func (l *loop) wakeup() {}
+
+func (l *loop) close() {}
+
+// Read 非 Linux 平台不支持
+func Read(rc syscall.RawConn, buf []byte) (int, error) {
+	return 0, ErrUnsupported
+}
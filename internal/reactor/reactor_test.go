@@ -0,0 +1,224 @@
+package reactor
+
+import (
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newReactor 创建 n 个循环的 Reactor，非 Linux 平台校验 ErrUnsupported 后跳过
+func newReactor(t *testing.T, n int) *Reactor {
+	t.Helper()
+	r, err := New(n, 4096)
+	if runtime.GOOS != "linux" {
+		if !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("New on %s: %v, want ErrUnsupported", runtime.GOOS, err)
+		}
+		t.Skip("reactor is not supported on this platform")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+// pair 建立一对 TCP 回环连接，返回服务端连接的 RawConn 与客户端连接
+func pair(t *testing.T) (*net.TCPConn, syscall.RawConn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	rc, err := server.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server.(*net.TCPConn), rc, client
+}
+
+func fdOf(t *testing.T, rc syscall.RawConn) int {
+	t.Helper()
+	var fd int
+	if err := rc.Control(func(f uintptr) { fd = int(f) }); err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+type readResult struct {
+	data string
+	err  error
+}
+
+// reader 注册读取并按 auto 决定是否自动 Resume 的处理函数，读取结果送入返回的通道
+func reader(t *testing.T, r *Reactor, rc syscall.RawConn, auto bool) (*Registration, <-chan readResult) {
+	t.Helper()
+	ch := make(chan readResult, 16)
+	var reg *Registration
+	ready := make(chan struct{})
+	reg, err := r.Register(rc, func(buf []byte) {
+		<-ready
+		n, err := Read(rc, buf)
+		ch <- readResult{string(buf[:n]), err}
+		if auto && err == nil {
+			reg.Resume()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ready)
+	t.Cleanup(reg.Close)
+	return reg, ch
+}
+
+func expectRead(t *testing.T, ch <-chan readResult, want string, wantErr error) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got.data != want || !errors.Is(got.err, wantErr) {
+			t.Fatalf("read %q, %v, want %q, %v", got.data, got.err, want, wantErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no readable event, want %q", want)
+	}
+}
+
+func expectNoEvent(t *testing.T, ch <-chan readResult) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		t.Fatalf("unexpected readable event: %q, %v", got.data, got.err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestReadAndEOF(t *testing.T) {
+	r := newReactor(t, 1)
+	_, rc, client := pair(t)
+	_, ch := reader(t, r, rc, true)
+
+	if _, err := client.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	expectRead(t, ch, "hello", nil)
+	if _, err := client.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	expectRead(t, ch, "world", nil)
+
+	_ = client.Close()
+	expectRead(t, ch, "", io.EOF)
+}
+
+// TestOneShot 每次通知后暂停，Resume 后未读完的数据继续通知
+func TestOneShot(t *testing.T) {
+	r := newReactor(t, 1)
+	_, rc, client := pair(t)
+
+	calls := make(chan struct{}, 16)
+	var reg *Registration
+	ready := make(chan struct{})
+	reg, err := r.Register(rc, func(buf []byte) {
+		<-ready
+		calls <- struct{}{} // 不读取，数据仍可读
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(ready)
+	defer reg.Close()
+
+	if _, err := client.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-calls:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %d not received", i)
+		}
+		select {
+		case <-calls:
+			t.Fatal("notified again before Resume")
+		case <-time.After(50 * time.Millisecond):
+		}
+		reg.Resume()
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	r := newReactor(t, 1)
+	_, rc, client := pair(t)
+	reg, ch := reader(t, r, rc, true)
+
+	if _, err := client.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	expectRead(t, ch, "a", nil)
+
+	reg.Close()
+	reg.Close() // 可重复调用
+	reg.Resume()
+	if _, err := client.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, ch)
+}
+
+// TestLoops 连接轮流分配到各循环，一个循环阻塞不影响其他循环上的连接
+func TestLoops(t *testing.T) {
+	r := newReactor(t, 2)
+
+	_, rc1, client1 := pair(t)
+	_, rc2, client2 := pair(t)
+	block := make(chan struct{})
+	defer close(block)
+	reg1, err := r.Register(rc1, func(buf []byte) { <-block })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reg1.Close()
+	_, ch := reader(t, r, rc2, true)
+
+	_, _ = client1.Write([]byte("stuck"))
+	time.Sleep(20 * time.Millisecond)
+	_, _ = client2.Write([]byte("free"))
+	expectRead(t, ch, "free", nil)
+}
+
+func TestClose(t *testing.T) {
+	r := newReactor(t, 1)
+	_, rc, client := pair(t)
+	_, ch := reader(t, r, rc, true)
+
+	r.Close()
+	r.Close() // 可重复调用
+	if _, err := client.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, ch)
+
+	_, rc2, _ := pair(t)
+	if _, err := r.Register(rc2, func([]byte) {}); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Register after Close = %v, want net.ErrClosed", err)
+	}
+}
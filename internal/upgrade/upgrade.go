@@ -0,0 +1,49 @@
+package upgrade
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/yurazsb/uno/internal/decoder"
+	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/framer"
+)
+
+// 运行时升级：
+//
+// 连接按协商结果替换 Framer / Decoder / Encoder，或在明文连接上开启 TLS（STARTTLS）。替换与拆帧互斥：
+// 读缓冲中尚未拆帧的数据交由新的 Framer 处理；升级确认（Reply）以旧的 Encoder 编码、在替换之后写出，
+// 对端收到确认后发送的新格式数据不会被旧 Framer 拆分。替换前已拆出的帧仍以旧的 Decoder 解码。
+
+var ErrUnsupported = errors.New("upgrade not supported")
+
+// ErrServerName 客户端连接无拨号地址（如 DialConn 包装的连接）时，开启 TLS 需设置 ServerName 或跳过校验
+var ErrServerName = errors.New("upgrade: TLS ServerName is required without a dial address")
+
+// Options 升级内容，零值字段保持不变
+type Options struct {
+	// Framer 新的 Framer，启用 RPC 时由信封帧拆分，不可替换
+	Framer framer.Framer
+
+	// Decoder 新的 Decoder
+	Decoder decoder.Decoder
+
+	// Encoder 新的 Encoder，对 Reply 之后发送的消息生效
+	Encoder encoder.Encoder
+
+	// Reply 升级确认消息（如 "220 Ready to start TLS"），以旧的 Encoder 编码，在替换 Framer 之后写出；
+	// 开启 TLS 时等待其以明文写出后再开始握手
+	Reply any
+
+	// TLS 非 nil 时在当前连接上开启 TLS：服务端连接作为 TLS 服务端，客户端连接作为 TLS 客户端
+	// （未设置 ServerName 且未跳过校验时使用拨号地址中的主机名，无拨号地址时返回 ErrServerName）。握手期间暂停读取，已读出但尚未拆帧的数据作为握手的开头；
+	// 握手失败时关闭连接。仅支持未启用 epoll 事件循环的 TCP / Unix 流式明文连接
+	TLS *tls.Config
+}
+
+// Conn 由连接实现，可通过类型断言在运行时升级
+type Conn interface {
+	// Upgrade 按 opts 升级连接，返回时升级已完成；ctx 限定等待 Reply 写出与 TLS 握手的时间。
+	// 开启 TLS 时，Upgrade 返回前经其他途径发送的消息可能以明文写出，应在返回后再发送
+	Upgrade(ctx context.Context, opts Options) error
+}
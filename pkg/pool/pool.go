@@ -356,3 +356,70 @@ func (e *orderedExecutor) Close() {
 }
 
 func (e *orderedExecutor) Len() int { return len(e.q) }
+
+/********** SerialExecutor（借用池的串行执行器） **********/
+
+// Submitter 可提交任务的执行者，如 Pool
+type Submitter interface {
+	Submit(task func()) bool
+}
+
+// SerialExecutor 按提交顺序串行执行任务，仅在有待执行任务时占用底层池的一个工作者，
+// 空闲时归还，适合为大量对象（如连接）各自提供有序执行。
+type SerialExecutor struct {
+	pool    Submitter
+	onPanic PanicHandler
+
+	mu      sync.Mutex
+	tasks   []func()
+	running bool
+}
+
+func NewSerialExecutor(p Submitter, onPanic PanicHandler) *SerialExecutor {
+	return &SerialExecutor{pool: p, onPanic: onPanic}
+}
+
+// Submit 追加任务，必要时向底层池提交调度任务；底层池拒绝时改用独立协程，保证不丢任务
+func (e *SerialExecutor) Submit(task func()) bool {
+	e.mu.Lock()
+	e.tasks = append(e.tasks, task)
+	if e.running {
+		e.mu.Unlock()
+		return true
+	}
+	e.running = true
+	e.mu.Unlock()
+
+	if !e.pool.Submit(e.drain) {
+		go e.drain()
+	}
+	return true
+}
+
+// drain 依次执行队列中的任务，直到队列为空
+func (e *SerialExecutor) drain() {
+	for {
+		e.mu.Lock()
+		if len(e.tasks) == 0 {
+			e.running = false
+			e.mu.Unlock()
+			return
+		}
+		tasks := e.tasks
+		e.tasks = nil
+		e.mu.Unlock()
+
+		for _, task := range tasks {
+			e.run(task)
+		}
+	}
+}
+
+func (e *SerialExecutor) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil && e.onPanic != nil {
+			e.onPanic(r)
+		}
+	}()
+	task()
+}
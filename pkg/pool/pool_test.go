@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type rejectAll struct{}
+
+func (rejectAll) Submit(task func()) bool { return false }
+
+// TestSerialExecutor 并发的底层池上按提交顺序逐个执行，任务 panic 不影响后续任务
+func TestSerialExecutor(t *testing.T) {
+	p := New(WithMaxWorkers(16), WithQueue(1024))
+	defer p.Close()
+
+	var panics atomic.Int32
+	e := NewSerialExecutor(p, func(any) { panics.Add(1) })
+
+	const n = 1000
+	var (
+		mu       sync.Mutex
+		got      []int
+		inflight atomic.Int32
+		overlap  atomic.Bool
+		done     = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		e.Submit(func() {
+			if inflight.Add(1) > 1 {
+				overlap.Store(true)
+			}
+			defer inflight.Add(-1)
+			mu.Lock()
+			got = append(got, i)
+			mu.Unlock()
+			if i == n/2 {
+				panic("boom")
+			}
+			if i == n-1 {
+				close(done)
+			}
+		})
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tasks not finished")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("task %d ran at position %d", v, i)
+		}
+	}
+	if len(got) != n {
+		t.Fatalf("ran %d tasks, want %d", len(got), n)
+	}
+	if overlap.Load() {
+		t.Fatal("tasks ran concurrently")
+	}
+	if panics.Load() != 1 {
+		t.Fatalf("panic handler called %d times, want 1", panics.Load())
+	}
+}
+
+// TestSerialExecutorFallback 底层池拒绝时改用独立协程，任务不丢失
+func TestSerialExecutorFallback(t *testing.T) {
+	e := NewSerialExecutor(rejectAll{}, nil)
+	ran := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		if !e.Submit(func() { ran <- i }) {
+			t.Fatal("Submit rejected")
+		}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case v := <-ran:
+			if v != i {
+				t.Fatalf("task %d ran at position %d", v, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("task %d not run", i)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
@@ -34,6 +35,18 @@ const (
 	StageOnEnter
 )
 
+// ErrFinal is returned by Change and Event when the machine is in a final state.
+var ErrFinal = errors.New("transition from final state")
+
+// ErrReentrant is returned by Change, Event and Force when ctx is the Transition.Ctx of a
+// transition still running on the same machine, e.g. from one of its Guard or Before hooks,
+// which would otherwise wait for that transition to finish.
+var ErrReentrant = errors.New("nested transition from a hook")
+
+// Any matches every state: as the source of AddTransition it enables the transition
+// from all states, and in HookSpec.From / HookSpec.To it matches any state.
+const Any State = -1
+
 type Transition struct {
 	From  State
 	To    State
@@ -60,21 +73,21 @@ type HookSpec struct {
 
 type transitionTable struct {
 	mu    sync.RWMutex
-	state map[State]State
+	state map[State]map[State]struct{}
 	event map[State]map[Event]State
 }
 
 func newTransitions() *transitionTable {
-	return &transitionTable{
-		state: make(map[State]State),
-		event: map[State]map[Event]State{},
-	}
+	return &transitionTable{}
 }
 
 func (t *transitionTable) Add(from State, to State, evs ...Event) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	if len(evs) > 0 {
+		if t.event == nil {
+			t.event = map[State]map[Event]State{}
+		}
 		if t.event[from] == nil {
 			t.event[from] = map[Event]State{}
 		}
@@ -82,25 +95,45 @@ func (t *transitionTable) Add(from State, to State, evs ...Event) {
 			t.event[from][ev] = to
 		}
 	} else {
-		t.state[from] = to
+		if t.state == nil {
+			t.state = map[State]map[State]struct{}{}
+		}
+		// a later transition from the same state replaces the previous targets
+		t.state[from] = map[State]struct{}{to: {}}
+	}
+}
+
+func (t *transitionTable) AddTargets(from State, to ...State) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state == nil {
+		t.state = map[State]map[State]struct{}{}
+	}
+	if t.state[from] == nil {
+		t.state[from] = map[State]struct{}{}
+	}
+	for _, s := range to {
+		t.state[from][s] = struct{}{}
 	}
 }
 
 func (t *transitionTable) Enable(from State, to State) bool {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	toState, ok := t.state[from]
-	return ok && to == toState
+	if _, ok := t.state[from][to]; ok {
+		return true
+	}
+	_, ok := t.state[Any][to]
+	return ok
 }
 
 func (t *transitionTable) Next(from State, ev Event) (State, bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
-	evs := t.event[from]
-	if evs == nil {
-		return 0, false
+	if s, ok := t.event[from][ev]; ok {
+		return s, true
 	}
-	s, ok := evs[ev]
+	s, ok := t.event[Any][ev]
 	return s, ok
 }
 
@@ -114,12 +147,15 @@ type hooks struct {
 }
 
 func newHooks() *hooks {
-	return &hooks{m: make(map[Stage][]HookSpec)}
+	return &hooks{}
 }
 
 func (r *hooks) Add(h HookSpec) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.m == nil {
+		r.m = make(map[Stage][]HookSpec)
+	}
 	r.m[h.Stage] = append(r.m[h.Stage], h)
 	// keep sorted by Priority ascending
 	sort.SliceStable(r.m[h.Stage], func(i, j int) bool {
@@ -153,16 +189,54 @@ func (r *hooks) Run(stage Stage, t *Transition) error {
 	return nil
 }
 
+// Fire runs the hooks of the stages in order in the calling goroutine, ignoring their errors.
+func (r *hooks) Fire(t *Transition, stages ...Stage) {
+	for _, stage := range stages {
+		r.mu.RLock()
+		hs := append([]HookSpec(nil), r.m[stage]...)
+		r.mu.RUnlock()
+
+		for _, h := range hs {
+			if matchHook(&h, t) {
+				_ = safeCall(h.Fn, t)
+			}
+		}
+	}
+}
+
+// Has reports whether any hook of the stages matches t.
+func (r *hooks) Has(t *Transition, stages ...Stage) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, stage := range stages {
+		for i := range r.m[stage] {
+			if matchHook(&r.m[stage][i], t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func matchHook(h *HookSpec, t *Transition) bool {
-	if h.From != t.From {
+	if h.From != Any && h.From != t.From {
 		return false
 	}
-	if h.To != t.To {
+	if h.To != Any && h.To != t.To {
 		return false
 	}
 	return true
 }
 
+func safeCall(fn HookFunc, t *Transition) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in hook: %v", r)
+		}
+	}()
+	return fn(t)
+}
+
 func safeInvoke(ctx context.Context, fn HookFunc, t *Transition) (err error) {
 	done := make(chan error, 1)
 	go func() {
@@ -194,8 +268,17 @@ type Snapshot struct {
 const (
 	changeCmd = iota
 	eventCmd
+	forceCmd
 )
 
+// activeKey marks Transition.Ctx with the transition it belongs to.
+type activeKey struct{}
+
+type active struct {
+	m    *Machine
+	done atomic.Bool // set once the transition no longer holds the machine
+}
+
 type cmd struct {
 	cmdType int
 	value   any
@@ -215,21 +298,57 @@ type Machine struct {
 	transitions *transitionTable
 	hooks       *hooks
 
+	inline bool                   // commands run in the caller, serialized by sem
+	sem    chan struct{}          // serializes commands in sync mode; a channel so waiting honors ctx
+	exec   func(task func()) bool // runs the hooks after the state is applied, nil to wait for them
+	final  []State                // states that Change / Event cannot leave
+
 	startOnce sync.Once
 
 	stopOnce sync.Once
 	stopped  chan struct{}
 }
 
-func NewMachine(name string, initial State) *Machine {
+type Option func(*Machine)
+
+// WithSync runs Change / Event / Force in the calling goroutine, serialized by a lock,
+// instead of the command loop; Run and Stop are then no-ops. Callers waiting for the
+// lock give up when their ctx ends, and hooks that call back into the machine with
+// Transition.Ctx get ErrReentrant instead of waiting for themselves.
+func WithSync() Option {
+	return func(s *Machine) { s.inline = true }
+}
+
+// WithExecutor submits the hooks of the stages after the state is applied
+// (Apply, After, OnExit, OnEnter) to exec as one task per transition without waiting
+// for them; their errors are ignored. Guards and Before still run before the
+// transition and may reject it.
+func WithExecutor(exec func(task func()) bool) Option {
+	return func(s *Machine) { s.exec = exec }
+}
+
+// WithFinal marks states that Change and Event cannot leave, regardless of the
+// transition table (including transitions from Any); Force still can.
+func WithFinal(states ...State) Option {
+	return func(s *Machine) { s.final = states }
+}
+
+func NewMachine(name string, initial State, opts ...Option) *Machine {
 	s := &Machine{
 		name:        name,
 		cur:         initial,
 		epoch:       0,
-		cmdCh:       make(chan cmd, 64),
 		transitions: newTransitions(),
 		hooks:       newHooks(),
-		stopped:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.inline {
+		s.sem = make(chan struct{}, 1)
+	} else {
+		s.cmdCh = make(chan cmd, 64)
+		s.stopped = make(chan struct{})
 	}
 	s.snap.Store(Snapshot{State: initial, Epoch: 0, Since: time.Now()})
 	return s
@@ -244,6 +363,13 @@ func (s *Machine) AddTransition(from State, to State, evs ...Event) {
 	s.transitions.Add(from, to, evs...)
 }
 
+// AddTargets enables Change from `from` to each of `to`, keeping the targets added
+// before. Without events AddTransition allows a single target per state and replaces
+// the previous one.
+func (s *Machine) AddTargets(from State, to ...State) {
+	s.transitions.AddTargets(from, to...)
+}
+
 func (s *Machine) RegHook(hook HookSpec) {
 	s.hooks.Add(hook)
 }
@@ -258,13 +384,25 @@ func (s *Machine) Event(ctx context.Context, event Event) error {
 	return s.resp(c)
 }
 
+// Force moves to state regardless of the transition table, skipping Guards and Before hooks.
+func (s *Machine) Force(ctx context.Context, state State) error {
+	c := cmd{cmdType: forceCmd, value: state, ctx: ctx, resp: make(chan error, 1)}
+	return s.resp(c)
+}
+
 func (s *Machine) Run() {
+	if s.inline {
+		return
+	}
 	s.startOnce.Do(func() {
 		go s.loop()
 	})
 }
 
 func (s *Machine) Stop() {
+	if s.inline {
+		return
+	}
 	s.stopOnce.Do(func() {
 		close(s.cmdCh)
 		<-s.stopped
@@ -272,6 +410,21 @@ func (s *Machine) Stop() {
 }
 
 func (s *Machine) resp(c cmd) error {
+	if a, ok := c.ctx.Value(activeKey{}).(*active); ok && a.m == s && !a.done.Load() {
+		return ErrReentrant
+	}
+
+	if s.inline {
+		select {
+		case s.sem <- struct{}{}:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+		_ = s.handleCmd(c)
+		<-s.sem
+		return <-c.resp
+	}
+
 	select {
 	case s.cmdCh <- c:
 	case <-c.ctx.Done():
@@ -299,12 +452,23 @@ func (s *Machine) handleCmd(c cmd) error {
 	var to State
 	var ev Event
 
-	if c.cmdType == changeCmd {
+	if c.cmdType != forceCmd && s.isFinal(from) {
+		select {
+		case c.resp <- ErrFinal:
+		default:
+		}
+		return nil
+	}
+
+	switch c.cmdType {
+	case changeCmd:
 		to = c.value.(State)
 		ok = s.transitions.Enable(from, to)
-	} else {
+	case eventCmd:
 		ev = c.value.(Event)
 		to, ok = s.transitions.Next(from, ev)
+	case forceCmd:
+		to, ok = c.value.(State), true
 	}
 
 	if !ok {
@@ -315,16 +479,20 @@ func (s *Machine) handleCmd(c cmd) error {
 		return nil
 	}
 
+	a := &active{m: s}
+	defer a.done.Store(true)
 	t := &Transition{
 		From:  from,
 		To:    to,
 		Event: ev,
-		Ctx:   c.ctx,
+		Ctx:   context.WithValue(c.ctx, activeKey{}, a),
 		Time:  time.Now(),
 	}
 
+	forced := c.cmdType == forceCmd
+
 	// Guards
-	if err := s.hooks.Run(StageGuards, t); err != nil {
+	if err := s.guard(forced, StageGuards, t); err != nil {
 		t.Err = err
 		select {
 		case c.resp <- err:
@@ -334,7 +502,7 @@ func (s *Machine) handleCmd(c cmd) error {
 	}
 
 	// Before
-	if err := s.hooks.Run(StageBefore, t); err != nil {
+	if err := s.guard(forced, StageBefore, t); err != nil {
 		t.Err = err
 		select {
 		case c.resp <- err:
@@ -346,6 +514,20 @@ func (s *Machine) handleCmd(c cmd) error {
 	s.cur = to
 	s.epoch++
 	s.snap.Store(Snapshot{State: s.cur, Epoch: s.epoch, Since: time.Now()})
+
+	if s.exec != nil {
+		a.done.Store(true) // the hooks run after the transition and may start the next one
+		stages := []Stage{StageApply, StageAfter, StageOnExit, StageOnEnter}
+		if s.hooks.Has(t, stages...) {
+			_ = s.exec(func() { s.hooks.Fire(t, stages...) })
+		}
+		select {
+		case c.resp <- nil:
+		default:
+		}
+		return nil
+	}
+
 	_ = s.hooks.Run(StageApply, t)
 
 	if err := s.hooks.Run(StageAfter, t); err != nil {
@@ -366,3 +548,19 @@ func (s *Machine) handleCmd(c cmd) error {
 	}
 	return nil
 }
+
+func (s *Machine) guard(forced bool, stage Stage, t *Transition) error {
+	if forced {
+		return nil
+	}
+	return s.hooks.Run(stage, t)
+}
+
+func (s *Machine) isFinal(st State) bool {
+	for _, f := range s.final {
+		if f == st {
+			return true
+		}
+	}
+	return false
+}
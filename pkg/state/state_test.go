@@ -0,0 +1,165 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	idle State = iota
+	running
+	paused
+	stopped
+)
+
+func TestAddTransitionReplaces(t *testing.T) {
+	m := NewMachine("test", idle, WithSync())
+	m.AddTransition(idle, running)
+	m.AddTransition(idle, paused) // replaces idle -> running
+
+	ctx := context.Background()
+	if err := m.Change(ctx, running); err == nil {
+		t.Fatal("replaced transition idle -> running is still enabled")
+	}
+	if err := m.Change(ctx, paused); err != nil {
+		t.Fatalf("idle -> paused: %v", err)
+	}
+}
+
+func TestAddTargets(t *testing.T) {
+	ctx := context.Background()
+	for _, to := range []State{running, paused, stopped} {
+		m := NewMachine("test", idle, WithSync())
+		m.AddTargets(idle, running, paused)
+		m.AddTargets(idle, stopped)
+
+		if err := m.Change(ctx, to); err != nil {
+			t.Fatalf("idle -> %v: %v", to, err)
+		}
+		if got := m.Snapshot().State; got != to {
+			t.Fatalf("state = %v, want %v", got, to)
+		}
+	}
+
+	// AddTransition replaces every target added by AddTargets
+	m := NewMachine("test", idle, WithSync())
+	m.AddTargets(idle, running, paused)
+	m.AddTransition(idle, stopped)
+	if err := m.Change(ctx, running); err == nil {
+		t.Fatal("idle -> running is still enabled after AddTransition")
+	}
+}
+
+// changeFromGuard registers a Guard on idle -> running that calls Change with the
+// transition's context and reports the result.
+func changeFromGuard(m *Machine) <-chan error {
+	nested := make(chan error, 1)
+	m.RegHook(HookSpec{Stage: StageGuards, From: idle, To: running, Fn: func(t *Transition) error {
+		nested <- m.Change(t.Ctx, paused)
+		return nil
+	}})
+	return nested
+}
+
+func TestReentrantChange(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"sync", []Option{WithSync()}},
+		{"loop", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMachine("test", idle, tc.opts...)
+			m.Run()
+			defer m.Stop()
+			m.AddTransition(idle, running)
+			m.AddTransition(running, paused)
+			nested := changeFromGuard(m)
+
+			done := make(chan error, 1)
+			go func() { done <- m.Change(context.Background(), running) }()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("idle -> running: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Change from a Guard deadlocked")
+			}
+			if err := <-nested; !errors.Is(err, ErrReentrant) {
+				t.Fatalf("nested Change = %v, want ErrReentrant", err)
+			}
+			if got := m.Snapshot().State; got != running {
+				t.Fatalf("state = %v, want running", got)
+			}
+			// the context is usable again once the transition has finished
+			if err := m.Change(context.Background(), paused); err != nil {
+				t.Fatalf("running -> paused: %v", err)
+			}
+		})
+	}
+}
+
+// TestChangeFromExecutor hooks run by the executor after the transition may start the next one.
+func TestChangeFromExecutor(t *testing.T) {
+	m := NewMachine("test", idle, WithSync(), WithExecutor(func(task func()) bool {
+		go task()
+		return true
+	}))
+	m.AddTransition(idle, running)
+	m.AddTransition(running, paused)
+	nested := make(chan error, 1)
+	m.RegHook(HookSpec{Stage: StageOnEnter, From: idle, To: running, Fn: func(t *Transition) error {
+		nested <- m.Change(t.Ctx, paused)
+		return nil
+	}})
+
+	if err := m.Change(context.Background(), running); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-nested:
+		if err != nil {
+			t.Fatalf("Change from OnEnter: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnEnter hook not run")
+	}
+	if got := m.Snapshot().State; got != paused {
+		t.Fatalf("state = %v, want paused", got)
+	}
+}
+
+// TestSyncWaitHonorsContext a caller waiting for a running transition gives up when its ctx ends.
+func TestSyncWaitHonorsContext(t *testing.T) {
+	m := NewMachine("test", idle, WithSync())
+	m.AddTransition(idle, running)
+	m.AddTransition(running, paused)
+	entered, release := make(chan struct{}), make(chan struct{})
+	m.RegHook(HookSpec{Stage: StageBefore, From: idle, To: running, Fn: func(t *Transition) error {
+		close(entered)
+		<-release
+		return nil
+	}})
+
+	first := make(chan error, 1)
+	go func() { first <- m.Change(context.Background(), running) }()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Change(ctx, paused); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waiting Change = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatalf("idle -> running: %v", err)
+	}
+	if got := m.Snapshot().State; got != running {
+		t.Fatalf("state = %v, want running", got)
+	}
+}
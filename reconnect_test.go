@@ -0,0 +1,122 @@
+package uno_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// helloHandshaker 客户端发送 HELLO，收到 OK 后握手成功
+type helloHandshaker struct{}
+
+func (helloHandshaker) Begin(h uno.Handshake) { h.Send("HELLO\n") }
+
+func (helloHandshaker) Handle(h uno.Handshake, frame []byte) {
+	if string(frame) == "OK" {
+		h.Accept()
+		return
+	}
+	h.Reject(errors.New(string(frame)))
+}
+
+type reconnectHook struct {
+	uno.ConnEvent
+	ready  chan uno.Conn
+	causes chan error
+}
+
+func (h *reconnectHook) OnReady(c uno.Conn) { h.ready <- c }
+
+func (h *reconnectHook) OnReconnecting(c uno.Conn, attempt int, err error) {
+	select {
+	case h.causes <- err:
+	default:
+	}
+}
+
+func (h *reconnectHook) OnReconnected(c uno.Conn, attempt int) {}
+
+func TestReconnectFacade(t *testing.T) {
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, "127.0.0.1:0", uno.WithLogger(&logRecorder{}),
+		uno.WithFramer(uno.LineFramer()), uno.WithDecoder(uno.StringDecoder(false)),
+		uno.WithHandshaker(uno.HandshakeFunc(func(h uno.Handshake, frame []byte) {
+			h.Send("OK\n")
+			h.Accept()
+		})),
+		uno.WithHandlers(func(ctx uno.Context, next func()) {
+			if ctx.Payload() == "bye" {
+				ctx.Conn().Close()
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+
+	h := &reconnectHook{ready: make(chan uno.Conn, 4), causes: make(chan error, 4)}
+	c, err := uno.Dial(context.Background(), h, srv.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithFramer(uno.LineFramer()), uno.WithDecoder(uno.StringDecoder(false)),
+		uno.WithHandshaker(helloHandshaker{}),
+		uno.WithReconnect(uno.ReconnectOptions{InitialDelay: 10 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	waitReady := func() {
+		t.Helper()
+		select {
+		case rc := <-h.ready:
+			if rc != c {
+				t.Fatalf("OnReady got %T, want the reconnecting conn", rc)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("OnReady not called")
+		}
+	}
+	waitReady()
+
+	sc, ok := c.(uno.StateConn)
+	if !ok || sc.State() != uno.StateReady || sc.Machine() == nil {
+		t.Fatalf("StateConn = %v", ok)
+	}
+	if _, ok := c.(uno.HeartbeatConn); !ok {
+		t.Fatal("reconnecting conn does not implement HeartbeatConn")
+	}
+	if tc, ok := c.(uno.TLSConn); !ok {
+		t.Fatal("reconnecting conn does not implement TLSConn")
+	} else if _, isTLS := tc.ConnectionState(); isTLS {
+		t.Fatal("plain connection reports TLS state")
+	}
+	if _, ok := c.(uno.UpgradeConn); !ok {
+		t.Fatal("reconnecting conn does not implement UpgradeConn")
+	}
+
+	c.Send("bye\n")
+	select {
+	case cause := <-h.causes:
+		if !errors.Is(cause, io.EOF) {
+			t.Fatalf("OnReconnecting cause = %v, want EOF", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnReconnecting not called")
+	}
+	waitReady()
+	// OnReady 先于门面切换到新连接
+	deadline := time.Now().Add(5 * time.Second)
+	for sc.State() != uno.StateReady {
+		if time.Now().After(deadline) {
+			t.Fatalf("state after reconnect = %v", sc.State())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	c.Close()
+	if sc.State() != uno.StateClosed {
+		t.Fatalf("state after close = %v", sc.State())
+	}
+}
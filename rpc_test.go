@@ -0,0 +1,111 @@
+package uno_test
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+func TestRPCConn(t *testing.T) {
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, "127.0.0.1:0", uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)),
+		uno.WithHandlers(func(ctx uno.Context, next func()) {
+			ctx.Reply("pong:" + ctx.Payload().(string))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, srv.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rc, ok := c.(uno.RPCConn)
+	if !ok {
+		t.Fatal("conn does not implement RPCConn")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := rc.Call(ctx, "ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "pong:ping" {
+		t.Fatalf("reply = %v", reply)
+	}
+	if err := <-rc.SendContext(ctx, "oneway"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRPCDuplicateReplies 对端对同一请求重复应答时丢弃多余的应答，读协程继续处理后续消息
+func TestRPCDuplicateReplies(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	envelope := func(kind byte, id uint64, body string) []byte {
+		buf := make([]byte, 13, 13+len(body))
+		buf[0] = kind
+		binary.BigEndian.PutUint64(buf[1:], id)
+		binary.BigEndian.PutUint32(buf[9:], uint32(len(body)))
+		return append(buf, body...)
+	}
+	go func() {
+		raw, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer raw.Close()
+		hdr := make([]byte, 13)
+		if _, err := io.ReadFull(raw, hdr); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(raw, make([]byte, binary.BigEndian.Uint32(hdr[9:]))); err != nil {
+			return
+		}
+		id := binary.BigEndian.Uint64(hdr[1:])
+		for i := 0; i < 5; i++ {
+			_, _ = raw.Write(envelope(2, id, "pong"))
+		}
+		_, _ = raw.Write(envelope(0, 0, "after"))
+		_, _ = io.Copy(io.Discard, raw)
+	}()
+
+	msgs := make(chan string, 4)
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, ln.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithRPC(true), uno.WithDecoder(uno.StringDecoder(false)), recvHandlers(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := c.(uno.RPCConn).Call(ctx, "ping")
+	if err != nil || reply != "pong" {
+		t.Fatalf("Call = %v, %v", reply, err)
+	}
+	expect(t, msgs, "after")
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Close blocked after duplicate replies")
+	}
+}
@@ -0,0 +1,226 @@
+package uno_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// testCA 进程内生成的测试 CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "uno test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// issue 签发叶子证书，usage 为 ExtKeyUsageServerAuth 时包含 localhost / 127.0.0.1
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	if usage == x509.ExtKeyUsageServerAuth {
+		return ca.issueFor(t, cn, usage, []string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1)})
+	}
+	return ca.issueFor(t, cn, usage, nil, nil)
+}
+
+// issueFor 签发包含指定主机名与 IP 的叶子证书
+func (ca *testCA) issueFor(t *testing.T, cn string, usage x509.ExtKeyUsage, dns []string, ips []net.IP) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dns,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}
+}
+
+// logRecorder 记录日志，用于断言服务端输出的握手错误
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logRecorder) record(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *logRecorder) Debug(format string, args ...any) {}
+func (l *logRecorder) Info(format string, args ...any)  {}
+func (l *logRecorder) Warn(format string, args ...any)  { l.record(format, args...) }
+func (l *logRecorder) Error(format string, args ...any) { l.record(format, args...) }
+
+func (l *logRecorder) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectHook 将建立的连接送入 conns
+type connectHook struct {
+	uno.ServerEvent
+	conns chan uno.Conn
+}
+
+func (h *connectHook) OnConnect(c uno.Conn) { h.conns <- c }
+
+func startMTLS(t *testing.T, ca *testCA, tc *tls.Config) (uno.Server, *connectHook, *logRecorder) {
+	t.Helper()
+	tc.Certificates = []tls.Certificate{ca.issue(t, "server", x509.ExtKeyUsageServerAuth)}
+	tc.ClientCAs = ca.pool
+	tc.ClientAuth = tls.RequireAndVerifyClientCert
+	h := &connectHook{conns: make(chan uno.Conn, 1)}
+	log := &logRecorder{}
+	srv, err := uno.Start(context.Background(), h, "127.0.0.1:0", uno.WithLogger(log), uno.WithTLSConfig(tc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	return srv, h, log
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	srv, h, _ := startMTLS(t, ca, &tls.Config{NextProtos: []string{"uno/1", "h2"}})
+
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, srv.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithTLSConfig(&tls.Config{
+			RootCAs:      ca.pool,
+			Certificates: []tls.Certificate{ca.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+			NextProtos:   []string{"uno/1"},
+		}))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	cc := c.(uno.TLSConn)
+	if state, ok := cc.ConnectionState(); !ok || !state.HandshakeComplete {
+		t.Fatalf("client ConnectionState = %v, %v", state.HandshakeComplete, ok)
+	}
+	if got := cc.NegotiatedProtocol(); got != "uno/1" {
+		t.Errorf("client NegotiatedProtocol = %q, want uno/1", got)
+	}
+	if certs := cc.PeerCertificates(); len(certs) == 0 || certs[0].Subject.CommonName != "server" {
+		t.Errorf("client PeerCertificates = %v", certs)
+	}
+
+	var sc uno.Conn
+	select {
+	case sc = <-h.conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not accept the connection")
+	}
+	st := sc.(uno.TLSConn)
+	if got := st.NegotiatedProtocol(); got != "uno/1" {
+		t.Errorf("server NegotiatedProtocol = %q, want uno/1", got)
+	}
+	if certs := st.PeerCertificates(); len(certs) == 0 || certs[0].Subject.CommonName != "client" {
+		t.Errorf("server PeerCertificates = %v", certs)
+	}
+}
+
+func TestMutualTLSRejectsClientWithoutCert(t *testing.T) {
+	ca := newTestCA(t)
+	// TLS 1.2 下客户端证书在握手内校验，Dial 即返回错误
+	srv, h, log := startMTLS(t, ca, &tls.Config{MaxVersion: tls.VersionTLS12})
+
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, srv.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithTLSConfig(&tls.Config{RootCAs: ca.pool}))
+	if err == nil {
+		c.Close()
+		t.Fatal("dial without client certificate succeeded")
+	}
+	if !strings.Contains(err.Error(), "tls handshake failed") {
+		t.Errorf("dial error = %v", err)
+	}
+
+	select {
+	case <-h.conns:
+		t.Fatal("server accepted a client without certificate")
+	case <-time.After(200 * time.Millisecond):
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !log.contains("tls handshake failed") {
+		if time.Now().After(deadline) {
+			t.Fatal("server did not report the handshake failure")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLSClientVerifiesServer(t *testing.T) {
+	ca := newTestCA(t)
+	srv, _, _ := startMTLS(t, ca, &tls.Config{})
+
+	// 客户端不信任签发服务端证书的 CA
+	other := newTestCA(t)
+	_, err := uno.Dial(context.Background(), &uno.ConnEvent{}, srv.Addr().String(), uno.WithLogger(&logRecorder{}),
+		uno.WithTLSConfig(&tls.Config{
+			RootCAs:      other.pool,
+			Certificates: []tls.Certificate{ca.issue(t, "client", x509.ExtKeyUsageClientAuth)},
+		}))
+	if err == nil {
+		t.Fatal("dial to an untrusted server succeeded")
+	}
+}
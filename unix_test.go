@@ -0,0 +1,177 @@
+//go:build unix
+
+package uno_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// msgHook 将收到的消息送入 msgs
+type msgHook struct {
+	uno.ConnEvent
+	msgs chan string
+}
+
+func (h *msgHook) OnMessage(c uno.Conn, msg any) { h.msgs <- string(msg.([]byte)) }
+
+// startEcho 启动原样回发消息的服务端，建立的连接送入返回的 connectHook
+func startEcho(t *testing.T, network, addr string, opts ...uno.Option) (uno.Server, *connectHook) {
+	t.Helper()
+	h := &connectHook{conns: make(chan uno.Conn, 4)}
+	opts = append([]uno.Option{uno.WithNetwork(network), uno.WithLogger(&logRecorder{}),
+		uno.WithHandlers(func(ctx uno.Context, next func()) { ctx.Conn().Send(ctx.Payload()) })}, opts...)
+	srv, err := uno.Start(context.Background(), h, addr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	return srv, h
+}
+
+func expectMsg(t *testing.T, h *msgHook, want string) {
+	t.Helper()
+	select {
+	case got := <-h.msgs:
+		if got != want {
+			t.Fatalf("received %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive %q", want)
+	}
+}
+
+// socketPath 返回临时目录下的套接字路径（Unix 套接字路径长度受限，文件名保持简短）
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "uno")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "s.sock")
+}
+
+func TestUnixRoundTrip(t *testing.T) {
+	for _, network := range []string{"unix", "unixpacket", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			if network == "unixpacket" && runtime.GOOS != "linux" {
+				t.Skip("unixpacket is only supported on Linux")
+			}
+			path := socketPath(t)
+			srv, sh := startEcho(t, network, path, uno.WithUnixSocketMode(0o600))
+			if fi, err := os.Stat(path); err != nil || fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0o600 {
+				t.Fatalf("socket file = %v, %v, want a socket with mode 0600", fi, err)
+			}
+
+			opts := []uno.Option{uno.WithNetwork(network), uno.WithLogger(&logRecorder{})}
+			if network == "unixgram" {
+				opts = append(opts, uno.WithLocalAddr(path+".c"))
+			}
+			h := &msgHook{msgs: make(chan string, 4)}
+			c, err := uno.Dial(context.Background(), h, path, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			for _, msg := range []string{"hello", "world"} {
+				if err := <-c.Send(msg); err != nil {
+					t.Fatal(err)
+				}
+				expectMsg(t, h, msg)
+			}
+
+			var sc uno.Conn
+			select {
+			case sc = <-sh.conns:
+			case <-time.After(5 * time.Second):
+				t.Fatal("server conn not created")
+			}
+			if _, ok := sc.Attrs().Get(uno.AttrPeerUID); ok != (network != "unixgram" && runtime.GOOS == "linux") {
+				t.Fatalf("peer credentials present = %v on %s/%s", ok, network, runtime.GOOS)
+			}
+
+			srv.Stop()
+			if _, err := os.Lstat(path); !os.IsNotExist(err) {
+				t.Fatalf("socket file left after Stop: %v", err)
+			}
+		})
+	}
+}
+
+// TestUnixPeerCred 服务端连接以 SO_PEERCRED 记录对端进程的凭证
+func TestUnixPeerCred(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_PEERCRED is only supported on Linux")
+	}
+	path := socketPath(t)
+	_, sh := startEcho(t, "unix", path)
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var sc uno.Conn
+	select {
+	case sc = <-sh.conns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server conn not created")
+	}
+	for _, tc := range []struct {
+		key  string
+		want any
+	}{
+		{uno.AttrPeerUID, uint32(os.Getuid())},
+		{uno.AttrPeerGID, uint32(os.Getgid())},
+		{uno.AttrPeerPID, int32(os.Getpid())},
+	} {
+		if got, ok := sc.Attrs().Get(tc.key); !ok || got != tc.want {
+			t.Errorf("%s = %v (%T), want %v (%T)", tc.key, got, got, tc.want, tc.want)
+		}
+	}
+}
+
+// TestUnixStaleSocket 监听前删除残留的套接字文件，但不删除同名的普通文件
+func TestUnixStaleSocket(t *testing.T) {
+	for _, network := range []string{"unix", "unixgram"} {
+		t.Run(network, func(t *testing.T) {
+			path := socketPath(t)
+
+			// 模拟异常退出的进程留下的套接字文件
+			stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stale.SetUnlinkOnClose(false)
+			_ = stale.Close()
+			if _, err := os.Lstat(path); err != nil {
+				t.Fatalf("stale socket not left: %v", err)
+			}
+
+			srv, _ := startEcho(t, network, path)
+			srv.Stop()
+
+			// 普通文件保持不变，监听失败
+			if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			srv2, err := uno.Start(context.Background(), &uno.ServerEvent{}, path,
+				uno.WithNetwork(network), uno.WithLogger(&logRecorder{}))
+			if err == nil {
+				srv2.Stop()
+				t.Fatal("listened on a path occupied by a regular file")
+			}
+			if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+				t.Fatalf("regular file changed: %q, %v", data, err)
+			}
+		})
+	}
+}
@@ -2,22 +2,47 @@ package uno
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"github.com/yurazsb/uno/internal/arq"
 	"github.com/yurazsb/uno/internal/boot"
+	"github.com/yurazsb/uno/internal/boot/conn"
+	"github.com/yurazsb/uno/internal/boot/reconnect"
 	"github.com/yurazsb/uno/internal/boot/tcp"
 	"github.com/yurazsb/uno/internal/boot/udp"
 	"github.com/yurazsb/uno/internal/conf"
 	"github.com/yurazsb/uno/internal/decoder"
 	"github.com/yurazsb/uno/internal/encoder"
+	"github.com/yurazsb/uno/internal/fragment"
 	"github.com/yurazsb/uno/internal/framer"
 	"github.com/yurazsb/uno/internal/handler"
+	"github.com/yurazsb/uno/internal/handoff"
+	"github.com/yurazsb/uno/internal/handshake"
+	"github.com/yurazsb/uno/internal/heartbeat"
 	"github.com/yurazsb/uno/internal/hook"
+	"github.com/yurazsb/uno/internal/lifecycle"
+	"github.com/yurazsb/uno/internal/mux"
+	"github.com/yurazsb/uno/internal/proxyproto"
+	"github.com/yurazsb/uno/internal/upgrade"
+	"github.com/yurazsb/uno/pkg/state"
+	"net"
+	"net/http"
+	"os"
 	"time"
 )
 
 type Server = boot.Server
 type Client = boot.Client
 type Conn = boot.Conn
+type TLSConn = boot.TLSConn
+type HeartbeatConn = boot.HeartbeatConn
+type StateConn = boot.StateConn
+type RPCConn = boot.RPCConn
+type ConnManager = boot.ConnManager
+type Group = boot.Group
+type BroadcastResult = boot.BroadcastResult
+type ShutdownReport = boot.ShutdownReport
 
 type Attrs = boot.Attrs
 type Pool = boot.Pool
@@ -27,6 +52,10 @@ type ServerHook = hook.ServerHook
 type ConnHook = hook.ConnHook
 type ServerEvent = hook.ServerEvent
 type ConnEvent = hook.ConnEvent
+type ReconnectHook = hook.ReconnectHook
+type BackpressureHook = hook.BackpressureHook
+type KickHook = hook.KickHook
+type ShutdownHook = hook.ShutdownHook
 
 type Framer = framer.Framer
 
@@ -35,6 +64,13 @@ var LineFramer = framer.LineFramer
 var DelimiterFramer = framer.DelimiterFramer
 var FixedLengthFramer = framer.FixedLengthFramer
 var LengthFieldFramer = framer.LengthFieldFramer
+var VarintLengthFramer = framer.VarintLengthFramer
+
+type VarintError = framer.VarintError
+type FrameSizeError = framer.FrameSizeError
+
+var ErrMalformedVarint = framer.ErrMalformedVarint
+var ErrFrameTooLarge = framer.ErrFrameTooLarge
 
 type Decoder = decoder.Decoder
 
@@ -44,6 +80,7 @@ var StringDecoder = decoder.StringDecoder
 type Encoder = encoder.Encoder
 
 var GenericEncoder = encoder.GenericEncoder
+var VarintLengthEncoder = encoder.VarintLengthEncoder
 
 type Handler = handler.Handler
 type Context = handler.Context
@@ -57,6 +94,141 @@ type Router = handler.Router
 type RouterGroup = handler.RouterGroup
 type Route = handler.Route
 
+// Unix 对端凭证属性键，可通过 Conn.Attrs() 读取（仅 Linux unix/unixpacket）
+const (
+	AttrPeerUID = conn.AttrPeerUID
+	AttrPeerGID = conn.AttrPeerGID
+	AttrPeerPID = conn.AttrPeerPID
+)
+
+// AttrWSRequest WebSocket 握手请求（*http.Request）属性键，仅服务端连接可用
+const AttrWSRequest = conn.AttrWSRequest
+
+type ARQOptions = arq.Options
+type ARQStats = arq.Stats
+
+// ErrARQDeadLink 数据段重传次数达到 DeadLink，连接以此为原因关闭
+var ErrARQDeadLink = arq.ErrDeadLink
+
+// ReliableStats 获取连接的可靠传输统计（RTT、重传、丢包率），未启用 ARQ 时返回 false
+var ReliableStats = arq.StatsOf
+
+type FragmentOptions = fragment.Options
+type FragmentStats = fragment.Stats
+
+// FragmentStatsOf 获取连接的分片重组统计（含丢弃的不完整消息数），未启用分片时返回 false
+var FragmentStatsOf = fragment.StatsOf
+
+type ReconnectOptions = reconnect.Options
+
+var ErrDisconnected = reconnect.ErrDisconnected
+var ErrReconnectBufferFull = reconnect.ErrBufferFull
+var ErrReconnectGiveUp = reconnect.ErrGiveUp
+
+type QueuePolicy = conf.QueuePolicy
+
+// 发送队列已满时的处理策略
+const (
+	QueueFailFast   = conf.QueueFailFast
+	QueueBlock      = conf.QueueBlock
+	QueueDropOldest = conf.QueueDropOldest
+	QueueClose      = conf.QueueClose
+)
+
+type QueueFullError = conn.QueueFullError
+
+var ErrSendQueueFull = conn.ErrSendQueueFull
+var ErrMessageDropped = conn.ErrMessageDropped
+
+var ErrRPCDisabled = conn.ErrRPCDisabled
+var ErrNotRequest = handler.ErrNotRequest
+var ErrReplied = handler.ErrReplied
+
+type MuxOptions = mux.Options
+type MuxSession = mux.Session
+type Stream = mux.Stream
+type StreamConfig = mux.StreamConfig
+
+// MuxSessionOf 获取连接的多路复用会话，通过 OpenStream 打开逻辑流，未启用多路复用时返回 false
+var MuxSessionOf = mux.SessionOf
+
+var ErrStreamRefused = mux.ErrRefused
+var ErrStreamReset = mux.ErrReset
+
+// NewGroup 创建独立于服务端的广播组，可混合不同服务端或客户端的连接
+func NewGroup(name string) Group { return conn.NewGroup(name) }
+
+// Except 广播过滤条件：排除指定连接（如消息发送者）
+var Except = conn.Except
+
+type HandoffOptions = handoff.Options
+
+type Handshaker = handshake.Handshaker
+type Handshake = handshake.Handshake
+type HandshakeFunc = handshake.Func
+
+var ErrHandshakeTimeout = handshake.ErrTimeout
+var ErrHandshakeRejected = handshake.ErrRejected
+
+type UpgradeOptions = upgrade.Options
+type UpgradeConn = upgrade.Conn
+
+var ErrUpgradeUnsupported = upgrade.ErrUnsupported
+var ErrUpgradeServerName = upgrade.ErrServerName
+
+type State = state.State
+type StateEvent = state.Event
+type StateMachine = state.Machine
+type StateHook = state.HookSpec
+type StateTransition = state.Transition
+
+// ErrStateFinal 连接进入 StateClosing 后 Change / Event 返回的错误
+var ErrStateFinal = state.ErrFinal
+
+// 连接生命周期状态，通过 StateConn.State() 读取；自定义状态取 StateUser 及以上的值
+const (
+	StateConnecting  = lifecycle.Connecting
+	StateHandshaking = lifecycle.Handshaking
+	StateReady       = lifecycle.Ready
+	StateDraining    = lifecycle.Draining
+	StateClosing     = lifecycle.Closing
+	StateClosed      = lifecycle.Closed
+	StateUser        = lifecycle.User
+	StateAny         = state.Any // 迁移来源或钩子的 From / To 匹配任意状态
+)
+
+// 状态机钩子阶段，Guards / Before 可拒绝迁移，其余阶段在状态生效后经协程池执行
+const (
+	StageGuards  = state.StageGuards
+	StageBefore  = state.StageBefore
+	StageApply   = state.StageApply
+	StageAfter   = state.StageAfter
+	StageOnExit  = state.StageOnExit
+	StageOnEnter = state.StageOnEnter
+)
+
+type HeartbeatOptions = heartbeat.Options
+type HeartbeatTimeoutError = heartbeat.TimeoutError
+
+var ErrHeartbeatTimeout = heartbeat.ErrTimeout
+
+type ProxyProtocolOptions = proxyproto.Options
+type ProxyHeader = proxyproto.Header
+type ProxyTLV = proxyproto.TLV
+
+// AttrProxyHeader PROXY 协议头部（*ProxyHeader）属性键，仅携带头部的服务端连接可用
+const AttrProxyHeader = conn.AttrProxyHeader
+
+// PROXY 协议 v2 TLV 类型，通过 ProxyHeader.TLV(typ) 读取
+const (
+	ProxyTLVALPN      = proxyproto.TypeALPN
+	ProxyTLVAuthority = proxyproto.TypeAuthority
+	ProxyTLVCRC32C    = proxyproto.TypeCRC32C
+	ProxyTLVUniqueID  = proxyproto.TypeUniqueID
+	ProxyTLVSSL       = proxyproto.TypeSSL
+	ProxyTLVNetNS     = proxyproto.TypeNetNS
+)
+
 type Config = conf.Config
 type Option = func(*Config)
 
@@ -102,7 +274,7 @@ func WithHandlers(Handlers ...Handler) Option {
 	}
 }
 
-// WithNetwork 设置网络类型 (tcp/udp)
+// WithNetwork 设置网络类型 (tcp/udp/unix/unixpacket/unixgram/ws/wss)
 func WithNetwork(n string) Option {
 	return func(c *Config) {
 		c.Network = n
@@ -151,6 +323,13 @@ func WithWriteTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithCloseTimeout 设置关闭连接时等待发送队列写出的最长时间
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.CloseTimeout = timeout
+	}
+}
+
 // WithReadBufferSize 设置读缓冲区大小
 func WithReadBufferSize(size int) Option {
 	return func(c *Config) {
@@ -179,6 +358,189 @@ func WithTickInterval(interval time.Duration) Option {
 	}
 }
 
+// WithTLSConfig 设置 TLS 配置，仅 TCP 有效
+func WithTLSConfig(tc *tls.Config) Option {
+	return func(c *Config) {
+		c.TLSConfig = tc
+	}
+}
+
+// WithTLSHandshakeTimeout 设置 TLS 握手超时
+func WithTLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithUnixSocketMode 设置 Unix 域套接字文件权限，仅服务端有效
+func WithUnixSocketMode(mode os.FileMode) Option {
+	return func(c *Config) {
+		c.UnixSocketMode = mode
+	}
+}
+
+// WithWSPath 设置 WebSocket 服务端升级路径
+func WithWSPath(path string) Option {
+	return func(c *Config) {
+		c.WSPath = path
+	}
+}
+
+// WithWSCheckOrigin 设置 WebSocket 服务端 Origin 校验
+func WithWSCheckOrigin(check func(r *http.Request) bool) Option {
+	return func(c *Config) {
+		c.WSCheckOrigin = check
+	}
+}
+
+// WithWSText 设置 WebSocket 以文本帧发送消息
+func WithWSText(text bool) Option {
+	return func(c *Config) {
+		c.WSText = text
+	}
+}
+
+// WithWSMaxMessageSize 设置 WebSocket 单条消息最大字节数
+func WithWSMaxMessageSize(size int) Option {
+	return func(c *Config) {
+		c.WSMaxMessageSize = size
+	}
+}
+
+// WithARQ 启用 UDP 可靠有序传输（服务端与客户端需同时开启）
+func WithARQ(opts ARQOptions) Option {
+	return func(c *Config) {
+		c.ARQ = &opts
+	}
+}
+
+// WithFragment 启用 UDP 分片重组（服务端与客户端需同时开启）
+func WithFragment(opts FragmentOptions) Option {
+	return func(c *Config) {
+		c.Fragment = &opts
+	}
+}
+
+// WithOrdered 设置按连接串行派发事件
+func WithOrdered(ordered bool) Option {
+	return func(c *Config) {
+		c.Ordered = ordered
+	}
+}
+
+// WithRPC 启用请求/应答模式（服务端与客户端需同时开启）
+func WithRPC(rpc bool) Option {
+	return func(c *Config) {
+		c.RPC = rpc
+	}
+}
+
+// WithSendQueueSize 设置单连接发送队列最大消息数
+func WithSendQueueSize(size int) Option {
+	return func(c *Config) {
+		c.SendQueueSize = size
+	}
+}
+
+// WithSendQueueBytes 设置单连接发送队列最大字节数
+func WithSendQueueBytes(size int) Option {
+	return func(c *Config) {
+		c.SendQueueBytes = size
+	}
+}
+
+// WithSendQueuePolicy 设置发送队列已满时的处理策略
+func WithSendQueuePolicy(policy QueuePolicy) Option {
+	return func(c *Config) {
+		c.SendQueuePolicy = policy
+	}
+}
+
+// WithSendQueueWatermark 设置发送队列高低水位（字节），回调实现 BackpressureHook 可收到水位通知
+func WithSendQueueWatermark(high, low int) Option {
+	return func(c *Config) {
+		c.SendQueueHighWatermark = high
+		c.SendQueueLowWatermark = low
+	}
+}
+
+// WithMux 启用多路复用（服务端与客户端需同时开启）
+func WithMux(opts MuxOptions) Option {
+	return func(c *Config) {
+		c.Mux = &opts
+	}
+}
+
+// WithHandoff 启用监听套接字交接，新进程启动时接管旧进程的监听套接字
+func WithHandoff(opts HandoffOptions) Option {
+	return func(c *Config) {
+		c.Handoff = &opts
+	}
+}
+
+// WithReusePort 设置服务端通过 SO_REUSEPORT 在同一地址上打开的套接字数量，各套接字独立 accept / 读取
+func WithReusePort(n int) Option {
+	return func(c *Config) {
+		c.ReusePort = n
+	}
+}
+
+// WithUDPBatch 设置 UDP 服务端单次批量收发的最大数据报数，Linux 下使用 recvmmsg / sendmmsg
+func WithUDPBatch(n int) Option {
+	return func(c *Config) {
+		c.UDPBatchSize = n
+	}
+}
+
+// WithHandshaker 设置连接握手处理，握手成功前收到的帧不进入处理器链
+func WithHandshaker(h Handshaker) Option {
+	return func(c *Config) {
+		c.Handshaker = h
+	}
+}
+
+// WithHandshakeTimeout 设置连接握手超时
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.HandshakeTimeout = timeout
+	}
+}
+
+// WithStates 为每个连接的状态机注册应用自定义状态、迁移与钩子，通过 StateConn 读取当前状态并迁移
+func WithStates(setup func(c Conn, m *StateMachine)) Option {
+	return func(c *Config) {
+		c.States = setup
+	}
+}
+
+// WithHeartbeat 启用应用层心跳，连续多次未收到应答时关闭连接，并通过 HeartbeatConn.RTT 获取往返时延
+func WithHeartbeat(opts HeartbeatOptions) Option {
+	return func(c *Config) {
+		c.Heartbeat = &opts
+	}
+}
+
+// WithReactor 设置 TCP / Unix 流式服务端的 epoll 事件循环数量（仅 Linux），由少量循环驱动海量连接的读取
+func WithReactor(loops int) Option {
+	return func(c *Config) {
+		c.Reactor = loops
+	}
+}
+
+// WithProxyProtocol 启用 HAProxy PROXY 协议，服务端据可信负载均衡写入的头部报告客户端真实地址
+func WithProxyProtocol(opts ProxyProtocolOptions) Option {
+	return func(c *Config) {
+		c.ProxyProtocol = &opts
+	}
+}
+
+// WithReconnect 启用客户端自动重连，回调实现 ReconnectHook 可感知重连过程
+func WithReconnect(opts ReconnectOptions) Option {
+	return func(c *Config) {
+		c.Reconnect = &opts
+	}
+}
+
 // initConfig 初始化配置
 func initConfig(opts ...Option) conf.Config {
 	cfg := conf.Config{}
@@ -191,38 +553,114 @@ func initConfig(opts ...Option) conf.Config {
 	return cfg
 }
 
-// Serve 启动一个 TCP 或 UDP 服务，阻塞运行直到 Stop() 调用或出错
+// Serve 启动一个 TCP、UDP、Unix 域套接字或 WebSocket 服务，阻塞运行直到 Stop() 调用或出错
 func Serve(ctx context.Context, hook hook.ServerHook, addr string, opts ...Option) error {
+	srv, err := Start(ctx, hook, addr, opts...)
+	if err != nil {
+		return err
+	}
+	return srv.Wait()
+}
+
+// Start 启动服务并在监听成功后立即返回，Addr() 即可用（监听 ":0" 时含实际端口）；
+// 服务循环在后台运行，通过 Wait() / Err() 获取其退出错误
+func Start(ctx context.Context, hook hook.ServerHook, addr string, opts ...Option) (Server, error) {
 	cfg := initConfig(opts...)
 
-	// 启动服务
+	var srv interface {
+		boot.Server
+		Bind() error
+		Serve() error
+	}
 	switch cfg.Network {
-	case "tcp", "tcp4", "tcp6":
-		srv := tcp.NewServer(ctx, cfg, hook, addr)
-		return srv.Listen()
-	case "udp", "udp4", "udp6":
-		srv := udp.NewServer(ctx, cfg, hook, addr)
-		return srv.Listen()
+	case "tcp", "tcp4", "tcp6", "unix", "unixpacket", "ws", "wss":
+		srv = tcp.NewServer(ctx, cfg, hook, addr)
+	case "udp", "udp4", "udp6", "unixgram":
+		srv = udp.NewServer(ctx, cfg, hook, addr)
 	default:
-		return fmt.Errorf("unknown network: %s", cfg.Network)
+		return nil, fmt.Errorf("unknown network: %s", cfg.Network)
 	}
+
+	if err := srv.Bind(); err != nil {
+		return nil, err
+	}
+	go func() { _ = srv.Serve() }()
+	return srv, nil
 }
 
-// Dial 连接一个 TCP 或 UDP 服务
+// ServeListener 在已有的监听器上启动服务（如 systemd 套接字激活、测试桩或包装过的监听器），阻塞运行直到 Stop() 调用或出错。
+// Network 为数据报网络时按监听器地址修正；WebSocket 需通过 WithNetwork("ws") 指定。
+func ServeListener(ctx context.Context, hook hook.ServerHook, ln net.Listener, opts ...Option) error {
+	cfg := initConfig(opts...)
+	if conn.IsPacketNetwork(cfg.Network) {
+		cfg.Network = ln.Addr().Network()
+	}
+	return tcp.NewListenerServer(ctx, cfg, hook, ln).Listen()
+}
+
+// ServePacketConn 在已有的数据报套接字上启动伪连接服务，阻塞运行直到 Stop() 调用或出错。
+// Network 未指定为数据报网络时按套接字地址修正。
+func ServePacketConn(ctx context.Context, hook hook.ServerHook, pc net.PacketConn, opts ...Option) error {
+	cfg := initConfig(opts...)
+	if !conn.IsPacketNetwork(cfg.Network) {
+		cfg.Network = pc.LocalAddr().Network()
+	}
+	return udp.NewPacketConnServer(ctx, cfg, hook, pc).Listen()
+}
+
+// DialConn 将已建立的连接包装为会话，按配置完成 TLS / WebSocket 握手；
+// 数据报连接（如 *net.UDPConn）按其地址修正 Network。不支持自动重连。
+func DialConn(ctx context.Context, hook hook.ConnHook, raw net.Conn, opts ...Option) (boot.Conn, error) {
+	cfg := initConfig(opts...)
+	if cfg.Reconnect != nil {
+		return nil, errors.New("DialConn does not support reconnect")
+	}
+	if raw.RemoteAddr() == nil {
+		return nil, errors.New("DialConn requires a connected socket")
+	}
+	if network := raw.LocalAddr().Network(); conn.IsPacketNetwork(network) {
+		cfg.Network = network
+	}
+
+	if conn.IsPacketNetwork(cfg.Network) {
+		return udp.NewConnClient(ctx, cfg, hook, raw).Dial()
+	}
+	return tcp.NewConnClient(ctx, cfg, hook, raw).Dial()
+}
+
+// Dial 连接一个 TCP、UDP、Unix 域套接字或 WebSocket 服务
 func Dial(ctx context.Context, hook hook.ConnHook, addr string, opts ...Option) (boot.Conn, error) {
 	cfg := initConfig(opts...)
 
 	// 创建客户端
 	var c boot.Client
-	switch cfg.Network {
-	case "tcp", "tcp4", "tcp6":
-		c = tcp.NewClient(ctx, cfg, hook, addr)
-	case "udp", "udp4", "udp6":
-		c = udp.NewClient(ctx, cfg, hook, addr)
-	default:
-		return nil, fmt.Errorf("unknown network: %s", cfg.Network)
+	if cfg.Reconnect != nil {
+		c = reconnect.NewClient(ctx, *cfg.Reconnect, hook, func(ctx context.Context, h ConnHook) (boot.Conn, error) {
+			c, err := newClient(ctx, cfg, h, addr)
+			if err != nil {
+				return nil, err
+			}
+			return c.Dial()
+		})
+	} else {
+		var err error
+		if c, err = newClient(ctx, cfg, hook, addr); err != nil {
+			return nil, err
+		}
 	}
 
 	// 连接
 	return c.Dial()
 }
+
+// newClient 按网络类型创建客户端
+func newClient(ctx context.Context, cfg conf.Config, hook hook.ConnHook, addr string) (boot.Client, error) {
+	switch cfg.Network {
+	case "tcp", "tcp4", "tcp6", "unix", "unixpacket", "ws", "wss":
+		return tcp.NewClient(ctx, cfg, hook, addr), nil
+	case "udp", "udp4", "udp6", "unixgram":
+		return udp.NewClient(ctx, cfg, hook, addr), nil
+	default:
+		return nil, fmt.Errorf("unknown network: %s", cfg.Network)
+	}
+}
@@ -0,0 +1,119 @@
+package uno_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/yurazsb/uno"
+)
+
+// startSTARTTLS 启动收到 STARTTLS 后开启 TLS 的服务端，其余消息回显为 "echo:<payload>"；
+// 服务端证书只包含主机名 localhost
+func startSTARTTLS(t *testing.T, ca *testCA) uno.Server {
+	t.Helper()
+	stc := &tls.Config{Certificates: []tls.Certificate{ca.issueFor(t, "server", x509.ExtKeyUsageServerAuth, []string{"localhost"}, nil)}}
+	srv, err := uno.Start(context.Background(), &uno.ServerEvent{}, "127.0.0.1:0", uno.WithLogger(&logRecorder{}),
+		uno.WithDecoder(uno.StringDecoder(false)),
+		uno.WithHandlers(func(ctx uno.Context, next func()) {
+			payload := ctx.Payload().(string)
+			if payload == "STARTTLS" {
+				_ = ctx.Conn().(uno.UpgradeConn).Upgrade(context.Background(), uno.UpgradeOptions{Reply: "ok", TLS: stc})
+				return
+			}
+			ctx.Conn().Send("echo:" + payload)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Stop)
+	return srv
+}
+
+// recvHandlers 将收到的消息送入 ch
+func recvHandlers(ch chan<- string) uno.Option {
+	return uno.WithHandlers(func(ctx uno.Context, next func()) { ch <- ctx.Payload().(string) })
+}
+
+func expect(t *testing.T, ch <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("received %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("did not receive %q", want)
+	}
+}
+
+func TestSTARTTLSVerifiesDialledHost(t *testing.T) {
+	ca := newTestCA(t)
+	srv := startSTARTTLS(t, ca)
+	_, port, _ := net.SplitHostPort(srv.Addr().String())
+
+	msgs := make(chan string, 4)
+	c, err := uno.Dial(context.Background(), &uno.ConnEvent{}, net.JoinHostPort("localhost", port), uno.WithLogger(&logRecorder{}),
+		uno.WithDecoder(uno.StringDecoder(false)), recvHandlers(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.Send("STARTTLS")
+	expect(t, msgs, "ok")
+
+	// 证书不含 127.0.0.1：须以拨号时的主机名 localhost 校验
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.(uno.UpgradeConn).Upgrade(ctx, uno.UpgradeOptions{TLS: &tls.Config{RootCAs: ca.pool}}); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	state, ok := c.(uno.TLSConn).ConnectionState()
+	if !ok || state.ServerName != "localhost" {
+		t.Fatalf("ConnectionState = %q, %v, want ServerName localhost", state.ServerName, ok)
+	}
+
+	c.Send("ping")
+	expect(t, msgs, "echo:ping")
+}
+
+func TestSTARTTLSRequiresServerNameWithoutDialAddr(t *testing.T) {
+	ca := newTestCA(t)
+	srv := startSTARTTLS(t, ca)
+
+	raw, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan string, 4)
+	c, err := uno.DialConn(context.Background(), &uno.ConnEvent{}, raw, uno.WithLogger(&logRecorder{}),
+		uno.WithDecoder(uno.StringDecoder(false)), recvHandlers(msgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 无拨号地址且未设置 ServerName：拒绝升级，连接保持可用
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = c.(uno.UpgradeConn).Upgrade(ctx, uno.UpgradeOptions{TLS: &tls.Config{RootCAs: ca.pool}})
+	if !errors.Is(err, uno.ErrUpgradeServerName) {
+		t.Fatalf("upgrade error = %v, want ErrUpgradeServerName", err)
+	}
+	c.Send("ping")
+	expect(t, msgs, "echo:ping")
+
+	c.Send("STARTTLS")
+	expect(t, msgs, "ok")
+	tc := &tls.Config{RootCAs: ca.pool, ServerName: "localhost"}
+	if err := c.(uno.UpgradeConn).Upgrade(ctx, uno.UpgradeOptions{TLS: tc}); err != nil {
+		t.Fatalf("upgrade with ServerName: %v", err)
+	}
+	c.Send("ping")
+	expect(t, msgs, "echo:ping")
+}